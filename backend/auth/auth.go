@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"personal-finance/models"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidSession 令牌不存在或已过期
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+// HashPassword 使用 bcrypt 计算密码哈希
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// CheckPassword 校验密码是否与哈希匹配
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NewSession 为用户创建登录会话，返回令牌明文；数据库中只保存其哈希
+func NewSession(db *gorm.DB, userID uint, ttl time.Duration) (string, models.Session, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", models.Session{}, err
+	}
+	token := hex.EncodeToString(buf)
+
+	session := models.Session{
+		TokenHash: hashToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return "", models.Session{}, err
+	}
+	return token, session, nil
+}
+
+// LookupSession 根据令牌查找未过期的会话
+func LookupSession(db *gorm.DB, token string) (models.Session, error) {
+	var session models.Session
+	if token == "" {
+		return session, ErrInvalidSession
+	}
+	if err := db.Where("token_hash = ? AND expires_at > ?", hashToken(token), time.Now()).First(&session).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return session, ErrInvalidSession
+		}
+		return session, err
+	}
+	return session, nil
+}
+
+// DeleteSession 注销令牌对应的会话
+func DeleteSession(db *gorm.DB, token string) error {
+	return db.Where("token_hash = ?", hashToken(token)).Delete(&models.Session{}).Error
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,224 @@
+package classifier
+
+import (
+	"math"
+	"sort"
+)
+
+// Prediction 一个候选分类及其置信度（0~1，所有候选之和为 1）
+type Prediction struct {
+	Label      uint    `json:"category_id"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Metrics 留一法评估的准确率
+type Metrics struct {
+	Samples      int            `json:"samples"`
+	Accuracy     float64        `json:"accuracy"`      // 首选分类正确的比例
+	Top3Accuracy float64        `json:"top3_accuracy"` // 正确分类位于前三的比例
+	ByLabel      []LabelMetrics `json:"by_category"`
+}
+
+// LabelMetrics 单个分类的评估结果
+type LabelMetrics struct {
+	Label     uint    `json:"category_id"`
+	Samples   int     `json:"samples"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+}
+
+// NaiveBayes 多项式朴素贝叶斯分类器，使用拉普拉斯平滑。
+// 每个样本以外部编号登记，可单独增加、替换或移除，从而增量训练
+type NaiveBayes struct {
+	samples     map[uint]sample
+	docs        map[uint]int            // 分类 → 样本数
+	tokens      map[uint]map[string]int // 分类 → 词元 → 出现次数
+	tokenTotals map[uint]int            // 分类 → 词元总数
+	vocabulary  map[string]int          // 词元 → 出现次数
+}
+
+type sample struct {
+	label    uint
+	features []string
+}
+
+// New 创建一个空的分类器
+func New() *NaiveBayes {
+	return &NaiveBayes{
+		samples:     make(map[uint]sample),
+		docs:        make(map[uint]int),
+		tokens:      make(map[uint]map[string]int),
+		tokenTotals: make(map[uint]int),
+		vocabulary:  make(map[string]int),
+	}
+}
+
+// Len 返回已训练的样本数
+func (nb *NaiveBayes) Len() int {
+	return len(nb.samples)
+}
+
+// IDs 返回所有已训练样本的编号
+func (nb *NaiveBayes) IDs() []uint {
+	ids := make([]uint, 0, len(nb.samples))
+	for id := range nb.samples {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Train 登记编号为 id 的样本，已存在时替换原样本
+func (nb *NaiveBayes) Train(id, label uint, features []string) {
+	nb.Forget(id)
+	nb.samples[id] = sample{label, features}
+	nb.add(label, features, 1)
+}
+
+// Forget 移除编号为 id 的样本
+func (nb *NaiveBayes) Forget(id uint) {
+	s, ok := nb.samples[id]
+	if !ok {
+		return
+	}
+	delete(nb.samples, id)
+	nb.add(s.label, s.features, -1)
+}
+
+func (nb *NaiveBayes) add(label uint, features []string, delta int) {
+	nb.docs[label] += delta
+	if nb.docs[label] <= 0 {
+		delete(nb.docs, label)
+	}
+	counts := nb.tokens[label]
+	if counts == nil {
+		counts = make(map[string]int)
+		nb.tokens[label] = counts
+	}
+	for _, token := range features {
+		counts[token] += delta
+		if counts[token] <= 0 {
+			delete(counts, token)
+		}
+		nb.vocabulary[token] += delta
+		if nb.vocabulary[token] <= 0 {
+			delete(nb.vocabulary, token)
+		}
+	}
+	nb.tokenTotals[label] += delta * len(features)
+	if len(counts) == 0 {
+		delete(nb.tokens, label)
+		delete(nb.tokenTotals, label)
+	}
+}
+
+// Predict 返回候选分类，按置信度从高到低排列。allowed 非空时只考虑其中的分类；
+// 未见过的词元不参与计算
+func (nb *NaiveBayes) Predict(features []string, allowed map[uint]bool) []Prediction {
+	total := 0
+	for label, count := range nb.docs {
+		if allowed == nil || allowed[label] {
+			total += count
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	vocabulary := float64(len(nb.vocabulary))
+	var predictions []Prediction
+	var scores []float64
+	for label, count := range nb.docs {
+		if allowed != nil && !allowed[label] {
+			continue
+		}
+		score := math.Log(float64(count) / float64(total))
+		denominator := float64(nb.tokenTotals[label]) + vocabulary
+		for _, token := range features {
+			if _, known := nb.vocabulary[token]; !known {
+				continue
+			}
+			score += math.Log((float64(nb.tokens[label][token]) + 1) / denominator)
+		}
+		predictions = append(predictions, Prediction{Label: label})
+		scores = append(scores, score)
+	}
+
+	// 对数概率经 softmax 归一化为置信度
+	max := math.Inf(-1)
+	for _, score := range scores {
+		max = math.Max(max, score)
+	}
+	sum := 0.0
+	for i, score := range scores {
+		predictions[i].Confidence = math.Exp(score - max)
+		sum += predictions[i].Confidence
+	}
+	for i := range predictions {
+		predictions[i].Confidence /= sum
+	}
+
+	sort.Slice(predictions, func(i, j int) bool {
+		if predictions[i].Confidence != predictions[j].Confidence {
+			return predictions[i].Confidence > predictions[j].Confidence
+		}
+		return predictions[i].Label < predictions[j].Label
+	})
+	return predictions
+}
+
+// Evaluate 用留一法评估分类器：依次移除每个样本，用其余样本预测它的分类。
+// group 返回每个分类可参与竞争的候选分类集合（如同类型的分类），为 nil 时不限制
+func (nb *NaiveBayes) Evaluate(group func(label uint) map[uint]bool) Metrics {
+	var metrics Metrics
+	correct, top3 := 0, 0
+	predicted := make(map[uint]int)
+	hits := make(map[uint]int)
+	actual := make(map[uint]int)
+
+	ids := nb.IDs()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		s := nb.samples[id]
+		nb.Forget(id)
+		var allowed map[uint]bool
+		if group != nil {
+			allowed = group(s.label)
+		}
+		predictions := nb.Predict(s.features, allowed)
+		nb.Train(id, s.label, s.features)
+
+		metrics.Samples++
+		actual[s.label]++
+		if len(predictions) == 0 {
+			continue
+		}
+		predicted[predictions[0].Label]++
+		for i, p := range predictions {
+			if i >= 3 {
+				break
+			}
+			if p.Label == s.label {
+				top3++
+				if i == 0 {
+					correct++
+					hits[s.label]++
+				}
+			}
+		}
+	}
+
+	if metrics.Samples == 0 {
+		return metrics
+	}
+	metrics.Accuracy = float64(correct) / float64(metrics.Samples)
+	metrics.Top3Accuracy = float64(top3) / float64(metrics.Samples)
+	for label, count := range actual {
+		m := LabelMetrics{Label: label, Samples: count, Recall: float64(hits[label]) / float64(count)}
+		if predicted[label] > 0 {
+			m.Precision = float64(hits[label]) / float64(predicted[label])
+		}
+		metrics.ByLabel = append(metrics.ByLabel, m)
+	}
+	sort.Slice(metrics.ByLabel, func(i, j int) bool { return metrics.ByLabel[i].Label < metrics.ByLabel[j].Label })
+	return metrics
+}
@@ -0,0 +1,93 @@
+// Package classifier 基于历史交易训练的本地朴素贝叶斯分类器，用于推荐交易分类，不依赖任何外部服务
+package classifier
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Features 提取交易的特征：描述分词、账户和金额量级
+func Features(description string, amount int64, accountID uint) []string {
+	tokens := Tokenize(description)
+	if accountID != 0 {
+		tokens = append(tokens, fmt.Sprintf("account:%d", accountID))
+	}
+	if amount != 0 {
+		tokens = append(tokens, "amount:"+amountBucket(amount))
+	}
+	return tokens
+}
+
+// Tokenize 将描述切分为词元：英文和数字按单词切分并转为小写（纯数字忽略），
+// 连续的汉字生成单字和相邻两字的 n-gram，如“星巴克”得到 星、巴、克、星巴、巴克
+func Tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var han []rune
+
+	flushWord := func() {
+		if len(word) > 0 && !isNumber(word) {
+			tokens = append(tokens, strings.ToLower(string(word)))
+		}
+		word = word[:0]
+	}
+	flushHan := func() {
+		for i := range han {
+			tokens = append(tokens, string(han[i]))
+			if i+1 < len(han) {
+				tokens = append(tokens, string(han[i:i+2]))
+			}
+		}
+		han = han[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return tokens
+}
+
+func isNumber(word []rune) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// amountBucket 按金额（分）的数量级分桶，同一桶内的金额视为相近
+func amountBucket(amount int64) string {
+	if amount < 0 {
+		amount = -amount
+	}
+	switch {
+	case amount < 1000:
+		return "<10"
+	case amount < 5000:
+		return "<50"
+	case amount < 10000:
+		return "<100"
+	case amount < 50000:
+		return "<500"
+	case amount < 100000:
+		return "<1000"
+	case amount < 500000:
+		return "<5000"
+	default:
+		return ">=5000"
+	}
+}
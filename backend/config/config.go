@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +13,24 @@ type Config struct {
 	DBPath     string
 	ServerPort string
 	GinMode    string
+	// BaseCurrency 首次启动及新建账本时写入的默认本位币，之后可通过 /settings 修改
+	BaseCurrency string
+	// SchedulerInterval 周期交易调度的检查间隔
+	SchedulerInterval time.Duration
+	// SessionTTL 登录令牌的有效期
+	SessionTTL time.Duration
+	// CORSOrigin 允许跨域访问的前端地址
+	CORSOrigin string
+	// SMTP 预算提醒邮件的发送配置，SMTPAddr 为空时不发送邮件
+	SMTPAddr     string
+	SMTPFrom     string
+	SMTPTo       []string
+	SMTPUsername string
+	SMTPPassword string
+	// AlertWebhookURL 预算提醒的 Webhook 地址，为空时不发送
+	AlertWebhookURL string
+	// BalanceCheck 启动时的账户余额校验：off（默认）不校验，report 只记录日志，repair 修复并写入修复记录
+	BalanceCheck string
 }
 
 func LoadConfig() *Config {
@@ -20,12 +40,44 @@ func LoadConfig() *Config {
 	}
 
 	return &Config{
-		DBPath:     getEnv("DB_PATH", "finance.db"),
-		ServerPort: getEnv("SERVER_PORT", "8080"),
-		GinMode:    getEnv("GIN_MODE", "debug"),
+		DBPath:            getEnv("DB_PATH", "finance.db"),
+		ServerPort:        getEnv("SERVER_PORT", "8080"),
+		GinMode:           getEnv("GIN_MODE", "debug"),
+		BaseCurrency:      getEnv("BASE_CURRENCY", "CNY"),
+		SchedulerInterval: getDuration("SCHEDULER_INTERVAL", time.Hour),
+		SessionTTL:        getDuration("SESSION_TTL", 30*24*time.Hour),
+		CORSOrigin:        getEnv("CORS_ORIGIN", "http://localhost:3000"),
+		SMTPAddr:          getEnv("SMTP_ADDR", ""),
+		SMTPFrom:          getEnv("SMTP_FROM", ""),
+		SMTPTo:            getList("SMTP_TO"),
+		SMTPUsername:      getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:      getEnv("SMTP_PASSWORD", ""),
+		AlertWebhookURL:   getEnv("ALERT_WEBHOOK_URL", ""),
+		BalanceCheck:      getEnv("BALANCE_CHECK", "off"),
 	}
 }
 
+// getList 读取以逗号分隔的列表配置，忽略空项
+func getList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getDuration 读取时长配置，如 "1h"、"30m"，无效时使用默认值
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, defaultValue.String()))
+	if err != nil || value <= 0 {
+		log.Printf("Warning: invalid %s, using %s", key, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
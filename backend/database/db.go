@@ -33,7 +33,7 @@ func InitDB(cfg *config.Config) *gorm.DB {
 		CREATE TABLE IF NOT EXISTS budgets (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			category_id INTEGER NOT NULL,
-			amount REAL NOT NULL,
+			amount INTEGER NOT NULL,
 			start_date TEXT NOT NULL,
 			end_date TEXT NOT NULL,
 			FOREIGN KEY (category_id) REFERENCES categories(id)
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"log"
+	"personal-finance/config"
+	"personal-finance/models"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// schemaMigration 记录已执行的数据迁移
+type schemaMigration struct {
+	ID        string `gorm:"primary_key"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// migration 是一次性的数据迁移，在 AutoMigrate 完成表结构变更后执行
+type migration struct {
+	ID string
+	Up func(tx *gorm.DB, cfg *config.Config) error
+}
+
+// migrations 按顺序执行，已执行过的迁移不会重复执行
+var migrations = []migration{
+	{
+		// 交易业务日期：旧数据用创建时间回填
+		ID: "0001_backfill_transaction_date",
+		Up: func(tx *gorm.DB, cfg *config.Config) error {
+			return tx.Exec("UPDATE transactions SET date = substr(created_at, 1, 10) WHERE date IS NULL OR date = ''").Error
+		},
+	},
+	{
+		// 金额由浮点数（元）改为整数（分）。旧库中列的 REAL 类型保持不变，
+		// 读取时由 models.Money 统一取整。
+		ID: "0002_money_to_minor_units",
+		Up: func(tx *gorm.DB, cfg *config.Config) error {
+			for _, stmt := range []string{
+				"UPDATE accounts SET balance = CAST(ROUND(balance * 100) AS INTEGER)",
+				"UPDATE transactions SET amount = CAST(ROUND(amount * 100) AS INTEGER)",
+				"UPDATE budgets SET amount = CAST(ROUND(amount * 100) AS INTEGER)",
+			} {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// 多币种：写入本位币设置，已有账户和交易视为本位币；旧转账的转入金额等于转出金额
+		ID: "0003_currency",
+		Up: func(tx *gorm.DB, cfg *config.Config) error {
+			for _, stmt := range []struct {
+				sql  string
+				args []interface{}
+			}{
+				{"INSERT OR IGNORE INTO settings (name, value) VALUES (?, ?)", []interface{}{"base_currency", cfg.BaseCurrency}},
+				{"UPDATE accounts SET currency = ? WHERE currency IS NULL OR currency = ''", []interface{}{cfg.BaseCurrency}},
+				{"UPDATE transactions SET currency = (SELECT accounts.currency FROM accounts WHERE accounts.id = transactions.account_id) WHERE currency IS NULL OR currency = ''", nil},
+				{"UPDATE transactions SET to_amount = amount WHERE type = 'transfer' AND (to_amount IS NULL OR to_amount = 0)", nil},
+			} {
+				if err := tx.Exec(stmt.sql, stmt.args...).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// 周期交易：同一模板在同一日期至多生成一笔交易
+		ID: "0004_recurring_occurrence_unique",
+		Up: func(tx *gorm.DB, cfg *config.Config) error {
+			return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_recurring_date ON transactions (recurring_id, date) WHERE recurring_id > 0").Error
+		},
+	},
+	{
+		// 共享账本：数据由按用户隔离改为按账本隔离，为每个已有用户创建个人账本并迁入其数据，
+		// 已有交易的记账成员即原所属用户
+		ID: "0005_ledgers",
+		Up: func(tx *gorm.DB, cfg *config.Config) error {
+			var userIDs []uint
+			if err := tx.Table("users").Order("id").Pluck("id", &userIDs).Error; err != nil {
+				return err
+			}
+			for _, userID := range userIDs {
+				ledger := models.Ledger{Name: "个人账本"}
+				if err := tx.Create(&ledger).Error; err != nil {
+					return err
+				}
+				if err := tx.Create(&models.LedgerMember{LedgerID: ledger.ID, UserID: userID, Role: models.RoleOwner}).Error; err != nil {
+					return err
+				}
+				for _, table := range []string{"accounts", "categories", "transactions", "budgets", "recurring_transactions", "import_profiles"} {
+					if err := tx.Exec("UPDATE "+table+" SET ledger_id = ? WHERE user_id = ?", ledger.ID, userID).Error; err != nil {
+						return err
+					}
+				}
+				for _, table := range []string{"transactions", "recurring_transactions"} {
+					if err := tx.Exec("UPDATE "+table+" SET created_by = ? WHERE user_id = ?", userID, userID).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// 期初余额：以当前余额减去所有交易的影响回推，转账对转入账户记入 to_amount
+		ID: "0006_opening_balance",
+		Up: func(tx *gorm.DB, cfg *config.Config) error {
+			return tx.Exec(`UPDATE accounts SET opening_balance = balance
+				- COALESCE((SELECT SUM(CASE type WHEN 'income' THEN amount ELSE -amount END) FROM transactions WHERE transactions.account_id = accounts.id), 0)
+				- COALESCE((SELECT SUM(to_amount) FROM transactions WHERE transactions.to_account_id = accounts.id AND transactions.type = 'transfer'), 0)`).Error
+		},
+	},
+	{
+		// 本位币和汇率改为按账本设置：settings 表重建为以账本和名称为主键，原有的全局设置和汇率复制到每个账本。
+		// 尚无账本时保留为账本 0，由第一个注册用户的个人账本接管
+		ID: "0007_ledger_settings",
+		Up: func(tx *gorm.DB, cfg *config.Config) error {
+			var ledgerCount int64
+			if err := tx.Model(&models.Ledger{}).Count(&ledgerCount).Error; err != nil {
+				return err
+			}
+			stmts := []string{
+				"CREATE TABLE settings_new (ledger_id integer NOT NULL DEFAULT 0, name varchar(255) NOT NULL, value varchar(255), PRIMARY KEY (ledger_id, name))",
+			}
+			if ledgerCount > 0 {
+				stmts = append(stmts,
+					"INSERT OR IGNORE INTO settings_new (ledger_id, name, value) SELECT ledgers.id, settings.name, settings.value FROM ledgers CROSS JOIN settings",
+					`INSERT INTO exchange_rates (ledger_id, currency, quote_currency, rate, date, created_at, updated_at)
+						SELECT ledgers.id, currency, quote_currency, rate, date, exchange_rates.created_at, exchange_rates.updated_at
+						FROM ledgers CROSS JOIN exchange_rates WHERE exchange_rates.ledger_id IS NULL OR exchange_rates.ledger_id = 0`,
+					"DELETE FROM exchange_rates WHERE ledger_id IS NULL OR ledger_id = 0",
+				)
+			} else {
+				stmts = append(stmts, "INSERT OR IGNORE INTO settings_new (ledger_id, name, value) SELECT 0, name, value FROM settings")
+			}
+			stmts = append(stmts, "DROP TABLE settings", "ALTER TABLE settings_new RENAME TO settings")
+			for _, stmt := range stmts {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// RunMigrations 执行所有尚未执行的数据迁移
+func RunMigrations(db *gorm.DB, cfg *config.Config) {
+	db.AutoMigrate(&schemaMigration{})
+
+	for _, m := range migrations {
+		var count int64
+		db.Model(&schemaMigration{}).Where("id = ?", m.ID).Count(&count)
+		if count > 0 {
+			continue
+		}
+
+		tx := db.Begin()
+		if err := m.Up(tx, cfg); err != nil {
+			tx.Rollback()
+			log.Fatalf("Migration %s failed: %v", m.ID, err)
+		}
+		if err := tx.Create(&schemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error; err != nil {
+			tx.Rollback()
+			log.Fatalf("Migration %s failed: %v", m.ID, err)
+		}
+		tx.Commit()
+		log.Printf("已执行数据迁移 %s", m.ID)
+	}
+}
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"net/http"
+	"personal-finance/middleware"
 	"personal-finance/models"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
@@ -20,6 +23,24 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 		return
 	}
 
+	// 未指定币种时使用本位币
+	if account.Currency == "" {
+		account.Currency = baseCurrency(h.DB, middleware.CurrentLedgerID(c))
+	}
+	currency, err := normalizeCurrency(account.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	account.Currency = currency
+	account.LedgerID = middleware.CurrentLedgerID(c)
+	// 新账户还没有交易，期初余额即当前余额
+	account.OpeningBalance = account.Balance
+	if err := validateAccount(&account); err != nil {
+		respondError(c, err)
+		return
+	}
+
 	if err := h.DB.Create(&account).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -28,47 +49,127 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 	c.JSON(http.StatusCreated, account)
 }
 
-// GetAccounts 获取所有账户
+// GetAccounts 获取所有账户，并按今日汇率换算为本位币汇总总资产、总负债和净资产
 func (h *AccountHandler) GetAccounts(c *gin.Context) {
 	var accounts []models.Account
-	if err := h.DB.Find(&accounts).Error; err != nil {
+	if err := h.DB.Scopes(ledgerScope(c)).Find(&accounts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	cv, err := newCurrencyConverter(h.DB, middleware.CurrentLedgerID(c))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 计算总余额
-	var totalBalance float64
-	for _, account := range accounts {
-		totalBalance += account.Balance
+	// 负债账户的欠款（负余额）计入总负债，资产账户的余额计入总资产
+	today := time.Now().Format("2006-01-02")
+	var totalAssets, totalLiabilities models.Money
+	byCurrency := make(map[string]models.Money)
+	for i := range accounts {
+		account := &accounts[i]
+		byCurrency[account.Currency] += account.Balance
+		converted, err := cv.toBase(account.Balance, account.Currency, today)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if account.IsLiability() {
+			totalLiabilities -= converted
+		} else {
+			totalAssets += converted
+		}
+		if account.Type == models.AccountCreditCard && account.CreditLimit > 0 {
+			available := account.CreditLimit + account.Balance
+			account.AvailableCredit = &available
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"accounts": accounts,
-		"total_balance": totalBalance,
+		"accounts":            accounts,
+		"total_assets":        totalAssets,
+		"total_liabilities":   totalLiabilities,
+		"net_worth":           totalAssets - totalLiabilities,
+		"base_currency":       cv.base,
+		"balance_by_currency": byCurrency,
 	})
 }
 
-// UpdateAccount 更新账户信息
+// UpdateAccount 更新账户信息，只修改请求中给出的字段
 func (h *AccountHandler) UpdateAccount(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := pathID(c, "id", "account")
+	if !ok {
+		return
+	}
 	var account models.Account
-	
-	if err := h.DB.First(&account, id).Error; err != nil {
+
+	if err := h.DB.Scopes(ledgerScope(c)).First(&account, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
 		return
 	}
 
 	var input struct {
-		Name    string  `json:"name"`
-		Balance float64 `json:"balance"`
+		Name          *string       `json:"name"`
+		Type          string        `json:"type"` // 为空时保留原类型
+		Balance       *models.Money `json:"balance"`
+		Currency      string        `json:"currency"` // 为空时保留原币种
+		CreditLimit   *models.Money `json:"credit_limit"`
+		StatementDay  *int          `json:"statement_day"`
+		PaymentDueDay *int          `json:"payment_due_day"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	account.Name = input.Name
-	account.Balance = input.Balance
+	// 已有交易的账户不能更换币种
+	if input.Currency != "" {
+		currency, err := normalizeCurrency(input.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if currency != account.Currency {
+			var transactionCount int64
+			h.DB.Model(&models.Transaction{}).Where("account_id = ? OR to_account_id = ?", id, id).Count(&transactionCount)
+			if transactionCount > 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change currency of an account with transactions"})
+				return
+			}
+			account.Currency = currency
+		}
+	}
+
+	// 余额由期初余额和交易决定，不能直接修改：差额应通过对账调整，或修改期初余额
+	if input.Balance != nil && *input.Balance != account.Balance {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Balance cannot be changed directly; reconcile the account or set its opening balance"})
+		return
+	}
+
+	if input.Name != nil {
+		if strings.TrimSpace(*input.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		account.Name = *input.Name
+	}
+	if input.Type != "" {
+		account.Type = input.Type
+	}
+	if input.CreditLimit != nil {
+		account.CreditLimit = *input.CreditLimit
+	}
+	if input.StatementDay != nil {
+		account.StatementDay = *input.StatementDay
+	}
+	if input.PaymentDueDay != nil {
+		account.PaymentDueDay = *input.PaymentDueDay
+	}
+	if err := validateAccount(&account); err != nil {
+		respondError(c, err)
+		return
+	}
 
 	if err := h.DB.Save(&account).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -78,24 +179,94 @@ func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 	c.JSON(http.StatusOK, account)
 }
 
+// SetOpeningBalance 修改账户的期初余额，当前余额随之变动同样的差额。
+// 已对账的账户不能修改，否则已完成的对账不再与对账单一致，差额只能通过对账调整交易修正
+func (h *AccountHandler) SetOpeningBalance(c *gin.Context) {
+	id, ok := pathID(c, "id", "account")
+	if !ok {
+		return
+	}
+	var input struct {
+		OpeningBalance *models.Money `json:"opening_balance" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := h.DB.Begin()
+	var account models.Account
+	if err := tx.Scopes(ledgerScope(c)).First(&account, id).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+	var reconciled int
+	tx.Model(&models.Reconciliation{}).Where("account_id = ? AND finished_at IS NOT NULL", account.ID).Count(&reconciled)
+	if reconciled > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Balance of a reconciled account can only be corrected by reconciliation"})
+		return
+	}
+
+	delta := *input.OpeningBalance - account.OpeningBalance
+	if err := tx.Model(&account).UpdateColumns(map[string]interface{}{
+		"opening_balance": gorm.Expr("opening_balance + ?", delta),
+		"balance":         gorm.Expr("balance + ?", delta),
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// 期初余额变化后所有余额快照都已失效
+	if err := invalidateSnapshots(tx, "", account.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.First(&account, account.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tx.Commit()
+
+	c.JSON(http.StatusOK, account)
+}
+
 // DeleteAccount 删除账户
 func (h *AccountHandler) DeleteAccount(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := pathID(c, "id", "account")
+	if !ok {
+		return
+	}
 	var account models.Account
 	
-	if err := h.DB.First(&account, id).Error; err != nil {
+	if err := h.DB.Scopes(ledgerScope(c)).First(&account, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
 		return
 	}
 
 	// 检查是否有关联的交易记录
 	var transactionCount int64
-	h.DB.Model(&models.Transaction{}).Where("account_id = ?", id).Count(&transactionCount)
+	h.DB.Model(&models.Transaction{}).Where("account_id = ? OR to_account_id = ?", id, id).Count(&transactionCount)
 	if transactionCount > 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无法删除有关联交易记录的账户，请先删除相关交易"})
 		return
 	}
 
+	// 检查是否有关联的周期交易
+	var recurringCount int64
+	h.DB.Model(&models.RecurringTransaction{}).Where("account_id = ? OR to_account_id = ?", id, id).Count(&recurringCount)
+	if recurringCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无法删除有关联周期交易的账户，请先删除相关周期交易"})
+		return
+	}
+
+	if err := invalidateSnapshots(h.DB, "", account.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	if err := h.DB.Delete(&account).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -103,3 +274,32 @@ func (h *AccountHandler) DeleteAccount(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
 }
+
+// validateAccount 校验账户类型及类型相关的设置，未指定类型时默认为借记账户。
+// 额度和账单日只适用于信用卡，还款日只适用于信用卡和贷款
+func validateAccount(account *models.Account) error {
+	switch account.Type {
+	case "":
+		account.Type = models.AccountDebit
+	case models.AccountCash, models.AccountDebit, models.AccountCreditCard, models.AccountLoan, models.AccountInvestment:
+	default:
+		return &requestError{http.StatusBadRequest, "Type must be 'cash', 'debit', 'credit_card', 'loan' or 'investment'"}
+	}
+	account.AvailableCredit = nil
+
+	if account.Type != models.AccountCreditCard && (account.CreditLimit != 0 || account.StatementDay != 0) {
+		return &requestError{http.StatusBadRequest, "Credit limit and statement day only apply to credit cards"}
+	}
+	if !account.IsLiability() && account.PaymentDueDay != 0 {
+		return &requestError{http.StatusBadRequest, "Payment due day only applies to credit cards and loans"}
+	}
+	if account.CreditLimit < 0 {
+		return &requestError{http.StatusBadRequest, "Credit limit cannot be negative"}
+	}
+	for _, day := range []int{account.StatementDay, account.PaymentDueDay} {
+		if day < 0 || day > 31 {
+			return &requestError{http.StatusBadRequest, "Statement day and payment due day must be between 1 and 31"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance/auth"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type AuthHandler struct {
+	DB           *gorm.DB
+	SessionTTL   time.Duration
+	BaseCurrency string // 新用户个人账本的默认本位币
+}
+
+const minPasswordLength = 8
+
+// ledgerTables 按账本隔离的数据表
+var ledgerTables = []string{"accounts", "categories", "transactions", "budgets", "recurring_transactions", "import_profiles", "exchange_rates", "settings"}
+
+// Register 注册新用户并直接登录，同时为其创建个人账本。第一个注册的用户的个人账本接管
+// 启用登录之前的已有数据，其他用户的个人账本获得一套默认收支分类
+func (h *AuthHandler) Register(c *gin.Context) {
+	var input models.Credentials
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	username := strings.TrimSpace(input.Username)
+	if len(username) < 3 || len(username) > 64 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username must be 3 to 64 characters"})
+		return
+	}
+	if len(input.Password) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters"})
+		return
+	}
+
+	hash, err := auth.HashPassword(input.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := h.DB.Begin()
+
+	var userCount int64
+	tx.Model(&models.User{}).Count(&userCount)
+	var existing int64
+	tx.Model(&models.User{}).Where("username = ?", username).Count(&existing)
+	if existing > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+		return
+	}
+
+	user := models.User{Username: username, PasswordHash: hash}
+	if err := tx.Create(&user).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ledger := models.Ledger{Name: "个人账本"}
+	if err := createLedger(tx, &ledger, user.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if userCount == 0 {
+		for _, table := range ledgerTables {
+			if err := tx.Exec("UPDATE "+table+" SET ledger_id = ? WHERE ledger_id IS NULL OR ledger_id = 0", ledger.ID).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if err := tx.Exec("UPDATE transactions SET created_by = ? WHERE created_by IS NULL OR created_by = 0", user.ID).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := seedDefaultCategories(tx, ledger.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := seedBaseCurrency(tx, ledger.ID, h.BaseCurrency); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, session, err := auth.NewSession(tx, user.ID, h.SessionTTL)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"user":       user,
+		"token":      token,
+		"expires_at": session.ExpiresAt,
+	})
+}
+
+// Login 校验用户名和密码，返回新的会话令牌
+func (h *AuthHandler) Login(c *gin.Context) {
+	var input models.Credentials
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Where("username = ?", strings.TrimSpace(input.Username)).First(&user).Error; err != nil ||
+		!auth.CheckPassword(user.PasswordHash, input.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+		return
+	}
+
+	token, session, err := auth.NewSession(h.DB, user.ID, h.SessionTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":       user,
+		"token":      token,
+		"expires_at": session.ExpiresAt,
+	})
+}
+
+// Logout 注销当前会话
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if err := auth.DeleteSession(h.DB, middleware.CurrentToken(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// GetCurrentUser 获取当前登录用户
+func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
+	var user models.User
+	if err := h.DB.First(&user, middleware.CurrentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// seedDefaultCategories 为还没有分类的账本初始化默认收支分类
+func seedDefaultCategories(tx *gorm.DB, ledgerID uint) error {
+	var count int64
+	tx.Model(&models.Category{}).Where("ledger_id = ?", ledgerID).Count(&count)
+	if count > 0 {
+		return nil
+	}
+	defaultCategories := []models.Category{
+		{Name: "工资", Type: "income"},
+		{Name: "奖金", Type: "income"},
+		{Name: "投资收益", Type: "income"},
+		{Name: "其他收入", Type: "income"},
+		{Name: "餐饮", Type: "expense"},
+		{Name: "交通", Type: "expense"},
+		{Name: "购物", Type: "expense"},
+		{Name: "住房", Type: "expense"},
+		{Name: "医疗", Type: "expense"},
+		{Name: "其他支出", Type: "expense"},
+	}
+	for _, category := range defaultCategories {
+		category.LedgerID = ledgerID
+		if err := tx.Create(&category).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
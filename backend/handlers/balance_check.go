@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance/middleware"
+	"personal-finance/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// BalanceDiscrepancy 账户记录的余额与按期初余额加上所有交易重算的结果不一致
+type BalanceDiscrepancy struct {
+	AccountID       uint         `json:"account_id"`
+	LedgerID        uint         `json:"-"`
+	Name            string       `json:"name"`
+	Currency        string       `json:"currency"`
+	RecordedBalance models.Money `json:"recorded_balance"`
+	ComputedBalance models.Money `json:"computed_balance"`
+	Difference      models.Money `json:"difference"` // 记录的余额减重算的余额
+}
+
+// CheckBalances 按期初余额和交易重算当前账本所有账户的余额，报告不一致的账户
+func (h *AccountHandler) CheckBalances(c *gin.Context) {
+	discrepancies, err := verifyBalances(h.DB, ledgerScope(c), false, "api", 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"consistent":    len(discrepancies) == 0,
+		"discrepancies": discrepancies,
+	})
+}
+
+// RepairBalances 将余额不一致的账户改为重算结果，并为每个账户写入修复记录
+func (h *AccountHandler) RepairBalances(c *gin.Context) {
+	discrepancies, err := verifyBalances(h.DB, ledgerScope(c), true, "api", middleware.CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"repaired":      len(discrepancies),
+		"discrepancies": discrepancies,
+	})
+}
+
+// GetBalanceAudits 获取当前账本的余额修复记录，最近的在前
+func (h *AccountHandler) GetBalanceAudits(c *gin.Context) {
+	var audits []models.BalanceAudit
+	if err := h.DB.Scopes(ledgerScope(c)).Order("id DESC").Find(&audits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, audits)
+}
+
+// VerifyBalances 检查所有账本的账户余额，供启动检查使用；repair 为真时修复并写入修复记录
+func VerifyBalances(db *gorm.DB, repair bool) ([]BalanceDiscrepancy, error) {
+	return verifyBalances(db, nil, repair, "startup", 0)
+}
+
+// verifyBalances 按期初余额加上所有交易的影响重算 scope 范围内（为空时为全部）账户的余额，
+// 返回不一致的账户。repair 为真时在同一事务中改正余额并写入 BalanceAudit
+func verifyBalances(db *gorm.DB, scope func(*gorm.DB) *gorm.DB, repair bool, source string, userID uint) ([]BalanceDiscrepancy, error) {
+	tx := db
+	if repair {
+		tx = db.Begin()
+	}
+	query := tx
+	if scope != nil {
+		query = tx.Scopes(scope)
+	}
+
+	discrepancies, err := findBalanceDiscrepancies(query)
+	if err != nil || !repair {
+		if repair {
+			tx.Rollback()
+		}
+		return discrepancies, err
+	}
+
+	for _, discrepancy := range discrepancies {
+		if err := tx.Model(&models.Account{}).Where("id = ?", discrepancy.AccountID).
+			UpdateColumn("balance", discrepancy.ComputedBalance).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Create(&models.BalanceAudit{
+			LedgerID:   discrepancy.LedgerID,
+			AccountID:  discrepancy.AccountID,
+			OldBalance: discrepancy.RecordedBalance,
+			NewBalance: discrepancy.ComputedBalance,
+			Source:     source,
+			CreatedBy:  userID,
+		}).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	return discrepancies, tx.Commit().Error
+}
+
+// findBalanceDiscrepancies 按账户和交易类型汇总交易金额，与账户记录的余额比较
+func findBalanceDiscrepancies(query *gorm.DB) ([]BalanceDiscrepancy, error) {
+	var accounts []models.Account
+	if err := query.Order("id").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+
+	var outgoing []struct {
+		AccountID uint
+		Type      string
+		Total     models.Money
+	}
+	if err := query.Model(&models.Transaction{}).Select("account_id, type, SUM(amount) AS total").
+		Group("account_id, type").Scan(&outgoing).Error; err != nil {
+		return nil, err
+	}
+	var incoming []struct {
+		ToAccountID uint
+		Total       models.Money
+	}
+	if err := query.Model(&models.Transaction{}).Select("to_account_id, SUM(to_amount) AS total").
+		Where("type = ?", "transfer").Group("to_account_id").Scan(&incoming).Error; err != nil {
+		return nil, err
+	}
+
+	movement := make(map[uint]models.Money)
+	for _, row := range outgoing {
+		movement[row.AccountID] += balanceDelta(models.Transaction{Type: row.Type, Amount: row.Total})
+	}
+	for _, row := range incoming {
+		movement[row.ToAccountID] += row.Total
+	}
+
+	discrepancies := []BalanceDiscrepancy{}
+	for _, account := range accounts {
+		computed := account.OpeningBalance + movement[account.ID]
+		if computed != account.Balance {
+			discrepancies = append(discrepancies, BalanceDiscrepancy{
+				AccountID:       account.ID,
+				LedgerID:        account.LedgerID,
+				Name:            account.Name,
+				Currency:        account.Currency,
+				RecordedBalance: account.Balance,
+				ComputedBalance: computed,
+				Difference:      account.Balance - computed,
+			})
+		}
+	}
+	return discrepancies, nil
+}
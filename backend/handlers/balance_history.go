@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// maxBalancePoints 单次查询最多返回的时间点数
+	maxBalancePoints = 1000
+	// snapshotMinTransactions 一次查询中某账户需推算的交易达到该数量时，为其写入月末余额快照
+	snapshotMinTransactions = 100
+)
+
+// balanceSeries 单个账户的余额走势（账户币种），与 dates 一一对应
+type balanceSeries struct {
+	AccountID uint           `json:"account_id"`
+	Name      string         `json:"name"`
+	Type      string         `json:"type"`
+	Currency  string         `json:"currency"`
+	Balances  []models.Money `json:"balances"`
+}
+
+// GetBalanceHistory 按日、周（周日为一周结束）或月返回各账户的余额走势，以及换算为本位币的合计（净资产）。
+// 每个时间点为该区间结束时的余额，最后一个点为 end_date。余额由期初余额加上交易推算，
+// 交易较多的账户会从最近的月末快照开始推算
+func (h *AccountHandler) GetBalanceHistory(c *gin.Context) {
+	interval := c.DefaultQuery("interval", "monthly")
+	if interval != "daily" && interval != "weekly" && interval != "monthly" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Interval must be 'daily', 'weekly' or 'monthly'"})
+		return
+	}
+	endDate, err := normalizeDate(c.Query("end_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format. Use YYYY-MM-DD"})
+		return
+	}
+	end := mustParseDate(endDate)
+
+	// 未指定开始日期时，按日为最近 30 天，按周为最近 12 周，按月为最近 12 个月
+	var start time.Time
+	if startDate := c.Query("start_date"); startDate != "" {
+		if start, err = time.Parse("2006-01-02", startDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format. Use YYYY-MM-DD"})
+			return
+		}
+	} else {
+		switch interval {
+		case "daily":
+			start = end.AddDate(0, 0, -29)
+		case "weekly":
+			start = end.AddDate(0, 0, -7*11)
+		case "monthly":
+			start = time.Date(end.Year(), end.Month()-11, 1, 0, 0, 0, 0, time.UTC)
+		}
+	}
+	if start.After(end) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start date must not be after end date"})
+		return
+	}
+
+	dates := balancePoints(start, end, interval)
+	if len(dates) > maxBalancePoints {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Too many points (%d); use a longer interval or a shorter range", len(dates))})
+		return
+	}
+
+	accountIDs, err := queryIDs(c, "account_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 在同一事务中读取交易并写入快照，避免快照与并发修改的交易不一致
+	tx := h.DB.Begin()
+	query := tx.Scopes(ledgerScope(c))
+	if len(accountIDs) > 0 {
+		query = query.Where("id IN (?)", accountIDs)
+	}
+	var accounts []models.Account
+	if err := query.Order("id").Find(&accounts).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cv, err := newCurrencyConverter(tx, middleware.CurrentLedgerID(c))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	today := time.Now().Format("2006-01-02")
+	series := make([]balanceSeries, 0, len(accounts))
+	total := make([]models.Money, len(dates))
+	for _, account := range accounts {
+		balances, err := accountBalanceSeries(tx, account, dates, today)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for k, balance := range balances {
+			converted, err := cv.toBase(balance, account.Currency, dates[k])
+			if err != nil {
+				tx.Rollback()
+				c.JSON(conversionStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+			total[k] += converted
+		}
+		series = append(series, balanceSeries{
+			AccountID: account.ID,
+			Name:      account.Name,
+			Type:      account.Type,
+			Currency:  account.Currency,
+			Balances:  balances,
+		})
+	}
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"interval":      interval,
+		"start_date":    start.Format("2006-01-02"),
+		"end_date":      endDate,
+		"base_currency": cv.base,
+		"dates":         dates,
+		"accounts":      series,
+		"total":         total,
+	})
+}
+
+// balancePoints 返回 start 至 end 之间各区间的结束日期，最后一个区间截止到 end
+func balancePoints(start, end time.Time, interval string) []string {
+	var dates []string
+	for t := bucketEnd(start, interval); t.Before(end); t = bucketEnd(t.AddDate(0, 0, 1), interval) {
+		dates = append(dates, t.Format("2006-01-02"))
+	}
+	return append(dates, end.Format("2006-01-02"))
+}
+
+// bucketEnd 返回 t 所在区间的最后一天：按日为当天，按周为当周周日，按月为当月月末
+func bucketEnd(t time.Time, interval string) time.Time {
+	switch interval {
+	case "weekly":
+		return t.AddDate(0, 0, (7-int(t.Weekday()))%7)
+	case "monthly":
+		return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	}
+	return t
+}
+
+// accountBalanceSeries 推算账户在各日期结束时的余额：从第一个日期之前最近的快照（没有时为期初余额）开始，
+// 依次累加之后的交易。需推算的交易较多时，顺带为今天之前的月末写入快照
+func accountBalanceSeries(tx *gorm.DB, account models.Account, dates []string, today string) ([]models.Money, error) {
+	balance, from := account.OpeningBalance, ""
+	var snapshot models.BalanceSnapshot
+	err := tx.Where("account_id = ? AND date <= ?", account.ID, dates[0]).Order("date DESC").First(&snapshot).Error
+	switch {
+	case err == nil:
+		balance, from = snapshot.Balance, snapshot.Date
+	case !gorm.IsRecordNotFoundError(err):
+		return nil, err
+	}
+
+	last := dates[len(dates)-1]
+	var transactions []models.Transaction
+	if err := tx.Where("(account_id = ? OR to_account_id = ?) AND date > ? AND date <= ?", account.ID, account.ID, from, last).
+		Order("date, id").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	var snapshotDates []string
+	if len(transactions) >= snapshotMinTransactions {
+		for t := bucketEnd(mustParseDate(transactions[0].Date), "monthly"); ; t = bucketEnd(t.AddDate(0, 0, 1), "monthly") {
+			date := t.Format("2006-01-02")
+			if date > last || date >= today {
+				break
+			}
+			snapshotDates = append(snapshotDates, date)
+		}
+	}
+
+	i := 0
+	advance := func(date string) {
+		for ; i < len(transactions) && transactions[i].Date <= date; i++ {
+			balance += accountDelta(transactions[i], account.ID)
+		}
+	}
+	balances := make([]models.Money, len(dates))
+	for p, s := 0, 0; p < len(dates) || s < len(snapshotDates); {
+		if s < len(snapshotDates) && (p == len(dates) || snapshotDates[s] < dates[p]) {
+			advance(snapshotDates[s])
+			// 快照只是缓存，写入失败（如并发查询已写入同一快照）不影响结果
+			tx.Create(&models.BalanceSnapshot{
+				LedgerID:  account.LedgerID,
+				AccountID: account.ID,
+				Date:      snapshotDates[s],
+				Balance:   balance,
+			})
+			s++
+			continue
+		}
+		advance(dates[p])
+		balances[p] = balance
+		p++
+	}
+	return balances, nil
+}
+
+// invalidateSnapshots 删除账户在 date 当天及之后的余额快照，date 为空时删除全部
+func invalidateSnapshots(tx *gorm.DB, date string, accountIDs ...uint) error {
+	query := tx.Where("account_id IN (?)", accountIDs)
+	if date != "" {
+		query = query.Where("date >= ?", date)
+	}
+	return query.Delete(&models.BalanceSnapshot{}).Error
+}
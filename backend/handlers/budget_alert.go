@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"personal-finance/models"
+	"personal-finance/notifier"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// maxBudgetThreshold 提醒阈值的上限（百分比）
+const maxBudgetThreshold = 1000
+
+// BudgetAlerter 在交易新增或修改后检查预算的提醒阈值，每个预算每期每个阈值只提醒一次
+type BudgetAlerter struct {
+	DB       *gorm.DB
+	Notifier notifier.Notifier // 为空时只记录提醒，不发送
+}
+
+// Check 检查账本中覆盖 dates 的预算周期是否越过提醒阈值，新越过的阈值写入提醒记录并在后台发送。
+// a 为 nil 时不做任何事；检查失败只记录日志，不影响交易本身
+func (a *BudgetAlerter) Check(ledgerID uint, dates ...string) {
+	if a == nil || len(dates) == 0 {
+		return
+	}
+	alerts, err := a.evaluate(ledgerID, dates)
+	if err != nil {
+		log.Printf("账本 %d 的预算提醒检查失败: %v", ledgerID, err)
+	}
+	if len(alerts) > 0 && a.Notifier != nil {
+		go a.send(alerts)
+	}
+}
+
+// pendingAlert 已记录、待发送的提醒
+type pendingAlert struct {
+	record models.BudgetAlert
+	alert  notifier.Alert
+}
+
+// evaluate 计算相关预算周期的执行情况，为新越过的阈值写入提醒记录
+func (a *BudgetAlerter) evaluate(ledgerID uint, dates []string) ([]pendingAlert, error) {
+	var budgets []models.Budget
+	if err := a.DB.Where("ledger_id = ? AND thresholds <> ''", ledgerID).
+		Preload("Category").Preload("Tag").Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+
+	cv, err := newCurrencyConverter(a.DB, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	owned := a.DB.Where("ledger_id = ?", ledgerID)
+
+	var alerts []pendingAlert
+	for _, budget := range budgets {
+		periods := budgetPeriodsCovering(budget, dates)
+		if len(periods) == 0 {
+			continue
+		}
+		categoryIDs, err := budgetCategories(owned, budget, false)
+		if err != nil {
+			return alerts, err
+		}
+		statuses, err := budgetPeriodStatuses(owned, cv, categoryIDs, budget, periods[len(periods)-1])
+		if err != nil {
+			return alerts, err
+		}
+
+		for _, n := range periods {
+			fired, err := a.fire(budget, statuses[n])
+			alerts = append(alerts, fired...)
+			if err != nil {
+				return alerts, err
+			}
+		}
+	}
+	return alerts, nil
+}
+
+// fire 为一个周期中已越过但尚未提醒的阈值写入提醒记录
+func (a *BudgetAlerter) fire(budget models.Budget, status budgetPeriodStatus) ([]pendingAlert, error) {
+	var sent []int
+	if err := a.DB.Model(&models.BudgetAlert{}).
+		Where("budget_id = ? AND period_start = ?", budget.ID, status.StartDate).
+		Pluck("threshold", &sent).Error; err != nil {
+		return nil, err
+	}
+	fired := make(map[int]bool, len(sent))
+	for _, threshold := range sent {
+		fired[threshold] = true
+	}
+
+	// 可用金额不为正时，任何支出都视为越过所有阈值
+	exhausted := status.Available <= 0 && status.ActualExpense > 0
+
+	var alerts []pendingAlert
+	for _, threshold := range budget.Thresholds {
+		if fired[threshold] || !(exhausted || status.PercentageUsed >= float64(threshold)) {
+			continue
+		}
+		record := models.BudgetAlert{
+			LedgerID:      budget.LedgerID,
+			BudgetID:      budget.ID,
+			PeriodStart:   status.StartDate,
+			PeriodEnd:     status.EndDate,
+			Threshold:     threshold,
+			Available:     status.Available,
+			ActualExpense: status.ActualExpense,
+			Percentage:    status.PercentageUsed,
+		}
+		// 唯一索引冲突说明同一阈值已被并发的检查记录，不再重复提醒
+		if err := a.DB.Create(&record).Error; err != nil {
+			if strings.Contains(err.Error(), "UNIQUE") {
+				continue
+			}
+			return alerts, err
+		}
+		alerts = append(alerts, pendingAlert{
+			record: record,
+			alert: notifier.Alert{
+				BudgetID:      budget.ID,
+				LedgerID:      budget.LedgerID,
+				Subject:       budgetSubject(budget),
+				Threshold:     threshold,
+				PeriodStart:   status.StartDate,
+				PeriodEnd:     status.EndDate,
+				Available:     status.Available,
+				ActualExpense: status.ActualExpense,
+				Percentage:    status.PercentageUsed,
+			},
+		})
+	}
+	return alerts, nil
+}
+
+// send 依次发送提醒，并记录发送时间或失败原因
+func (a *BudgetAlerter) send(alerts []pendingAlert) {
+	for _, pending := range alerts {
+		updates := map[string]interface{}{"sent_at": time.Now()}
+		if err := a.Notifier.Notify(pending.alert); err != nil {
+			log.Printf("预算 %d 的 %d%% 提醒发送失败: %v", pending.record.BudgetID, pending.record.Threshold, err)
+			updates = map[string]interface{}{"error": err.Error()}
+		}
+		a.DB.Model(&models.BudgetAlert{}).Where("id = ?", pending.record.ID).Updates(updates)
+	}
+}
+
+// budgetPeriodsCovering 返回包含 dates 中任一日期的预算周期序号，按升序排列
+func budgetPeriodsCovering(budget models.Budget, dates []string) []int {
+	seen := make(map[int]bool)
+	var periods []int
+	for _, date := range dates {
+		n := budgetPeriodIndex(budget, date)
+		startDate, endDate, ok := budgetPeriod(budget, n)
+		if !ok || date < startDate || date > endDate || seen[n] {
+			continue
+		}
+		seen[n] = true
+		periods = append(periods, n)
+	}
+	sort.Ints(periods)
+	return periods
+}
+
+// budgetSubject 返回预算的对象名称，如“餐饮”或“餐饮 #旅行”
+func budgetSubject(budget models.Budget) string {
+	var parts []string
+	if budget.CategoryID != 0 {
+		parts = append(parts, budget.Category.Name)
+	}
+	if budget.Tag != nil {
+		parts = append(parts, "#"+budget.Tag.Name)
+	}
+	return strings.Join(parts, " ")
+}
+
+// normalizeThresholds 校验提醒阈值（1~1000 的百分比），去重并按升序排列
+func normalizeThresholds(thresholds []int) (models.IntList, error) {
+	seen := make(map[int]bool, len(thresholds))
+	var result models.IntList
+	for _, threshold := range thresholds {
+		if threshold < 1 || threshold > maxBudgetThreshold {
+			return nil, &requestError{http.StatusBadRequest, "Thresholds must be between 1 and 1000 percent"}
+		}
+		if !seen[threshold] {
+			seen[threshold] = true
+			result = append(result, threshold)
+		}
+	}
+	sort.Ints(result)
+	return result, nil
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"personal-finance/middleware"
 	"personal-finance/models"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -22,32 +25,23 @@ func (h *BudgetHandler) CreateBudget(c *gin.Context) {
 		return
 	}
 
-	// 验证日期格式
-	_, err := time.Parse("2006-01-02", input.StartDate)
+	thresholds, err := validateBudgetInput(h.DB.Scopes(ledgerScope(c)), input)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format"})
-		return
-	}
-
-	_, err = time.Parse("2006-01-02", input.EndDate)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format"})
+		respondError(c, err)
 		return
 	}
 
 	// 创建预算对象
 	budget := models.Budget{
 		CategoryID: input.CategoryID,
+		TagID:      input.TagID,
 		Amount:     input.Amount,
+		Period:     input.Period,
+		Rollover:   input.Rollover,
 		StartDate:  input.StartDate,
 		EndDate:    input.EndDate,
-	}
-
-	// 检查分类是否存在
-	var category models.Category
-	if err := h.DB.First(&category, budget.CategoryID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found"})
-		return
+		Thresholds: thresholds,
+		LedgerID:   middleware.CurrentLedgerID(c),
 	}
 
 	if err := h.DB.Create(&budget).Error; err != nil {
@@ -55,8 +49,8 @@ func (h *BudgetHandler) CreateBudget(c *gin.Context) {
 		return
 	}
 
-	// 加载关联的分类信息
-	h.DB.Model(&budget).Related(&budget.Category)
+	// 加载关联的分类和标签信息
+	loadBudgetRelations(h.DB, &budget)
 
 	c.JSON(http.StatusCreated, budget)
 }
@@ -65,7 +59,7 @@ func (h *BudgetHandler) CreateBudget(c *gin.Context) {
 func (h *BudgetHandler) GetBudgets(c *gin.Context) {
 	var budgets []models.Budget
 	
-	query := h.DB.Preload("Category")
+	query := h.DB.Scopes(ledgerScope(c), plainBudgets).Preload("Category").Preload("Tag")
 	
 	// 支持按时间范围筛选
 	startDate := c.Query("start_date")
@@ -86,68 +80,434 @@ func (h *BudgetHandler) GetBudgets(c *gin.Context) {
 	c.JSON(http.StatusOK, budgets)
 }
 
-// GetBudgetStatus 获取预算执行状况
+// GetBudgetStatus 获取预算执行状况。周期预算统计 date（默认今天）所在的周期，
+// 可用金额 available 为本期金额加上按结转方式从之前各期结转的金额
 func (h *BudgetHandler) GetBudgetStatus(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := pathID(c, "id", "budget")
+	if !ok {
+		return
+	}
 	var budget models.Budget
 	
-	if err := h.DB.Preload("Category").First(&budget, id).Error; err != nil {
+	if err := h.DB.Scopes(ledgerScope(c), plainBudgets).Preload("Category").Preload("Tag").First(&budget, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
 		return
 	}
 
-	// 计算该预算分类下的实际支出
-	var actualExpense float64
-	h.DB.Model(&models.Transaction{}).
-		Where("category_id = ? AND type = 'expense' AND date(created_at) BETWEEN ? AND ?",
-			budget.CategoryID, budget.StartDate, budget.EndDate).
-		Select("COALESCE(SUM(amount), 0)").Row().Scan(&actualExpense)
+	date, err := normalizeDate(c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
 
-	// 计算预算使用百分比
-	percentageUsed := (actualExpense / budget.Amount) * 100
+	cv, err := newCurrencyConverter(h.DB, middleware.CurrentLedgerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 计算该预算分类（和标签）下的实际支出（本位币），rollup=true 时包含下级分类
+	categoryIDs, err := budgetCategories(h.DB.Scopes(ledgerScope(c)), budget, c.Query("rollup") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	n := clampBudgetPeriod(budget, date)
+	statuses, err := budgetPeriodStatuses(h.DB.Scopes(ledgerScope(c)), cv, categoryIDs, budget, n)
+	if err != nil {
+		c.JSON(conversionStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	status := statuses[len(statuses)-1]
 
 	c.JSON(http.StatusOK, gin.H{
 		"budget": budget,
+		"period": gin.H{
+			"start_date": status.StartDate,
+			"end_date":   status.EndDate,
+		},
 		"status": gin.H{
-			"actual_expense":   actualExpense,
-			"percentage_used": percentageUsed,
-			"remaining":       budget.Amount - actualExpense,
+			"carried_over":    status.CarriedOver,
+			"available":       status.Available,
+			"actual_expense":  status.ActualExpense,
+			"percentage_used": status.PercentageUsed,
+			"remaining":       status.Remaining,
 		},
+		"base_currency": cv.base,
 	})
 }
 
+// budgetPeriodStatus 预算某个周期的计划、结转与实际支出
+type budgetPeriodStatus struct {
+	StartDate      string       `json:"start_date"`
+	EndDate        string       `json:"end_date"`
+	Planned        models.Money `json:"planned"`
+	CarriedOver    models.Money `json:"carried_over"` // 从上一期结转的金额，超支结转为负
+	Available      models.Money `json:"available"`    // 本期可用金额 = planned + carried_over
+	ActualExpense  models.Money `json:"actual_expense"`
+	Remaining      models.Money `json:"remaining"`
+	PercentageUsed float64      `json:"percentage_used"` // 相对于可用金额
+}
+
+// settle 记入结转金额，计算可用金额、剩余金额和使用百分比
+func (s *budgetPeriodStatus) settle(carriedOver models.Money) {
+	s.CarriedOver = carriedOver
+	s.Available = s.Planned + carriedOver
+	s.Remaining = s.Available - s.ActualExpense
+	if s.Available > 0 {
+		s.PercentageUsed = float64(s.ActualExpense) / float64(s.Available) * 100
+	}
+}
+
+const (
+	defaultBudgetHistory = 12
+	maxBudgetHistory     = 120
+)
+
+// GetBudgetHistory 获取预算最近 periods 个周期（默认 12 个，含当前周期）的计划、结转与实际支出，
+// 最近的周期在前；一次性预算只有一个周期
+func (h *BudgetHandler) GetBudgetHistory(c *gin.Context) {
+	id, ok := pathID(c, "id", "budget")
+	if !ok {
+		return
+	}
+	var budget models.Budget
+
+	if err := h.DB.Scopes(ledgerScope(c), plainBudgets).Preload("Category").Preload("Tag").First(&budget, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+		return
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("periods", strconv.Itoa(defaultBudgetHistory)))
+	if err != nil || count < 1 || count > maxBudgetHistory {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("periods must be between 1 and %d", maxBudgetHistory)})
+		return
+	}
+
+	cv, err := newCurrencyConverter(h.DB, middleware.CurrentLedgerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	categoryIDs, err := budgetCategories(h.DB.Scopes(ledgerScope(c)), budget, c.Query("rollup") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	statuses, err := budgetPeriodStatuses(h.DB.Scopes(ledgerScope(c)), cv, categoryIDs, budget, clampBudgetPeriod(budget, time.Now().Format("2006-01-02")))
+	if err != nil {
+		c.JSON(conversionStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	periods := []budgetPeriodStatus{}
+	for i := len(statuses) - 1; i >= 0 && len(periods) < count; i-- {
+		periods = append(periods, statuses[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budget":        budget,
+		"periods":       periods,
+		"base_currency": cv.base,
+	})
+}
+
+// GetBudgetAlerts 获取预算已触发的阈值提醒，最近的在前
+func (h *BudgetHandler) GetBudgetAlerts(c *gin.Context) {
+	id, ok := pathID(c, "id", "budget")
+	if !ok {
+		return
+	}
+	var budget models.Budget
+	if err := h.DB.Scopes(ledgerScope(c), plainBudgets).First(&budget, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+		return
+	}
+
+	alerts := []models.BudgetAlert{}
+	if err := h.DB.Where("budget_id = ?", budget.ID).Order("period_start DESC, threshold DESC").Find(&alerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
 // UpdateBudget 更新预算
 func (h *BudgetHandler) UpdateBudget(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := pathID(c, "id", "budget")
+	if !ok {
+		return
+	}
 	var budget models.Budget
-	
-	if err := h.DB.First(&budget, id).Error; err != nil {
+
+	if err := h.DB.Scopes(ledgerScope(c), plainBudgets).First(&budget, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
 		return
 	}
 
-	if err := c.ShouldBindJSON(&budget); err != nil {
+	// 绑定到输入结构再逐项复制，请求体不能改写编号、所属账本、信封类型等字段
+	var input models.BudgetInput
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	thresholds, err := validateBudgetInput(h.DB.Scopes(ledgerScope(c)), input)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	budget.CategoryID = input.CategoryID
+	budget.TagID = input.TagID
+	budget.Amount = input.Amount
+	budget.Period = input.Period
+	budget.Rollover = input.Rollover
+	budget.StartDate = input.StartDate
+	budget.EndDate = input.EndDate
+	budget.Thresholds = thresholds
 
 	if err := h.DB.Save(&budget).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 加载关联的分类信息
-	h.DB.Model(&budget).Related(&budget.Category)
+	// 加载关联的分类和标签信息
+	loadBudgetRelations(h.DB, &budget)
 	c.JSON(http.StatusOK, budget)
 }
 
 // DeleteBudget 删除预算
 func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
-	id := c.Param("id")
-	if err := h.DB.Delete(&models.Budget{}, id).Error; err != nil {
+	id, ok := pathID(c, "id", "budget")
+	if !ok {
+		return
+	}
+	if err := h.DB.Scopes(ledgerScope(c), plainBudgets).Delete(&models.Budget{}, id).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Budget deleted successfully"})
 }
+
+// validateBudgetInput 校验新建或修改预算的输入：日期、金额、周期和提醒阈值，以及分类和标签须属于 db 范围内的账本。
+// 返回规范化后的提醒阈值
+func validateBudgetInput(db *gorm.DB, input models.BudgetInput) (models.IntList, error) {
+	startDate, err := time.Parse("2006-01-02", input.StartDate)
+	if err != nil {
+		return nil, &requestError{http.StatusBadRequest, "Invalid start date format. Use YYYY-MM-DD"}
+	}
+	if input.EndDate != "" {
+		endDate, err := time.Parse("2006-01-02", input.EndDate)
+		if err != nil {
+			return nil, &requestError{http.StatusBadRequest, "Invalid end date format. Use YYYY-MM-DD"}
+		}
+		if endDate.Before(startDate) {
+			return nil, &requestError{http.StatusBadRequest, "End date must be after start date"}
+		}
+	}
+	if input.Amount <= 0 {
+		return nil, &requestError{http.StatusBadRequest, "Amount must be greater than 0"}
+	}
+	if err := validateBudgetPeriod(input.Period, input.Rollover, input.EndDate); err != nil {
+		return nil, err
+	}
+	thresholds, err := normalizeThresholds(input.Thresholds)
+	if err != nil {
+		return nil, err
+	}
+	return thresholds, validateBudgetScope(db, input.CategoryID, input.TagID)
+}
+
+// validateBudgetScope 检查预算的分类和标签：至少指定一个，且都属于 db 范围内的账本
+func validateBudgetScope(db *gorm.DB, categoryID, tagID uint) error {
+	if categoryID == 0 && tagID == 0 {
+		return &requestError{http.StatusBadRequest, "Category or tag is required"}
+	}
+	if categoryID != 0 {
+		var category models.Category
+		if err := db.First(&category, categoryID).Error; err != nil {
+			return &requestError{http.StatusBadRequest, "Category not found"}
+		}
+	}
+	if tagID != 0 {
+		var tag models.Tag
+		if err := db.First(&tag, tagID).Error; err != nil {
+			return &requestError{http.StatusBadRequest, "Tag not found"}
+		}
+	}
+	return nil
+}
+
+// plainBudgets 排除零基预算的信封，信封只能通过 /envelopes 接口管理
+func plainBudgets(db *gorm.DB) *gorm.DB {
+	return db.Where("kind = ''")
+}
+
+// budgetCategories 返回预算统计的分类 ID，未指定分类的预算返回 nil（不限分类）
+func budgetCategories(db *gorm.DB, budget models.Budget, rollup bool) ([]uint, error) {
+	if budget.CategoryID == 0 {
+		return nil, nil
+	}
+	return categoryScope(db, budget.CategoryID, rollup)
+}
+
+// loadBudgetRelations 加载预算关联的分类和标签
+func loadBudgetRelations(db *gorm.DB, budget *models.Budget) {
+	if budget.CategoryID != 0 {
+		db.Model(budget).Related(&budget.Category)
+	}
+	budget.Tag = nil
+	if budget.TagID != 0 {
+		var tag models.Tag
+		if db.First(&tag, budget.TagID).Error == nil {
+			budget.Tag = &tag
+		}
+	}
+}
+
+// validateBudgetPeriod 校验预算周期和结转方式，一次性预算必须有结束日期且不能结转
+func validateBudgetPeriod(period, rollover, endDate string) error {
+	switch period {
+	case "", "weekly", "monthly", "quarterly", "yearly":
+	default:
+		return &requestError{http.StatusBadRequest, "Period must be 'weekly', 'monthly', 'quarterly' or 'yearly'"}
+	}
+	if period == "" && endDate == "" {
+		return &requestError{http.StatusBadRequest, "End date is required for a one-off budget"}
+	}
+	switch rollover {
+	case "", "none":
+	case "surplus", "deficit", "both":
+		if period == "" {
+			return &requestError{http.StatusBadRequest, "Rollover requires a recurring budget"}
+		}
+	default:
+		return &requestError{http.StatusBadRequest, "Rollover must be 'none', 'surplus', 'deficit' or 'both'"}
+	}
+	return nil
+}
+
+// rolloverAmount 按结转方式返回一期剩余金额中结转到下一期的部分
+func rolloverAmount(rollover string, remaining models.Money) models.Money {
+	switch {
+	case remaining > 0 && (rollover == "surplus" || rollover == "both"):
+		return remaining
+	case remaining < 0 && (rollover == "deficit" || rollover == "both"):
+		return remaining
+	default:
+		return 0
+	}
+}
+
+// budgetPeriodStatuses 计算预算从第一个周期到第 last 个周期（不超过最后一个周期）的执行情况，
+// 每期的剩余金额按结转方式计入下一期。所有周期的支出一次载入后按日期归入各期
+func budgetPeriodStatuses(db *gorm.DB, cv *currencyConverter, categoryIDs []uint, budget models.Budget, last int) ([]budgetPeriodStatus, error) {
+	var statuses []budgetPeriodStatus
+	for n := 0; n <= last; n++ {
+		startDate, endDate, ok := budgetPeriod(budget, n)
+		if !ok {
+			break
+		}
+		statuses = append(statuses, budgetPeriodStatus{StartDate: startDate, EndDate: endDate, Planned: budget.Amount})
+	}
+	if len(statuses) == 0 {
+		return nil, fmt.Errorf("budget %d has no period", budget.ID)
+	}
+
+	entries, err := budgetEntries(db, cv, categoryIDs, budget.TagID, statuses[0].StartDate, statuses[len(statuses)-1].EndDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if n := budgetPeriodIndex(budget, entry.Date); n >= 0 && n < len(statuses) {
+			statuses[n].ActualExpense += entry.Amount
+		}
+	}
+
+	var carry models.Money
+	for i := range statuses {
+		statuses[i].settle(carry)
+		carry = rolloverAmount(budget.Rollover, statuses[i].Remaining)
+	}
+	return statuses, nil
+}
+
+// budgetPeriodStart 返回从 start 起第 n 个周期（从 0 开始）的开始日期。按月、季、年的周期
+// 以 start 的日期为准，当月没有该日时取月末
+func budgetPeriodStart(start time.Time, period string, n int) time.Time {
+	switch period {
+	case "weekly":
+		return start.AddDate(0, 0, 7*n)
+	case "quarterly":
+		return addMonths(start, 3*n)
+	case "yearly":
+		return addMonths(start, 12*n)
+	default:
+		return addMonths(start, n)
+	}
+}
+
+// budgetPeriod 返回预算第 n 个周期的起止日期，最后一个周期截止于 EndDate；
+// 一次性预算只有第 0 个周期。周期不存在时 ok 为 false
+func budgetPeriod(budget models.Budget, n int) (startDate, endDate string, ok bool) {
+	if budget.Period == "" {
+		return budget.StartDate, budget.EndDate, n == 0
+	}
+	start, err := time.Parse("2006-01-02", budget.StartDate)
+	if err != nil || n < 0 {
+		return "", "", false
+	}
+	startDate = budgetPeriodStart(start, budget.Period, n).Format("2006-01-02")
+	endDate = budgetPeriodStart(start, budget.Period, n+1).AddDate(0, 0, -1).Format("2006-01-02")
+	if budget.EndDate != "" {
+		if startDate > budget.EndDate {
+			return "", "", false
+		}
+		if endDate > budget.EndDate {
+			endDate = budget.EndDate
+		}
+	}
+	return startDate, endDate, true
+}
+
+// budgetPeriodIndex 返回 date 所在周期的序号，早于预算开始日期时返回 -1；不检查 EndDate
+func budgetPeriodIndex(budget models.Budget, date string) int {
+	if budget.Period == "" {
+		return 0
+	}
+	start, err := time.Parse("2006-01-02", budget.StartDate)
+	if err != nil || date < budget.StartDate {
+		return -1
+	}
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return -1
+	}
+
+	// 先按周期长度估算，再逐期校正
+	n := int(day.Sub(start).Hours()/24) / 7
+	if budget.Period != "weekly" {
+		months := map[string]int{"monthly": 1, "quarterly": 3, "yearly": 12}[budget.Period]
+		n = ((day.Year()-start.Year())*12 + int(day.Month()) - int(start.Month())) / months
+	}
+	for n > 0 && budgetPeriodStart(start, budget.Period, n).After(day) {
+		n--
+	}
+	for !budgetPeriodStart(start, budget.Period, n+1).After(day) {
+		n++
+	}
+	return n
+}
+
+// clampBudgetPeriod 返回 date 所在周期的序号，早于第一个周期时取第一个，晚于最后一个周期时取最后一个
+func clampBudgetPeriod(budget models.Budget, date string) int {
+	if budget.EndDate != "" && date > budget.EndDate {
+		date = budget.EndDate
+	}
+	if n := budgetPeriodIndex(budget, date); n > 0 {
+		return n
+	}
+	return 0
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"personal-finance/middleware"
 	"personal-finance/models"
 
 	"github.com/gin-gonic/gin"
@@ -26,6 +27,13 @@ func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 		return
 	}
 
+	// 验证上级分类
+	category.LedgerID = middleware.CurrentLedgerID(c)
+	if err := validateCategoryParent(h.DB, category); err != nil {
+		respondError(c, err)
+		return
+	}
+
 	if err := h.DB.Create(&category).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -34,13 +42,13 @@ func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 	c.JSON(http.StatusCreated, category)
 }
 
-// GetCategories 获取所有分类
+// GetCategories 获取所有分类，tree=true 时按上下级关系返回树形结构
 func (h *CategoryHandler) GetCategories(c *gin.Context) {
 	var categories []models.Category
 	
 	// 支持按类型筛选
 	categoryType := c.Query("type")
-	query := h.DB
+	query := h.DB.Scopes(ledgerScope(c))
 	if categoryType != "" {
 		query = query.Where("type = ?", categoryType)
 	}
@@ -50,15 +58,23 @@ func (h *CategoryHandler) GetCategories(c *gin.Context) {
 		return
 	}
 
+	if c.Query("tree") == "true" {
+		c.JSON(http.StatusOK, categoryTree(categories))
+		return
+	}
+
 	c.JSON(http.StatusOK, categories)
 }
 
 // UpdateCategory 更新分类
 func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := pathID(c, "id", "category")
+	if !ok {
+		return
+	}
 	var category models.Category
 	
-	if err := h.DB.First(&category, id).Error; err != nil {
+	if err := h.DB.Scopes(ledgerScope(c)).First(&category, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
 		return
 	}
@@ -75,10 +91,27 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 		return
 	}
 
+	// 有下级分类时不能修改类型，否则上下级类型不一致
+	if updatedCategory.Type != category.Type {
+		var childCount int64
+		h.DB.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&childCount)
+		if childCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change type of a category with child categories"})
+			return
+		}
+	}
+
 	// 更新字段
 	category.Name = updatedCategory.Name
 	category.Type = updatedCategory.Type
 	category.Icon = updatedCategory.Icon
+	category.ParentID = updatedCategory.ParentID
+
+	// 验证上级分类
+	if err := validateCategoryParent(h.DB, category); err != nil {
+		respondError(c, err)
+		return
+	}
 
 	if err := h.DB.Save(&category).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -90,18 +123,31 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 
 // DeleteCategory 删除分类
 func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := pathID(c, "id", "category")
+	if !ok {
+		return
+	}
 	
 	// 检查分类是否存在
 	var category models.Category
-	if err := h.DB.First(&category, id).Error; err != nil {
+	if err := h.DB.Scopes(ledgerScope(c)).First(&category, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
 		return
 	}
 
+	// 检查是否有下级分类
+	var childCount int64
+	h.DB.Model(&models.Category{}).Where("parent_id = ?", id).Count(&childCount)
+	if childCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete category with child categories"})
+		return
+	}
+
 	// 检查是否有关联的交易
 	var transactionCount int64
-	h.DB.Model(&models.Transaction{}).Where("category_id = ?", id).Count(&transactionCount)
+	h.DB.Model(&models.Transaction{}).
+		Where("category_id = ? OR id IN (SELECT transaction_id FROM transaction_splits WHERE category_id = ?)", id, id).
+		Count(&transactionCount)
 	if transactionCount > 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete category with associated transactions"})
 		return
@@ -115,6 +161,14 @@ func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 		return
 	}
 
+	// 检查是否有关联的周期交易
+	var recurringCount int64
+	h.DB.Model(&models.RecurringTransaction{}).Where("category_id = ?", id).Count(&recurringCount)
+	if recurringCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete category with associated recurring transactions"})
+		return
+	}
+
 	if err := h.DB.Delete(&category).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -122,3 +176,104 @@ func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
 }
+
+// validateCategoryParent 校验上级分类存在且属于同一用户、类型与下级一致，且不会形成循环
+func validateCategoryParent(db *gorm.DB, category models.Category) error {
+	if category.ParentID == 0 {
+		return nil
+	}
+	if category.ParentID == category.ID {
+		return &requestError{http.StatusBadRequest, "Category cannot be its own parent"}
+	}
+
+	var parent models.Category
+	owned := db.Where("ledger_id = ?", category.LedgerID)
+	if err := owned.First(&parent, category.ParentID).Error; err != nil {
+		return &requestError{http.StatusNotFound, "Parent category not found"}
+	}
+	if parent.Type != category.Type {
+		return &requestError{http.StatusBadRequest, "Child category must have the same type as its parent"}
+	}
+
+	// 沿上级链向上查找，新建分类（ID 为 0）不可能出现在链中
+	seen := map[uint]bool{parent.ID: true}
+	for parent.ParentID != 0 {
+		if parent.ParentID == category.ID {
+			return &requestError{http.StatusBadRequest, "Parent category would create a cycle"}
+		}
+		if seen[parent.ParentID] {
+			break
+		}
+		seen[parent.ParentID] = true
+		var next models.Category
+		if err := owned.First(&next, parent.ParentID).Error; err != nil {
+			break
+		}
+		parent = next
+	}
+	return nil
+}
+
+// categoryTree 将分类列表组装为树形结构；上级不在列表中的分类作为顶级节点
+func categoryTree(categories []models.Category) []models.Category {
+	children := make(map[uint][]models.Category)
+	ids := make(map[uint]bool)
+	for _, category := range categories {
+		ids[category.ID] = true
+	}
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentID != 0 && ids[category.ParentID] {
+			children[category.ParentID] = append(children[category.ParentID], category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+
+	var attach func(nodes []models.Category) []models.Category
+	attach = func(nodes []models.Category) []models.Category {
+		for i := range nodes {
+			nodes[i].Children = attach(children[nodes[i].ID])
+		}
+		return nodes
+	}
+	return attach(roots)
+}
+
+// categoryAncestors 返回分类的所有上级分类 ID，由近及远
+func categoryAncestors(parents map[uint]uint, id uint) []uint {
+	var ancestors []uint
+	seen := map[uint]bool{id: true}
+	for parent := parents[id]; parent != 0 && !seen[parent]; parent = parents[parent] {
+		seen[parent] = true
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// categoryScope 返回统计某分类时包含的分类 ID；rollup 为 true 时包含 db 范围内的所有下级分类
+func categoryScope(db *gorm.DB, id uint, rollup bool) ([]uint, error) {
+	if !rollup {
+		return []uint{id}, nil
+	}
+
+	var categories []models.Category
+	if err := db.Select("id, parent_id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	parents := make(map[uint]uint)
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+
+	scope := []uint{id}
+	for _, category := range categories {
+		for _, ancestor := range categoryAncestors(parents, category.ID) {
+			if ancestor == id {
+				scope = append(scope, category.ID)
+				break
+			}
+		}
+	}
+	return scope, nil
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"personal-finance/models"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// normalizeCurrency 将币种代码转为大写并校验 ISO 4217 格式
+func normalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !currencyCodePattern.MatchString(code) {
+		return "", fmt.Errorf("invalid currency code %q", code)
+	}
+	return code, nil
+}
+
+// baseCurrency 返回账本设置的本位币
+func baseCurrency(db *gorm.DB, ledgerID uint) string {
+	var setting models.Setting
+	if err := db.Where("ledger_id = ? AND name = ?", ledgerID, models.SettingBaseCurrency).First(&setting).Error; err != nil || setting.Value == "" {
+		return "CNY"
+	}
+	return setting.Value
+}
+
+// seedBaseCurrency 为新账本写入默认本位币；已有设置（如首个用户接管的旧数据）保持不变
+func seedBaseCurrency(tx *gorm.DB, ledgerID uint, currency string) error {
+	if currency == "" {
+		return nil
+	}
+	return tx.Exec("INSERT OR IGNORE INTO settings (ledger_id, name, value) VALUES (?, ?, ?)",
+		ledgerID, models.SettingBaseCurrency, currency).Error
+}
+
+// currencyConverter 按交易日期生效的汇率换算金额，汇率在创建时一次性载入
+type currencyConverter struct {
+	base  string
+	rates map[[2]string][]models.ExchangeRate // 按生效日期升序
+}
+
+// newCurrencyConverter 载入账本的全部汇率，换算目标为账本的本位币
+func newCurrencyConverter(db *gorm.DB, ledgerID uint) (*currencyConverter, error) {
+	var rates []models.ExchangeRate
+	if err := db.Where("ledger_id = ?", ledgerID).Order("date asc").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+
+	cv := &currencyConverter{
+		base:  baseCurrency(db, ledgerID),
+		rates: make(map[[2]string][]models.ExchangeRate),
+	}
+	for _, rate := range rates {
+		pair := [2]string{rate.Currency, rate.QuoteCurrency}
+		cv.rates[pair] = append(cv.rates[pair], rate)
+	}
+	return cv, nil
+}
+
+// latest 返回某货币对在 date 当天或之前最近生效的汇率
+func (cv *currencyConverter) latest(from, to, date string) (float64, bool) {
+	rates := cv.rates[[2]string{from, to}]
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].Date > date })
+	if i == 0 {
+		return 0, false
+	}
+	return rates[i-1].Rate, true
+}
+
+// missingRateError 表示缺少换算所需的汇率，属于可由用户补录修复的错误
+type missingRateError struct {
+	From, To, Date string
+}
+
+func (e *missingRateError) Error() string {
+	return fmt.Sprintf("no exchange rate from %s to %s on %s", e.From, e.To, e.Date)
+}
+
+// conversionStatus 返回换算错误对应的 HTTP 状态码
+func conversionStatus(err error) int {
+	if _, ok := err.(*missingRateError); ok {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// rate 返回 date 当天 from 兑 to 的汇率，支持反向报价
+func (cv *currencyConverter) rate(from, to, date string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if rate, ok := cv.latest(from, to, date); ok {
+		return rate, nil
+	}
+	if rate, ok := cv.latest(to, from, date); ok && rate != 0 {
+		return 1 / rate, nil
+	}
+	return 0, &missingRateError{from, to, date}
+}
+
+// convert 将 date 当天的金额从 from 换算为 to，结果四舍五入到分
+func (cv *currencyConverter) convert(amount models.Money, from, to, date string) (models.Money, error) {
+	rate, err := cv.rate(from, to, date)
+	if err != nil {
+		return 0, err
+	}
+	if rate == 1 {
+		return amount, nil
+	}
+	return models.Money(math.Round(float64(amount) * rate)), nil
+}
+
+// toBase 将金额换算为本位币，未记录币种的金额视为本位币
+func (cv *currencyConverter) toBase(amount models.Money, from, date string) (models.Money, error) {
+	if from == "" {
+		return amount, nil
+	}
+	return cv.convert(amount, from, cv.base, date)
+}
@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// EnvelopeHandler 零基（信封）预算：开始日期之后的收入进入待分配资金，由用户分配到各支出分类的信封，
+// 信封余额随该分类的支出减少。信封是 Kind 为 "envelope" 的预算，Amount 为累计分配的金额
+type EnvelopeHandler struct {
+	DB *gorm.DB
+}
+
+// envelopeStatus 单个信封的分配、支出与余额（本位币）
+type envelopeStatus struct {
+	BudgetID     uint         `json:"budget_id"`
+	CategoryID   uint         `json:"category_id"`
+	CategoryName string       `json:"category_name"`
+	Assigned     models.Money `json:"assigned"`
+	Spent        models.Money `json:"spent"`
+	Balance      models.Money `json:"balance"`
+}
+
+// envelopeSummary 零基预算的整体情况
+type envelopeSummary struct {
+	StartDate         string           `json:"start_date"`
+	TotalIncome       models.Money     `json:"total_income"`
+	Assigned          models.Money     `json:"assigned"`
+	ToBeAssigned      models.Money     `json:"to_be_assigned"`     // 按划拨记录计算，收入被删除后可能为负
+	UnbudgetedExpense models.Money     `json:"unbudgeted_expense"` // 没有信封的分类下的支出
+	Envelopes         []envelopeStatus `json:"envelopes"`
+	BaseCurrency      string           `json:"base_currency"`
+
+	transferred map[uint]models.Money // 按划拨记录计算的各信封累计分配金额
+}
+
+// balance 返回信封的当前余额
+func (s *envelopeSummary) balance(budgetID uint) models.Money {
+	for _, envelope := range s.Envelopes {
+		if envelope.BudgetID == budgetID {
+			return envelope.Balance
+		}
+	}
+	return 0
+}
+
+// EnableEnvelopes 为当前账本启用零基预算，start_date 起的收入进入待分配资金。
+// 已有划拨记录后不能再修改开始日期
+func (h *EnvelopeHandler) EnableEnvelopes(c *gin.Context) {
+	var input struct {
+		StartDate string `json:"start_date" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := time.Parse("2006-01-02", input.StartDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	ledgerID := middleware.CurrentLedgerID(c)
+	var ledger models.Ledger
+	if err := h.DB.First(&ledger, ledgerID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ledger not found"})
+		return
+	}
+
+	var transfers int
+	h.DB.Model(&models.EnvelopeTransfer{}).Where("ledger_id = ?", ledgerID).Count(&transfers)
+	if transfers > 0 && ledger.EnvelopeStartDate != input.StartDate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change the start date after money has been assigned"})
+		return
+	}
+
+	if err := h.DB.Model(&ledger).Update("envelope_start_date", input.StartDate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondSummary(c, http.StatusOK)
+}
+
+// GetEnvelopes 获取待分配资金和各信封的余额
+func (h *EnvelopeHandler) GetEnvelopes(c *gin.Context) {
+	h.respondSummary(c, http.StatusOK)
+}
+
+// AssignEnvelope 从待分配资金分配到分类的信封，信封不存在时自动创建；金额为负表示退回待分配资金
+func (h *EnvelopeHandler) AssignEnvelope(c *gin.Context) {
+	var input struct {
+		CategoryID uint         `json:"category_id" binding:"required"`
+		Amount     models.Money `json:"amount" binding:"required"`
+		Note       string       `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ledgerID := middleware.CurrentLedgerID(c)
+	tx := h.DB.Begin()
+
+	summary, err := loadEnvelopeSummary(tx, ledgerID)
+	if err != nil {
+		tx.Rollback()
+		respondError(c, err)
+		return
+	}
+	envelope, err := findOrCreateEnvelope(tx, ledgerID, summary.StartDate, input.CategoryID)
+	if err != nil {
+		tx.Rollback()
+		respondError(c, err)
+		return
+	}
+
+	transfer := models.EnvelopeTransfer{
+		LedgerID:   ledgerID,
+		ToBudgetID: envelope.ID,
+		Amount:     input.Amount,
+		Note:       input.Note,
+		CreatedBy:  middleware.CurrentUserID(c),
+	}
+	if input.Amount > 0 && input.Amount > summary.ToBeAssigned {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Only %s is left to be assigned", summary.ToBeAssigned)})
+		return
+	}
+	if input.Amount < 0 {
+		if balance := summary.balance(envelope.ID); -input.Amount > balance {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Envelope balance is only %s", balance)})
+			return
+		}
+		transfer.FromBudgetID, transfer.ToBudgetID, transfer.Amount = envelope.ID, 0, -input.Amount
+	}
+
+	if err := moveEnvelopeMoney(tx, &transfer); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tx.Commit()
+
+	h.respondSummary(c, http.StatusOK)
+}
+
+// MoveEnvelope 在两个信封之间移动资金，转出信封的余额必须足够
+func (h *EnvelopeHandler) MoveEnvelope(c *gin.Context) {
+	var input struct {
+		FromCategoryID uint         `json:"from_category_id" binding:"required"`
+		ToCategoryID   uint         `json:"to_category_id" binding:"required"`
+		Amount         models.Money `json:"amount" binding:"required"`
+		Note           string       `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount must be greater than 0"})
+		return
+	}
+	if input.FromCategoryID == input.ToCategoryID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot move money to the same envelope"})
+		return
+	}
+
+	ledgerID := middleware.CurrentLedgerID(c)
+	tx := h.DB.Begin()
+
+	summary, err := loadEnvelopeSummary(tx, ledgerID)
+	if err != nil {
+		tx.Rollback()
+		respondError(c, err)
+		return
+	}
+	var from models.Budget
+	if err := tx.Where("ledger_id = ? AND kind = ? AND category_id = ?", ledgerID, "envelope", input.FromCategoryID).First(&from).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Envelope not found"})
+		return
+	}
+	if balance := summary.balance(from.ID); input.Amount > balance {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Envelope balance is only %s", balance)})
+		return
+	}
+	to, err := findOrCreateEnvelope(tx, ledgerID, summary.StartDate, input.ToCategoryID)
+	if err != nil {
+		tx.Rollback()
+		respondError(c, err)
+		return
+	}
+
+	transfer := models.EnvelopeTransfer{
+		LedgerID:     ledgerID,
+		FromBudgetID: from.ID,
+		ToBudgetID:   to.ID,
+		Amount:       input.Amount,
+		Note:         input.Note,
+		CreatedBy:    middleware.CurrentUserID(c),
+	}
+	if err := moveEnvelopeMoney(tx, &transfer); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tx.Commit()
+
+	h.respondSummary(c, http.StatusOK)
+}
+
+// CheckEnvelopes 校验零基预算的一致性：各信封记录的分配金额与划拨记录一致，
+// 且已分配与待分配之和等于开始日期以来的总收入
+func (h *EnvelopeHandler) CheckEnvelopes(c *gin.Context) {
+	summary, err := loadEnvelopeSummary(h.DB, middleware.CurrentLedgerID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	type discrepancy struct {
+		BudgetID   uint         `json:"budget_id"`
+		CategoryID uint         `json:"category_id"`
+		Recorded   models.Money `json:"recorded"` // 信封预算上记录的分配金额
+		Expected   models.Money `json:"expected"` // 按划拨记录计算的分配金额
+	}
+	discrepancies := []discrepancy{}
+	for _, envelope := range summary.Envelopes {
+		if expected := summary.transferred[envelope.BudgetID]; envelope.Assigned != expected {
+			discrepancies = append(discrepancies, discrepancy{envelope.BudgetID, envelope.CategoryID, envelope.Assigned, expected})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total_income":   summary.TotalIncome,
+		"assigned":       summary.Assigned,
+		"to_be_assigned": summary.ToBeAssigned,
+		"consistent":     len(discrepancies) == 0 && summary.Assigned+summary.ToBeAssigned == summary.TotalIncome,
+		"discrepancies":  discrepancies,
+		"base_currency":  summary.BaseCurrency,
+	})
+}
+
+// respondSummary 返回当前账本的零基预算情况
+func (h *EnvelopeHandler) respondSummary(c *gin.Context, status int) {
+	summary, err := loadEnvelopeSummary(h.DB, middleware.CurrentLedgerID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(status, summary)
+}
+
+// loadEnvelopeSummary 计算账本的零基预算情况。收入和支出均从开始日期起按本位币统计，
+// 拆分交易按拆分行计入各自分类的信封
+func loadEnvelopeSummary(db *gorm.DB, ledgerID uint) (*envelopeSummary, error) {
+	var ledger models.Ledger
+	if err := db.First(&ledger, ledgerID).Error; err != nil {
+		return nil, &requestError{http.StatusNotFound, "Ledger not found"}
+	}
+	if ledger.EnvelopeStartDate == "" {
+		return nil, &requestError{http.StatusBadRequest, "Envelope budgeting is not enabled"}
+	}
+
+	cv, err := newCurrencyConverter(db, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := loadStatEntries(db.Where("ledger_id = ? AND date >= ?", ledgerID, ledger.EnvelopeStartDate), cv)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelopes []models.Budget
+	if err := db.Where("ledger_id = ? AND kind = ?", ledgerID, "envelope").Preload("Category").Find(&envelopes).Error; err != nil {
+		return nil, err
+	}
+	var transfers []models.EnvelopeTransfer
+	if err := db.Where("ledger_id = ?", ledgerID).Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	var categories []models.Category
+	if err := db.Where("ledger_id = ?", ledgerID).Select("id, parent_id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	parents := make(map[uint]uint)
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+	hasEnvelope := make(map[uint]bool)
+	for _, envelope := range envelopes {
+		hasEnvelope[envelope.CategoryID] = true
+	}
+
+	summary := &envelopeSummary{
+		StartDate:    ledger.EnvelopeStartDate,
+		Envelopes:    []envelopeStatus{},
+		BaseCurrency: cv.base,
+		transferred:  make(map[uint]models.Money),
+	}
+	var fromPool models.Money
+	for _, transfer := range transfers {
+		if transfer.FromBudgetID == 0 {
+			fromPool += transfer.Amount
+		} else {
+			summary.transferred[transfer.FromBudgetID] -= transfer.Amount
+		}
+		if transfer.ToBudgetID == 0 {
+			fromPool -= transfer.Amount
+		} else {
+			summary.transferred[transfer.ToBudgetID] += transfer.Amount
+		}
+	}
+
+	// 支出计入其分类或最近的有信封的上级分类，每笔支出只计入一个信封
+	spent := make(map[uint]models.Money)
+	for _, entry := range entries {
+		if entry.Type == "income" {
+			summary.TotalIncome += entry.Amount
+			continue
+		}
+		categoryID := entry.CategoryID
+		if !hasEnvelope[categoryID] {
+			for _, ancestor := range categoryAncestors(parents, categoryID) {
+				if hasEnvelope[ancestor] {
+					categoryID = ancestor
+					break
+				}
+			}
+		}
+		spent[categoryID] += entry.Amount
+	}
+	summary.ToBeAssigned = summary.TotalIncome - fromPool
+
+	for _, envelope := range envelopes {
+		status := envelopeStatus{
+			BudgetID:     envelope.ID,
+			CategoryID:   envelope.CategoryID,
+			CategoryName: envelope.Category.Name,
+			Assigned:     envelope.Amount,
+			Spent:        spent[envelope.CategoryID],
+		}
+		status.Balance = status.Assigned - status.Spent
+		summary.Assigned += status.Assigned
+		summary.Envelopes = append(summary.Envelopes, status)
+		delete(spent, envelope.CategoryID)
+	}
+	for _, amount := range spent {
+		summary.UnbudgetedExpense += amount
+	}
+	sort.Slice(summary.Envelopes, func(i, j int) bool {
+		return summary.Envelopes[i].CategoryName < summary.Envelopes[j].CategoryName
+	})
+	return summary, nil
+}
+
+// findOrCreateEnvelope 返回支出分类的信封，不存在时创建一个分配金额为 0 的信封
+func findOrCreateEnvelope(tx *gorm.DB, ledgerID uint, startDate string, categoryID uint) (models.Budget, error) {
+	var category models.Category
+	if err := tx.Where("ledger_id = ?", ledgerID).First(&category, categoryID).Error; err != nil {
+		return models.Budget{}, &requestError{http.StatusNotFound, "Category not found"}
+	}
+	if category.Type != "expense" {
+		return models.Budget{}, &requestError{http.StatusBadRequest, "Envelopes must use an expense category"}
+	}
+
+	var envelope models.Budget
+	err := tx.Where("ledger_id = ? AND kind = ? AND category_id = ?", ledgerID, "envelope", categoryID).First(&envelope).Error
+	if err == nil || !gorm.IsRecordNotFoundError(err) {
+		return envelope, err
+	}
+	envelope = models.Budget{
+		LedgerID:   ledgerID,
+		Kind:       "envelope",
+		CategoryID: categoryID,
+		StartDate:  startDate,
+	}
+	return envelope, tx.Create(&envelope).Error
+}
+
+// moveEnvelopeMoney 在事务 tx 中写入划拨记录，并同步调整相关信封的分配金额
+func moveEnvelopeMoney(tx *gorm.DB, transfer *models.EnvelopeTransfer) error {
+	if err := tx.Create(transfer).Error; err != nil {
+		return err
+	}
+	if transfer.FromBudgetID != 0 {
+		if err := tx.Model(&models.Budget{}).Where("id = ?", transfer.FromBudgetID).
+			UpdateColumn("amount", gorm.Expr("amount - ?", transfer.Amount)).Error; err != nil {
+			return err
+		}
+	}
+	if transfer.ToBudgetID != 0 {
+		if err := tx.Model(&models.Budget{}).Where("id = ?", transfer.ToBudgetID).
+			UpdateColumn("amount", gorm.Expr("amount + ?", transfer.Amount)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestError 描述请求处理失败的原因及对应的 HTTP 状态码
+type requestError struct {
+	Status  int
+	Message string
+}
+
+func (e *requestError) Error() string {
+	return e.Message
+}
+
+// respondError 将处理错误写入响应，requestError 使用其状态码，其他错误按 500 处理
+func respondError(c *gin.Context, err error) {
+	if re, ok := err.(*requestError); ok {
+		c.JSON(re.Status, gin.H{"error": re.Message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
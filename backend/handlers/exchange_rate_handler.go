@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type ExchangeRateHandler struct {
+	DB *gorm.DB
+}
+
+// CreateExchangeRate 为当前账本录入汇率
+func (h *ExchangeRateHandler) CreateExchangeRate(c *gin.Context) {
+	var rate models.ExchangeRate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateExchangeRate(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate.LedgerID = middleware.CurrentLedgerID(c)
+	if err := h.DB.Create(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+
+// GetExchangeRates 获取当前账本的汇率列表
+func (h *ExchangeRateHandler) GetExchangeRates(c *gin.Context) {
+	var rates []models.ExchangeRate
+
+	query := h.DB.Scopes(ledgerScope(c)).Order("date desc")
+
+	// 支持按币种筛选
+	if currency := c.Query("currency"); currency != "" {
+		query = query.Where("currency = ? OR quote_currency = ?", currency, currency)
+	}
+
+	if err := query.Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// UpdateExchangeRate 更新汇率
+func (h *ExchangeRateHandler) UpdateExchangeRate(c *gin.Context) {
+	id, ok := pathID(c, "id", "exchange rate")
+	if !ok {
+		return
+	}
+	var rate models.ExchangeRate
+
+	if err := h.DB.Scopes(ledgerScope(c)).First(&rate, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate not found"})
+		return
+	}
+
+	var input models.ExchangeRate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateExchangeRate(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate.Currency = input.Currency
+	rate.QuoteCurrency = input.QuoteCurrency
+	rate.Rate = input.Rate
+	rate.Date = input.Date
+
+	if err := h.DB.Save(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// DeleteExchangeRate 删除汇率
+func (h *ExchangeRateHandler) DeleteExchangeRate(c *gin.Context) {
+	id, ok := pathID(c, "id", "exchange rate")
+	if !ok {
+		return
+	}
+	if err := h.DB.Scopes(ledgerScope(c)).Delete(&models.ExchangeRate{}, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted successfully"})
+}
+
+// validateExchangeRate 校验币种、汇率和生效日期
+func validateExchangeRate(rate *models.ExchangeRate) error {
+	var err error
+	if rate.Currency, err = normalizeCurrency(rate.Currency); err != nil {
+		return err
+	}
+	if rate.QuoteCurrency, err = normalizeCurrency(rate.QuoteCurrency); err != nil {
+		return err
+	}
+	if rate.Currency == rate.QuoteCurrency {
+		return errors.New("currency and quote_currency must differ")
+	}
+	if rate.Rate <= 0 {
+		return errors.New("rate must be greater than 0")
+	}
+	if _, err := time.Parse("2006-01-02", rate.Date); err != nil {
+		return errors.New("invalid date format. Use YYYY-MM-DD")
+	}
+	return nil
+}
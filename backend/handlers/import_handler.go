@@ -0,0 +1,494 @@
+package handlers
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+	"personal-finance/importer"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type ImportHandler struct {
+	DB     *gorm.DB
+	Alerts *BudgetAlerter // 为空时不检查预算提醒
+}
+
+// CreateImportProfile 创建 CSV 列映射配置
+func (h *ImportHandler) CreateImportProfile(c *gin.Context) {
+	var profile models.ImportProfile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := importer.ValidateProfile(profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile.LedgerID = middleware.CurrentLedgerID(c)
+	if err := h.DB.Create(&profile).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, profile)
+}
+
+// GetImportProfiles 获取所有 CSV 列映射配置
+func (h *ImportHandler) GetImportProfiles(c *gin.Context) {
+	var profiles []models.ImportProfile
+	if err := h.DB.Scopes(ledgerScope(c)).Find(&profiles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profiles)
+}
+
+// UpdateImportProfile 更新 CSV 列映射配置
+func (h *ImportHandler) UpdateImportProfile(c *gin.Context) {
+	id, ok := pathID(c, "id", "import profile")
+	if !ok {
+		return
+	}
+	var profile models.ImportProfile
+
+	if err := h.DB.Scopes(ledgerScope(c)).First(&profile, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import profile not found"})
+		return
+	}
+
+	var input models.ImportProfile
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := importer.ValidateProfile(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input.ID = profile.ID
+	input.LedgerID = profile.LedgerID
+	input.CreatedAt = profile.CreatedAt
+	if err := h.DB.Save(&input).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, input)
+}
+
+// DeleteImportProfile 删除 CSV 列映射配置
+func (h *ImportHandler) DeleteImportProfile(c *gin.Context) {
+	id, ok := pathID(c, "id", "import profile")
+	if !ok {
+		return
+	}
+	if err := h.DB.Scopes(ledgerScope(c)).Delete(&models.ImportProfile{}, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Import profile deleted successfully"})
+}
+
+// PreviewCSVImport 试运行 CSV 导入：解析文件并返回每一行的校验结果，不写入数据库
+func (h *ImportHandler) PreviewCSVImport(c *gin.Context) {
+	batch, ok := h.parseCSVUpload(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, batch.preview(h.DB))
+}
+
+// ConfirmCSVImport 确认 CSV 导入：所有行校验通过后，在同一个数据库事务中创建交易并更新账户余额
+func (h *ImportHandler) ConfirmCSVImport(c *gin.Context) {
+	batch, ok := h.parseCSVUpload(c)
+	if !ok {
+		return
+	}
+
+	h.commit(c, batch)
+}
+
+// PreviewOFXImport 试运行 OFX/QFX 导入，已导入过的 FITID 会标记为重复
+func (h *ImportHandler) PreviewOFXImport(c *gin.Context) {
+	batch, ok := h.parseStatementUpload(c, importer.ParseOFX)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, batch.preview(h.DB))
+}
+
+// ConfirmOFXImport 确认 OFX/QFX 导入，跳过已导入过的 FITID
+func (h *ImportHandler) ConfirmOFXImport(c *gin.Context) {
+	batch, ok := h.parseStatementUpload(c, importer.ParseOFX)
+	if !ok {
+		return
+	}
+
+	h.commit(c, batch)
+}
+
+// PreviewQIFImport 试运行 QIF 导入，可通过表单参数 date_format 指定日期格式
+func (h *ImportHandler) PreviewQIFImport(c *gin.Context) {
+	batch, ok := h.parseStatementUpload(c, qifParser(c))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, batch.preview(h.DB))
+}
+
+// ConfirmQIFImport 确认 QIF 导入
+func (h *ImportHandler) ConfirmQIFImport(c *gin.Context) {
+	batch, ok := h.parseStatementUpload(c, qifParser(c))
+	if !ok {
+		return
+	}
+
+	h.commit(c, batch)
+}
+
+func qifParser(c *gin.Context) func(io.Reader) ([]importer.Row, error) {
+	dateFormat := c.PostForm("date_format")
+	return func(r io.Reader) ([]importer.Row, error) {
+		return importer.ParseQIF(r, dateFormat)
+	}
+}
+
+// importRow 待导入的一行及其归类结果
+type importRow struct {
+	importer.Row
+	CategoryID uint     `json:"category_id"`
+	Tags       []string `json:"tags,omitempty"` // 规则追加的标签
+}
+
+// importBatch 一次导入的全部行、目标账户、归类规则及未被规则归类的行使用的默认分类
+type importBatch struct {
+	LedgerID          uint
+	CreatedBy         uint
+	AccountID         uint
+	IncomeCategoryID  uint
+	ExpenseCategoryID uint
+	Rules             *ruleSet
+	Rows              []importRow
+}
+
+// parseCSVUpload 读取 multipart 表单中的文件、配置和目标账户并解析 CSV，
+// 出错时已写入响应并返回 false
+func (h *ImportHandler) parseCSVUpload(c *gin.Context) (*importBatch, bool) {
+	profileID, ok := formID(c, "profile_id")
+	if !ok {
+		return nil, false
+	}
+	var profile models.ImportProfile
+	if err := h.DB.Scopes(ledgerScope(c)).First(&profile, profileID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import profile not found"})
+		return nil, false
+	}
+
+	batch, ok := h.newBatch(c, profile.IncomeCategoryID, profile.ExpenseCategoryID)
+	if !ok {
+		return nil, false
+	}
+
+	f, ok := openUpload(c)
+	if !ok {
+		return nil, false
+	}
+	defer f.Close()
+
+	rows, err := importer.ParseCSV(f, profile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	batch.addRows(rows)
+	return batch, true
+}
+
+// parseStatementUpload 读取 multipart 表单中的文件和目标账户，并用 parse 解析 OFX、QIF 等对账单，
+// 这类文件没有列映射配置，默认分类只能由表单参数指定
+func (h *ImportHandler) parseStatementUpload(c *gin.Context, parse func(io.Reader) ([]importer.Row, error)) (*importBatch, bool) {
+	batch, ok := h.newBatch(c, 0, 0)
+	if !ok {
+		return nil, false
+	}
+
+	f, ok := openUpload(c)
+	if !ok {
+		return nil, false
+	}
+	defer f.Close()
+
+	rows, err := parse(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	batch.addRows(rows)
+	if err := batch.markDuplicates(h.DB); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return batch, true
+}
+
+// openUpload 打开表单中上传的文件，出错时已写入响应并返回 false
+func openUpload(c *gin.Context) (multipart.File, bool) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return nil, false
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return f, true
+}
+
+// formID 读取表单中必填的编号，缺失或无效时已写入 400 响应并返回 false
+func formID(c *gin.Context, name string) (uint, bool) {
+	value := c.PostForm(name)
+	if value == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " is required"})
+		return 0, false
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// newBatch 校验目标账户，并确定未归类行使用的默认收入、支出分类（表单参数优先于配置）
+func (h *ImportHandler) newBatch(c *gin.Context, incomeCategoryID, expenseCategoryID uint) (*importBatch, bool) {
+	accountID, ok := formID(c, "account_id")
+	if !ok {
+		return nil, false
+	}
+	var account models.Account
+	if err := h.DB.Scopes(ledgerScope(c)).First(&account, accountID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return nil, false
+	}
+
+	if id, err := strconv.ParseUint(c.PostForm("income_category_id"), 10, 32); err == nil {
+		incomeCategoryID = uint(id)
+	}
+	if id, err := strconv.ParseUint(c.PostForm("expense_category_id"), 10, 32); err == nil {
+		expenseCategoryID = uint(id)
+	}
+
+	rules, err := loadRuleSet(h.DB, account.LedgerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return &importBatch{
+		LedgerID:          account.LedgerID,
+		CreatedBy:         middleware.CurrentUserID(c),
+		AccountID:         account.ID,
+		IncomeCategoryID:  incomeCategoryID,
+		ExpenseCategoryID: expenseCategoryID,
+		Rules:             rules,
+	}, true
+}
+
+// addRows 按规则归类解析出的行，未被规则归类的行使用默认分类
+func (b *importBatch) addRows(rows []importer.Row) {
+	for _, row := range rows {
+		item := importRow{Row: row}
+		if row.Valid() {
+			result := b.Rules.apply(b.transaction(item))
+			item.CategoryID = result.CategoryID
+			if result.Description != "" {
+				item.Description = result.Description
+			}
+			item.Tags = result.Tags
+		}
+		if item.CategoryID == 0 {
+			item.CategoryID = b.ExpenseCategoryID
+			if row.Type == "income" {
+				item.CategoryID = b.IncomeCategoryID
+			}
+		}
+		b.Rows = append(b.Rows, item)
+	}
+}
+
+// transaction 将导入行转换为交易
+func (b *importBatch) transaction(row importRow) models.Transaction {
+	return models.Transaction{
+		LedgerID:    b.LedgerID,
+		CreatedBy:   b.CreatedBy,
+		AccountID:   b.AccountID,
+		Amount:      row.Amount,
+		Type:        row.Type,
+		CategoryID:  row.CategoryID,
+		Description: row.Description,
+		Date:        row.Date,
+		ExternalID:  row.ExternalID,
+	}
+}
+
+// markDuplicates 标记目标账户中已存在、或在本文件中重复出现的外部编号
+func (b *importBatch) markDuplicates(db *gorm.DB) error {
+	var ids []string
+	for _, row := range b.Rows {
+		if row.ExternalID != "" {
+			ids = append(ids, row.ExternalID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var existing []string
+	if err := db.Model(&models.Transaction{}).
+		Where("account_id = ? AND external_id IN (?)", b.AccountID, ids).
+		Pluck("external_id", &existing).Error; err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, id := range existing {
+		seen[id] = true
+	}
+	for i := range b.Rows {
+		row := &b.Rows[i]
+		if row.ExternalID == "" {
+			continue
+		}
+		row.Duplicate = seen[row.ExternalID]
+		seen[row.ExternalID] = true
+	}
+	return nil
+}
+
+// preview 用与正式导入相同的规则校验每一行，返回试运行结果；重复行不参与校验
+func (b *importBatch) preview(db *gorm.DB) gin.H {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	valid, duplicates := 0, 0
+	for i := range b.Rows {
+		row := &b.Rows[i]
+		if row.Duplicate {
+			duplicates++
+			continue
+		}
+		if row.Valid() {
+			transaction := b.transaction(*row)
+			if err := validateTransaction(tx, &transaction); err != nil {
+				row.Errors = append(row.Errors, err.Error())
+			}
+		}
+		if row.Valid() {
+			valid++
+		}
+	}
+
+	return gin.H{
+		"account_id": b.AccountID,
+		"rows":       b.Rows,
+		"total":      len(b.Rows),
+		"valid":      valid,
+		"invalid":    len(b.Rows) - valid - duplicates,
+		"duplicates": duplicates,
+	}
+}
+
+// commit 在同一个数据库事务中创建所有交易并更新账户余额；任意一行失败则全部回滚，
+// 标记为重复的行直接跳过
+func (h *ImportHandler) commit(c *gin.Context, batch *importBatch) {
+	if len(batch.Rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No rows to import"})
+		return
+	}
+	for _, row := range batch.Rows {
+		if !row.Duplicate && !row.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Import contains invalid rows",
+				"preview": batch.preview(h.DB),
+			})
+			return
+		}
+	}
+
+	tx := h.DB.Begin()
+
+	var transactions []models.Transaction
+	skipped := 0
+	for _, row := range batch.Rows {
+		if row.Duplicate {
+			skipped++
+			continue
+		}
+		transaction := batch.transaction(row)
+		if err := validateTransaction(tx, &transaction); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "line": row.Line})
+			return
+		}
+
+		if _, err := applyTransaction(tx, transaction, 1); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := tx.Create(&transaction).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := tagTransaction(tx, &transaction, row.Tags); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	var account models.Account
+	if err := tx.First(&account, batch.AccountID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx.Commit()
+
+	var dates []string
+	for _, transaction := range transactions {
+		if transaction.Type == "expense" {
+			dates = append(dates, transaction.Date)
+		}
+	}
+	h.Alerts.Check(batch.LedgerID, dates...)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"imported":     len(transactions),
+		"skipped":      skipped,
+		"transactions": transactions,
+		"new_balance":  account.Balance,
+	})
+}
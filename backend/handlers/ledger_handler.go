@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type LedgerHandler struct {
+	DB           *gorm.DB
+	BaseCurrency string // 新建账本的默认本位币
+}
+
+// CreateLedger 创建账本，创建者成为所有者，并初始化默认收支分类和本位币
+func (h *LedgerHandler) CreateLedger(c *gin.Context) {
+	var ledger models.Ledger
+	if err := c.ShouldBindJSON(&ledger); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if err := createLedger(tx, &ledger, middleware.CurrentUserID(c)); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := seedDefaultCategories(tx, ledger.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := seedBaseCurrency(tx, ledger.ID, h.BaseCurrency); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, ledger)
+}
+
+// GetLedgers 获取当前用户加入的所有账本及其角色
+func (h *LedgerHandler) GetLedgers(c *gin.Context) {
+	var members []models.LedgerMember
+	if err := h.DB.Where("user_id = ?", middleware.CurrentUserID(c)).Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	roles := make(map[uint]string)
+	var ids []uint
+	for _, member := range members {
+		roles[member.LedgerID] = member.Role
+		ids = append(ids, member.LedgerID)
+	}
+
+	var ledgers []models.Ledger
+	if err := h.DB.Where("id IN (?)", ids).Order("id").Find(&ledgers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range ledgers {
+		ledgers[i].Role = roles[ledgers[i].ID]
+	}
+
+	c.JSON(http.StatusOK, ledgers)
+}
+
+// UpdateLedger 重命名账本，仅所有者可操作
+func (h *LedgerHandler) UpdateLedger(c *gin.Context) {
+	member, ok := h.requireRole(c, models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var input models.Ledger
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var ledger models.Ledger
+	if err := h.DB.First(&ledger, member.LedgerID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ledger not found"})
+		return
+	}
+	ledger.Name = input.Name
+	if err := h.DB.Save(&ledger).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ledger.Role = member.Role
+
+	c.JSON(http.StatusOK, ledger)
+}
+
+// DeleteLedger 删除账本及其分类、预算等配置，仅所有者可操作；账本中仍有账户时不能删除
+func (h *LedgerHandler) DeleteLedger(c *gin.Context) {
+	member, ok := h.requireRole(c, models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var accountCount int64
+	h.DB.Model(&models.Account{}).Where("ledger_id = ?", member.LedgerID).Count(&accountCount)
+	if accountCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete ledger with accounts"})
+		return
+	}
+
+	// 不能删除自己唯一的账本，否则无处记账
+	var ledgerCount int64
+	h.DB.Model(&models.LedgerMember{}).Where("user_id = ?", member.UserID).Count(&ledgerCount)
+	if ledgerCount <= 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete your only ledger"})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if err := tx.Exec("DELETE FROM transaction_tags WHERE tag_id IN (SELECT id FROM tags WHERE ledger_id = ?)", member.LedgerID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Exec("DELETE FROM transaction_splits WHERE transaction_id IN (SELECT id FROM transactions WHERE ledger_id = ?)", member.LedgerID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Exec("DELETE FROM cleared_transactions WHERE reconciliation_id IN (SELECT id FROM reconciliations WHERE ledger_id = ?)", member.LedgerID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, value := range []interface{}{
+		&models.Transaction{},
+		&models.Reconciliation{},
+		&models.BalanceAudit{},
+		&models.BalanceSnapshot{},
+		&models.RecurringTransaction{},
+		&models.Budget{},
+		&models.BudgetAlert{},
+		&models.EnvelopeTransfer{},
+		&models.Category{},
+		&models.ImportProfile{},
+		&models.Rule{},
+		&models.Tag{},
+		&models.ExchangeRate{},
+		&models.Setting{},
+		&models.LedgerMember{},
+	} {
+		if err := tx.Unscoped().Where("ledger_id = ?", member.LedgerID).Delete(value).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := tx.Delete(&models.Ledger{}, member.LedgerID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ledger deleted successfully"})
+}
+
+// GetLedgerMembers 获取账本成员列表，所有成员均可查看
+func (h *LedgerHandler) GetLedgerMembers(c *gin.Context) {
+	member, ok := h.requireRole(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var members []models.LedgerMember
+	if err := h.DB.Preload("User").Where("ledger_id = ?", member.LedgerID).Order("id").Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// AddLedgerMember 按用户名邀请成员加入账本，仅所有者可操作
+func (h *LedgerHandler) AddLedgerMember(c *gin.Context) {
+	owner, ok := h.requireRole(c, models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Username string `json:"username" binding:"required"`
+		Role     string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !middleware.ValidRole(input.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be 'owner', 'editor' or 'viewer'"})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Where("username = ?", input.Username).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var existing int64
+	h.DB.Model(&models.LedgerMember{}).Where("ledger_id = ? AND user_id = ?", owner.LedgerID, user.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member of this ledger"})
+		return
+	}
+
+	member := models.LedgerMember{LedgerID: owner.LedgerID, UserID: user.ID, Role: input.Role}
+	if err := h.DB.Create(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	member.User = user
+
+	c.JSON(http.StatusCreated, member)
+}
+
+// UpdateLedgerMember 修改成员角色，仅所有者可操作；账本至少保留一个所有者
+func (h *LedgerHandler) UpdateLedgerMember(c *gin.Context) {
+	owner, ok := h.requireRole(c, models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !middleware.ValidRole(input.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be 'owner', 'editor' or 'viewer'"})
+		return
+	}
+
+	var member models.LedgerMember
+	if err := h.DB.Where("ledger_id = ? AND user_id = ?", owner.LedgerID, c.Param("user_id")).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	if member.Role == models.RoleOwner && input.Role != models.RoleOwner && h.lastOwner(member) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ledger must keep at least one owner"})
+		return
+	}
+
+	member.Role = input.Role
+	if err := h.DB.Save(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveLedgerMember 移除成员，所有者可移除任何成员，其他成员只能退出；账本至少保留一个所有者
+func (h *LedgerHandler) RemoveLedgerMember(c *gin.Context) {
+	current, ok := h.requireRole(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var member models.LedgerMember
+	if err := h.DB.Where("ledger_id = ? AND user_id = ?", current.LedgerID, c.Param("user_id")).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	if member.UserID != current.UserID && !middleware.HasRole(current.Role, models.RoleOwner) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can remove other members"})
+		return
+	}
+	if member.Role == models.RoleOwner && h.lastOwner(member) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ledger must keep at least one owner"})
+		return
+	}
+
+	if err := h.DB.Delete(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// requireRole 检查当前用户在路径参数 id 指定的账本中至少具有 minRole 角色，
+// 不满足时已写入响应并返回 false
+func (h *LedgerHandler) requireRole(c *gin.Context, minRole string) (models.LedgerMember, bool) {
+	var member models.LedgerMember
+	if err := h.DB.Where("ledger_id = ? AND user_id = ?", c.Param("id"), middleware.CurrentUserID(c)).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ledger not found"})
+		return member, false
+	}
+	if !middleware.HasRole(member.Role, minRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient ledger role"})
+		return member, false
+	}
+	return member, true
+}
+
+// lastOwner 判断该成员是否为账本唯一的所有者
+func (h *LedgerHandler) lastOwner(member models.LedgerMember) bool {
+	var owners int64
+	h.DB.Model(&models.LedgerMember{}).Where("ledger_id = ? AND role = ?", member.LedgerID, models.RoleOwner).Count(&owners)
+	return owners <= 1
+}
+
+// createLedger 在事务 tx 中创建账本，并将 userID 设为所有者
+func createLedger(tx *gorm.DB, ledger *models.Ledger, userID uint) error {
+	if err := tx.Create(ledger).Error; err != nil {
+		return err
+	}
+	ledger.Role = models.RoleOwner
+	return tx.Create(&models.LedgerMember{LedgerID: ledger.ID, UserID: userID, Role: models.RoleOwner}).Error
+}
+
+// pathID 解析路径参数中的编号，无效时返回 400。编号须以整数传给 First、Delete 等方法：
+// gorm v1 会把字符串形式的内联条件当作原始 SQL 拼接
+func pathID(c *gin.Context, name, resource string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s ID", resource)})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// ledgerScope 将查询限定为当前账本的数据
+func ledgerScope(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	ledgerID := middleware.CurrentLedgerID(c)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("ledger_id = ?", ledgerID)
+	}
+}
@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// ReconciliationHandler 按银行对账单核对账户：勾选已清算的交易直到已清算余额与对账单余额一致，
+// 完成后锁定这些交易。余额不一致时以调整交易补平，而不是直接改写账户余额
+type ReconciliationHandler struct {
+	DB *gorm.DB
+}
+
+// adjustmentCategoryName 对账调整交易使用的分类名称，不存在时自动创建
+const adjustmentCategoryName = "余额调整"
+
+// reconcileItem 对账中可勾选的交易
+type reconcileItem struct {
+	models.Transaction
+	Delta   models.Money `json:"delta"` // 对本账户余额的影响
+	Cleared bool         `json:"cleared"`
+}
+
+// reconciliationSummary 对账进度。已清算余额为账户余额减去所有未清算交易的影响
+type reconciliationSummary struct {
+	models.Reconciliation
+	ClearedBalance models.Money    `json:"cleared_balance"`
+	Difference     models.Money    `json:"difference"` // 对账单余额减已清算余额，为 0 时可直接完成对账
+	Transactions   []reconcileItem `json:"transactions"`
+}
+
+// StartReconciliation 为账户开始一次对账，对账单日期须晚于上一次完成的对账
+func (h *ReconciliationHandler) StartReconciliation(c *gin.Context) {
+	id, ok := pathID(c, "id", "account")
+	if !ok {
+		return
+	}
+	var account models.Account
+	if err := h.DB.Scopes(ledgerScope(c)).First(&account, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	var input struct {
+		StatementDate    string        `json:"statement_date" binding:"required"`
+		StatementBalance *models.Money `json:"statement_balance" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	date, err := normalizeDate(input.StatementDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid statement date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	tx := h.DB.Begin()
+	var inProgress int
+	tx.Model(&models.Reconciliation{}).Where("account_id = ? AND finished_at IS NULL", account.ID).Count(&inProgress)
+	if inProgress > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "A reconciliation is already in progress for this account"})
+		return
+	}
+	var last models.Reconciliation
+	err = tx.Where("account_id = ? AND finished_at IS NOT NULL", account.ID).Order("statement_date DESC").First(&last).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err == nil && date <= last.StatementDate {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Statement date must be after the last reconciliation (%s)", last.StatementDate)})
+		return
+	}
+
+	reconciliation := models.Reconciliation{
+		LedgerID:         account.LedgerID,
+		AccountID:        account.ID,
+		StatementDate:    date,
+		StatementBalance: *input.StatementBalance,
+		CreatedBy:        middleware.CurrentUserID(c),
+	}
+	if err := tx.Create(&reconciliation).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tx.Commit()
+
+	h.respondSummary(c, http.StatusCreated, reconciliation)
+}
+
+// GetReconciliations 获取账户的对账记录，最近的在前
+func (h *ReconciliationHandler) GetReconciliations(c *gin.Context) {
+	id, ok := pathID(c, "id", "account")
+	if !ok {
+		return
+	}
+	var account models.Account
+	if err := h.DB.Scopes(ledgerScope(c)).First(&account, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	var reconciliations []models.Reconciliation
+	if err := h.DB.Where("account_id = ?", account.ID).Order("statement_date DESC, id DESC").Find(&reconciliations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, reconciliations)
+}
+
+// GetReconciliation 获取对账进度及可勾选的交易：进行中时为对账单日期及之前尚未锁定的交易，完成后为本次清算的交易
+func (h *ReconciliationHandler) GetReconciliation(c *gin.Context) {
+	id, ok := pathID(c, "id", "reconciliation")
+	if !ok {
+		return
+	}
+	var reconciliation models.Reconciliation
+	if err := h.DB.Scopes(ledgerScope(c)).First(&reconciliation, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reconciliation not found"})
+		return
+	}
+	h.respondSummary(c, http.StatusOK, reconciliation)
+}
+
+// SetCleared 在进行中的对账里勾选或取消勾选已清算的交易
+func (h *ReconciliationHandler) SetCleared(c *gin.Context) {
+	var input struct {
+		TransactionIDs []uint `json:"transaction_ids" binding:"required"`
+		Cleared        bool   `json:"cleared"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, ok := pathID(c, "id", "reconciliation")
+	if !ok {
+		return
+	}
+	tx := h.DB.Begin()
+	reconciliation, err := openReconciliation(tx.Scopes(ledgerScope(c)), id)
+	if err != nil {
+		tx.Rollback()
+		respondError(c, err)
+		return
+	}
+
+	for _, id := range uniqueIDs(input.TransactionIDs) {
+		if err := setCleared(tx, reconciliation, id, input.Cleared); err != nil {
+			tx.Rollback()
+			respondError(c, err)
+			return
+		}
+	}
+	tx.Commit()
+
+	h.respondSummary(c, http.StatusOK, reconciliation)
+}
+
+// FinishReconciliation 完成对账并锁定已清算的交易。已清算余额与对账单余额不一致时，
+// 需指定 adjust 才会在对账单日期补记一笔已清算的调整交易
+func (h *ReconciliationHandler) FinishReconciliation(c *gin.Context) {
+	var input struct {
+		Adjust bool `json:"adjust"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, ok := pathID(c, "id", "reconciliation")
+	if !ok {
+		return
+	}
+	tx := h.DB.Begin()
+	reconciliation, err := openReconciliation(tx.Scopes(ledgerScope(c)), id)
+	if err != nil {
+		tx.Rollback()
+		respondError(c, err)
+		return
+	}
+	summary, err := loadReconciliationSummary(tx, reconciliation)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if summary.Difference != 0 {
+		if !input.Adjust {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cleared balance differs from the statement balance by %s", summary.Difference)})
+			return
+		}
+		adjustment, err := postAdjustment(tx, reconciliation, summary.Difference, middleware.CurrentUserID(c))
+		if err != nil {
+			tx.Rollback()
+			respondError(c, err)
+			return
+		}
+		reconciliation.AdjustmentID = adjustment.ID
+	}
+
+	now := time.Now()
+	reconciliation.FinishedAt = &now
+	if err := tx.Save(&reconciliation).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tx.Commit()
+
+	h.respondSummary(c, http.StatusOK, reconciliation)
+}
+
+// CancelReconciliation 放弃进行中的对账，已勾选的交易恢复为未清算
+func (h *ReconciliationHandler) CancelReconciliation(c *gin.Context) {
+	id, ok := pathID(c, "id", "reconciliation")
+	if !ok {
+		return
+	}
+	tx := h.DB.Begin()
+	reconciliation, err := openReconciliation(tx.Scopes(ledgerScope(c)), id)
+	if err != nil {
+		tx.Rollback()
+		respondError(c, err)
+		return
+	}
+	if err := tx.Where("reconciliation_id = ?", reconciliation.ID).Delete(&models.ClearedTransaction{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Delete(&reconciliation).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reconciliation cancelled"})
+}
+
+func (h *ReconciliationHandler) respondSummary(c *gin.Context, status int, reconciliation models.Reconciliation) {
+	summary, err := loadReconciliationSummary(h.DB, reconciliation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(status, summary)
+}
+
+// openReconciliation 载入进行中的对账，已完成的对账不能再修改
+func openReconciliation(query *gorm.DB, id uint) (models.Reconciliation, error) {
+	var reconciliation models.Reconciliation
+	if err := query.First(&reconciliation, id).Error; err != nil {
+		return reconciliation, &requestError{http.StatusNotFound, "Reconciliation not found"}
+	}
+	if reconciliation.FinishedAt != nil {
+		return reconciliation, &requestError{http.StatusConflict, "Reconciliation is already finished"}
+	}
+	return reconciliation, nil
+}
+
+// setCleared 勾选或取消勾选一笔交易，交易须属于对账账户且不晚于对账单日期，已被锁定的交易不能修改
+func setCleared(tx *gorm.DB, reconciliation models.Reconciliation, transactionID uint, cleared bool) error {
+	var transaction models.Transaction
+	if err := tx.Where("ledger_id = ? AND (account_id = ? OR to_account_id = ?)", reconciliation.LedgerID, reconciliation.AccountID, reconciliation.AccountID).
+		First(&transaction, transactionID).Error; err != nil {
+		return &requestError{http.StatusNotFound, fmt.Sprintf("Transaction %d not found", transactionID)}
+	}
+	if transaction.Date > reconciliation.StatementDate {
+		return &requestError{http.StatusBadRequest, fmt.Sprintf("Transaction %d is after the statement date", transactionID)}
+	}
+
+	var existing models.ClearedTransaction
+	err := tx.Where("account_id = ? AND transaction_id = ?", reconciliation.AccountID, transactionID).First(&existing).Error
+	switch {
+	case err != nil && !gorm.IsRecordNotFoundError(err):
+		return err
+	case err == nil && existing.ReconciliationID != reconciliation.ID:
+		return &requestError{http.StatusConflict, fmt.Sprintf("Transaction %d is reconciled and locked", transactionID)}
+	case err == nil && !cleared:
+		return tx.Delete(&existing).Error
+	case err != nil && cleared:
+		return tx.Create(&models.ClearedTransaction{
+			ReconciliationID: reconciliation.ID,
+			AccountID:        reconciliation.AccountID,
+			TransactionID:    transactionID,
+		}).Error
+	}
+	return nil
+}
+
+// postAdjustment 在对账单日期补记一笔金额为 difference 的调整交易，并将其计入本次对账
+func postAdjustment(tx *gorm.DB, reconciliation models.Reconciliation, difference models.Money, userID uint) (models.Transaction, error) {
+	adjustment := models.Transaction{
+		LedgerID:    reconciliation.LedgerID,
+		CreatedBy:   userID,
+		AccountID:   reconciliation.AccountID,
+		Amount:      difference,
+		Type:        "income",
+		Description: fmt.Sprintf("对账调整（%s）", reconciliation.StatementDate),
+		Date:        reconciliation.StatementDate,
+	}
+	if difference < 0 {
+		adjustment.Amount, adjustment.Type = -difference, "expense"
+	}
+
+	category, err := findOrCreateCategory(tx, reconciliation.LedgerID, adjustmentCategoryName, adjustment.Type)
+	if err != nil {
+		return adjustment, err
+	}
+	adjustment.CategoryID = category.ID
+
+	if err := validateTransaction(tx, &adjustment); err != nil {
+		return adjustment, err
+	}
+	if _, err := applyTransaction(tx, adjustment, 1); err != nil {
+		return adjustment, err
+	}
+	if err := tx.Create(&adjustment).Error; err != nil {
+		return adjustment, err
+	}
+	return adjustment, tx.Create(&models.ClearedTransaction{
+		ReconciliationID: reconciliation.ID,
+		AccountID:        reconciliation.AccountID,
+		TransactionID:    adjustment.ID,
+	}).Error
+}
+
+// findOrCreateCategory 查找账本中指定名称和类型的顶级分类，不存在时创建
+func findOrCreateCategory(tx *gorm.DB, ledgerID uint, name, categoryType string) (models.Category, error) {
+	var category models.Category
+	err := tx.Where("ledger_id = ? AND name = ? AND type = ? AND (parent_id = 0 OR parent_id IS NULL)", ledgerID, name, categoryType).First(&category).Error
+	if err == nil || !gorm.IsRecordNotFoundError(err) {
+		return category, err
+	}
+	category = models.Category{LedgerID: ledgerID, Name: name, Type: categoryType}
+	return category, tx.Create(&category).Error
+}
+
+// loadReconciliationSummary 计算对账的已清算余额和差额，并列出相关交易
+func loadReconciliationSummary(db *gorm.DB, reconciliation models.Reconciliation) (reconciliationSummary, error) {
+	summary := reconciliationSummary{Reconciliation: reconciliation, Transactions: []reconcileItem{}}
+
+	var account models.Account
+	if err := db.First(&account, reconciliation.AccountID).Error; err != nil {
+		return summary, err
+	}
+	var transactions []models.Transaction
+	if err := db.Preload("Category").Where("account_id = ? OR to_account_id = ?", account.ID, account.ID).
+		Order("date, id").Find(&transactions).Error; err != nil {
+		return summary, err
+	}
+	var clearances []models.ClearedTransaction
+	if err := db.Where("account_id = ?", account.ID).Find(&clearances).Error; err != nil {
+		return summary, err
+	}
+	clearedIn := make(map[uint]uint, len(clearances))
+	for _, clearance := range clearances {
+		clearedIn[clearance.TransactionID] = clearance.ReconciliationID
+	}
+
+	summary.ClearedBalance = account.Balance
+	for _, transaction := range transactions {
+		delta := accountDelta(transaction, account.ID)
+		reconciliationID, cleared := clearedIn[transaction.ID]
+		if !cleared {
+			summary.ClearedBalance -= delta
+		}
+
+		include := reconciliationID == reconciliation.ID
+		if reconciliation.FinishedAt == nil && !cleared {
+			include = transaction.Date <= reconciliation.StatementDate
+		}
+		if include {
+			summary.Transactions = append(summary.Transactions, reconcileItem{Transaction: transaction, Delta: delta, Cleared: cleared})
+		}
+	}
+
+	// 已完成的对账以调整后的对账单余额为准，之后的清算不再影响它
+	if reconciliation.FinishedAt != nil {
+		summary.ClearedBalance = reconciliation.StatementBalance
+	}
+	summary.Difference = reconciliation.StatementBalance - summary.ClearedBalance
+	return summary, nil
+}
+
+// transactionLocked 判断交易是否已在完成的对账中清算
+func transactionLocked(tx *gorm.DB, transactionID uint) (bool, error) {
+	var count int
+	err := tx.Table("cleared_transactions").
+		Joins("JOIN reconciliations ON reconciliations.id = cleared_transactions.reconciliation_id").
+		Where("cleared_transactions.transaction_id = ? AND reconciliations.finished_at IS NOT NULL", transactionID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// affectsBalance 判断交易的修改是否会改变对账结果（账户、金额、类型或日期）
+func affectsBalance(before, after models.Transaction) bool {
+	return before.AccountID != after.AccountID || before.ToAccountID != after.ToAccountID ||
+		before.Amount != after.Amount || before.ToAmount != after.ToAmount ||
+		before.Type != after.Type || before.Date != after.Date
+}
@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type RecurringHandler struct {
+	DB     *gorm.DB
+	Alerts *BudgetAlerter // 为空时不检查预算提醒
+}
+
+// 各周期单位的最大天数，用于估算跳过的期数
+var frequencyDays = map[string]int{
+	"daily":   1,
+	"weekly":  7,
+	"monthly": 31,
+	"yearly":  366,
+}
+
+// CreateRecurringTransaction 创建周期交易
+func (h *RecurringHandler) CreateRecurringTransaction(c *gin.Context) {
+	var recurring models.RecurringTransaction
+	if err := c.ShouldBindJSON(&recurring); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recurring.LedgerID = middleware.CurrentLedgerID(c)
+	recurring.CreatedBy = middleware.CurrentUserID(c)
+	if err := validateRecurring(h.DB, &recurring); err != nil {
+		respondError(c, err)
+		return
+	}
+	recurring.NextDate = nextOccurrence(recurring, "")
+
+	if err := h.DB.Create(&recurring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, recurring)
+}
+
+// GetRecurringTransactions 获取所有周期交易
+func (h *RecurringHandler) GetRecurringTransactions(c *gin.Context) {
+	var recurring []models.RecurringTransaction
+	if err := h.DB.Scopes(ledgerScope(c)).Order("id").Find(&recurring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, recurring)
+}
+
+// UpdateRecurringTransaction 更新周期交易；已生成的交易不受影响，之后的发生日期按新的规则重新计算
+func (h *RecurringHandler) UpdateRecurringTransaction(c *gin.Context) {
+	id, ok := pathID(c, "id", "recurring transaction")
+	if !ok {
+		return
+	}
+	var recurring models.RecurringTransaction
+
+	if err := h.DB.Scopes(ledgerScope(c)).First(&recurring, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recurring transaction not found"})
+		return
+	}
+
+	var input models.RecurringTransaction
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input.LedgerID = recurring.LedgerID
+	input.CreatedBy = recurring.CreatedBy
+	if err := validateRecurring(h.DB, &input); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	input.ID = recurring.ID
+	input.CreatedAt = recurring.CreatedAt
+	input.LastDate = recurring.LastDate
+	input.LastError = ""
+	input.NextDate = nextOccurrence(input, recurring.LastDate)
+	if err := h.DB.Save(&input).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, input)
+}
+
+// DeleteRecurringTransaction 删除周期交易，已生成的交易保留
+func (h *RecurringHandler) DeleteRecurringTransaction(c *gin.Context) {
+	id, ok := pathID(c, "id", "recurring transaction")
+	if !ok {
+		return
+	}
+	var recurring models.RecurringTransaction
+
+	if err := h.DB.Scopes(ledgerScope(c)).First(&recurring, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recurring transaction not found"})
+		return
+	}
+
+	if err := h.DB.Delete(&recurring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recurring transaction deleted successfully"})
+}
+
+// RunRecurringTransactions 立即生成当前账本所有已到期的周期交易
+func (h *RecurringHandler) RunRecurringTransactions(c *gin.Context) {
+	created, err := materializeRecurring(h.DB, ledgerScope(c), h.Alerts, time.Now().Format("2006-01-02"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"created": created})
+}
+
+// MaterializeRecurring 为所有账本的周期交易生成截至 today（含）尚未生成的交易，供定时调度使用
+func MaterializeRecurring(db *gorm.DB, alerts *BudgetAlerter, today string) (int, error) {
+	return materializeRecurring(db, nil, alerts, today)
+}
+
+// materializeRecurring 为 scope 范围内（为空时为全部）的周期交易生成截至 today（含）尚未生成的交易，
+// 包括停机期间错过的日期，返回新生成的交易数。单个模板生成失败时记录原因并跳过，下次运行时重试。
+// alerts 非空时检查生成的支出的预算提醒。
+func materializeRecurring(db *gorm.DB, scope func(*gorm.DB) *gorm.DB, alerts *BudgetAlerter, today string) (int, error) {
+	query := db
+	if scope != nil {
+		query = db.Scopes(scope)
+	}
+	var templates []models.RecurringTransaction
+	if err := query.Where("next_date <> '' AND next_date <= ?", today).Order("id").Find(&templates).Error; err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for i := range templates {
+		recurring := &templates[i]
+		for recurring.NextDate != "" && recurring.NextDate <= today {
+			date := recurring.NextDate
+			ok, err := materializeOccurrence(db, recurring)
+			if err != nil {
+				log.Printf("周期交易 %d 在 %s 生成失败: %v", recurring.ID, recurring.NextDate, err)
+				db.Model(&models.RecurringTransaction{}).Where("id = ?", recurring.ID).Update("last_error", err.Error())
+				break
+			}
+			if ok {
+				created++
+				if recurring.Type == "expense" {
+					alerts.Check(recurring.LedgerID, date)
+				}
+			}
+		}
+	}
+	return created, nil
+}
+
+// materializeOccurrence 生成 NextDate 对应的交易并推进 NextDate。next_date 作为乐观锁：
+// 若已被其他调度推进，则不生成交易并重新读取模板。
+func materializeOccurrence(db *gorm.DB, recurring *models.RecurringTransaction) (bool, error) {
+	date := recurring.NextDate
+	next := nextOccurrence(*recurring, date)
+
+	tx := db.Begin()
+
+	result := tx.Model(&models.RecurringTransaction{}).
+		Where("id = ? AND next_date = ?", recurring.ID, date).
+		Updates(map[string]interface{}{"next_date": next, "last_date": date, "last_error": ""})
+	if result.Error != nil {
+		tx.Rollback()
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return false, db.First(recurring, recurring.ID).Error
+	}
+
+	// 与手动记账和导入一样按规则补全分类、改写描述并追加标签，模板的分类优先
+	transaction := recurringTransaction(*recurring, date)
+	rules, err := loadRuleSet(tx, recurring.LedgerID)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	matched := rules.apply(transaction)
+	if transaction.CategoryID == 0 {
+		transaction.CategoryID = matched.CategoryID
+	}
+	if matched.Description != "" {
+		transaction.Description = matched.Description
+	}
+
+	if err := validateTransaction(tx, &transaction); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if _, err := applyTransaction(tx, transaction, 1); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tx.Create(&transaction).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tagTransaction(tx, &transaction, matched.Tags); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return false, err
+	}
+	recurring.NextDate = next
+	recurring.LastDate = date
+	return true, nil
+}
+
+// validateRecurring 校验周期规则，并按普通交易的规则校验模板本身
+func validateRecurring(db *gorm.DB, recurring *models.RecurringTransaction) error {
+	if _, ok := frequencyDays[recurring.Frequency]; !ok {
+		return &requestError{http.StatusBadRequest, "Frequency must be 'daily', 'weekly', 'monthly' or 'yearly'"}
+	}
+	if recurring.Interval == 0 {
+		recurring.Interval = 1
+	}
+	if recurring.Interval < 0 {
+		return &requestError{http.StatusBadRequest, "Interval must be greater than 0"}
+	}
+
+	start, err := normalizeDate(recurring.StartDate)
+	if err != nil {
+		return &requestError{http.StatusBadRequest, "Invalid start_date format. Use YYYY-MM-DD"}
+	}
+	recurring.StartDate = start
+	if recurring.EndDate != "" {
+		if _, err := time.Parse("2006-01-02", recurring.EndDate); err != nil {
+			return &requestError{http.StatusBadRequest, "Invalid end_date format. Use YYYY-MM-DD"}
+		}
+		if recurring.EndDate < recurring.StartDate {
+			return &requestError{http.StatusBadRequest, "end_date must not be before start_date"}
+		}
+	}
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	transaction := recurringTransaction(*recurring, recurring.StartDate)
+	if err := validateTransaction(tx, &transaction); err != nil {
+		return err
+	}
+	recurring.CategoryID = transaction.CategoryID
+	recurring.ToAccountID = transaction.ToAccountID
+	return nil
+}
+
+// recurringTransaction 按模板生成指定日期的交易
+func recurringTransaction(recurring models.RecurringTransaction, date string) models.Transaction {
+	return models.Transaction{
+		LedgerID:    recurring.LedgerID,
+		CreatedBy:   recurring.CreatedBy,
+		AccountID:   recurring.AccountID,
+		ToAccountID: recurring.ToAccountID,
+		Amount:      recurring.Amount,
+		Type:        recurring.Type,
+		CategoryID:  recurring.CategoryID,
+		Description: recurring.Description,
+		Date:        date,
+		RecurringID: recurring.ID,
+	}
+}
+
+// nextOccurrence 返回晚于 after 的第一个发生日期（after 为空时即 StartDate），超过 EndDate 时返回空
+func nextOccurrence(recurring models.RecurringTransaction, after string) string {
+	start, err := time.Parse("2006-01-02", recurring.StartDate)
+	if err != nil {
+		return ""
+	}
+
+	// 从估算的期数开始，避免长期停机后逐期查找
+	n := 0
+	if last, err := time.Parse("2006-01-02", after); err == nil && last.After(start) {
+		days := int(last.Sub(start).Hours() / 24)
+		n = days/(frequencyDays[recurring.Frequency]*recurring.Interval) - 1
+		if n < 0 {
+			n = 0
+		}
+	}
+
+	for ; ; n++ {
+		date := occurrence(start, recurring.Frequency, recurring.Interval, n).Format("2006-01-02")
+		if recurring.EndDate != "" && date > recurring.EndDate {
+			return ""
+		}
+		if date > after {
+			return date
+		}
+	}
+}
+
+// occurrence 返回从 start 起第 n 次（从 0 开始）发生的日期。按月和按年的规则始终以 start 的日期为准，
+// 当月没有该日时取月末，如 1 月 31 日之后依次为 2 月 28 日、3 月 31 日
+func occurrence(start time.Time, frequency string, interval, n int) time.Time {
+	switch frequency {
+	case "daily":
+		return start.AddDate(0, 0, n*interval)
+	case "weekly":
+		return start.AddDate(0, 0, 7*n*interval)
+	case "yearly":
+		return addMonths(start, 12*n*interval)
+	default:
+		return addMonths(start, n*interval)
+	}
+}
+
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	day := t.Day()
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
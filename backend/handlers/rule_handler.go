@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type RuleHandler struct {
+	DB     *gorm.DB
+	Alerts *BudgetAlerter // 为空时不检查预算提醒
+}
+
+// CreateRule 创建自动归类规则
+func (h *RuleHandler) CreateRule(c *gin.Context) {
+	var rule models.Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule.LedgerID = middleware.CurrentLedgerID(c)
+	if err := validateRule(h.DB, &rule); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// GetRules 按执行顺序获取所有规则
+func (h *RuleHandler) GetRules(c *gin.Context) {
+	var rules []models.Rule
+	if err := h.DB.Scopes(ledgerScope(c)).Order("priority, id").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// UpdateRule 更新规则
+func (h *RuleHandler) UpdateRule(c *gin.Context) {
+	id, ok := pathID(c, "id", "rule")
+	if !ok {
+		return
+	}
+	var rule models.Rule
+
+	if err := h.DB.Scopes(ledgerScope(c)).First(&rule, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+
+	var input models.Rule
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input.ID = rule.ID
+	input.LedgerID = rule.LedgerID
+	input.CreatedAt = rule.CreatedAt
+	if err := validateRule(h.DB, &input); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.DB.Save(&input).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, input)
+}
+
+// DeleteRule 删除规则，已归类的交易不受影响
+func (h *RuleHandler) DeleteRule(c *gin.Context) {
+	id, ok := pathID(c, "id", "rule")
+	if !ok {
+		return
+	}
+	if err := h.DB.Scopes(ledgerScope(c)).Delete(&models.Rule{}, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted successfully"})
+}
+
+// ruleChange 重新应用规则对一笔已有交易的修改
+type ruleChange struct {
+	TransactionID  uint     `json:"transaction_id"`
+	Date           string   `json:"date"`
+	Rules          []uint   `json:"rules"` // 匹配的规则
+	OldCategoryID  uint     `json:"old_category_id,omitempty"`
+	NewCategoryID  uint     `json:"new_category_id,omitempty"`
+	OldDescription string   `json:"old_description,omitempty"`
+	NewDescription string   `json:"new_description,omitempty"`
+	AddedTags      []string `json:"added_tags,omitempty"`
+}
+
+// ApplyRules 对已有交易重新应用规则，可按 start_date、end_date 限定日期范围。
+// dry_run=true 时只返回将要发生的修改，不写入数据库。规则设置的分类会覆盖交易原有的分类
+func (h *RuleHandler) ApplyRules(c *gin.Context) {
+	rules, err := loadRuleSet(h.DB, middleware.CurrentLedgerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.DB.Scopes(ledgerScope(c)).Preload("Tags").Preload("Splits")
+	if startDate := c.Query("start_date"); startDate != "" {
+		query = query.Where("date >= ?", startDate)
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		query = query.Where("date <= ?", endDate)
+	}
+	var transactions []models.Transaction
+	if err := query.Order("date, id").Find(&transactions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var changes []ruleChange
+	var changed []*models.Transaction
+	for i := range transactions {
+		if change, ok := rules.diff(transactions[i]); ok {
+			changes = append(changes, change)
+			changed = append(changed, &transactions[i])
+		}
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	if !dryRun {
+		tx := h.DB.Begin()
+		for i, change := range changes {
+			if err := applyRuleChange(tx, changed[i], change); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "transaction_id": change.TransactionID})
+				return
+			}
+		}
+		tx.Commit()
+
+		// 分类变化可能使支出计入新的预算
+		var dates []string
+		for _, transaction := range changed {
+			if transaction.Type == "expense" {
+				dates = append(dates, transaction.Date)
+			}
+		}
+		h.Alerts.Check(middleware.CurrentLedgerID(c), dates...)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run": dryRun,
+		"checked": len(transactions),
+		"changed": len(changes),
+		"changes": changes,
+	})
+}
+
+// applyRuleChange 在事务 tx 中写入一笔交易的修改。分类只影响统计，不影响账户余额
+func applyRuleChange(tx *gorm.DB, transaction *models.Transaction, change ruleChange) error {
+	updates := make(map[string]interface{})
+	if change.NewCategoryID != 0 {
+		updates["category_id"] = change.NewCategoryID
+	}
+	if change.NewDescription != "" {
+		updates["description"] = change.NewDescription
+	}
+	if len(updates) > 0 {
+		if err := tx.Model(&models.Transaction{}).Where("id = ?", transaction.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return tagTransaction(tx, transaction, change.AddedTags)
+}
+
+// validateRule 校验规则的条件和动作，引用的账户和分类必须属于规则所在的账本
+func validateRule(db *gorm.DB, rule *models.Rule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return &requestError{http.StatusBadRequest, "Name is required"}
+	}
+	switch rule.Type {
+	case "", "income", "expense", "transfer":
+	default:
+		return &requestError{http.StatusBadRequest, "Type must be 'income', 'expense' or 'transfer'"}
+	}
+	if rule.DescriptionRegex != "" {
+		if _, err := regexp.Compile(rule.DescriptionRegex); err != nil {
+			return &requestError{http.StatusBadRequest, "Invalid description_regex: " + err.Error()}
+		}
+	}
+	if rule.MinAmount < 0 || rule.MaxAmount < 0 {
+		return &requestError{http.StatusBadRequest, "Amount limits must not be negative"}
+	}
+	if rule.MaxAmount > 0 && rule.MinAmount > rule.MaxAmount {
+		return &requestError{http.StatusBadRequest, "min_amount must not be greater than max_amount"}
+	}
+
+	var tags models.StringList
+	for _, name := range rule.AddTags {
+		if name = strings.TrimSpace(name); name != "" {
+			tags = append(tags, name)
+		}
+	}
+	rule.AddTags = tags
+	rule.SetDescription = strings.TrimSpace(rule.SetDescription)
+	if rule.CategoryID == 0 && len(rule.AddTags) == 0 && rule.SetDescription == "" {
+		return &requestError{http.StatusBadRequest, "Rule must set a category, add tags or rewrite the description"}
+	}
+
+	owned := db.Where("ledger_id = ?", rule.LedgerID)
+	if rule.AccountID != 0 {
+		var account models.Account
+		if err := owned.First(&account, rule.AccountID).Error; err != nil {
+			return &requestError{http.StatusNotFound, "Account not found"}
+		}
+	}
+	if rule.CategoryID != 0 {
+		var category models.Category
+		if err := owned.First(&category, rule.CategoryID).Error; err != nil {
+			return &requestError{http.StatusNotFound, "Category not found"}
+		}
+		if rule.Type == "transfer" || (rule.Type != "" && category.Type != rule.Type) {
+			return &requestError{http.StatusBadRequest, "Category type does not match rule type"}
+		}
+	}
+	return nil
+}
+
+// ruleSet 账本中已启用的规则，按执行顺序排列
+type ruleSet struct {
+	rules      []compiledRule
+	categories map[uint]string // 分类编号 → 分类类型
+}
+
+type compiledRule struct {
+	models.Rule
+	re *regexp.Regexp
+}
+
+// ruleResult 规则对一笔交易的处理结果，未设置的字段表示没有规则修改它
+type ruleResult struct {
+	Rules       []uint
+	CategoryID  uint
+	Description string
+	Tags        []string
+}
+
+// loadRuleSet 载入账本中已启用的规则
+func loadRuleSet(db *gorm.DB, ledgerID uint) (*ruleSet, error) {
+	var rules []models.Rule
+	if err := db.Where("ledger_id = ? AND disabled = ?", ledgerID, false).Order("priority, id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	rs := &ruleSet{categories: make(map[uint]string)}
+	for _, rule := range rules {
+		compiled := compiledRule{Rule: rule}
+		if rule.DescriptionRegex != "" {
+			re, err := regexp.Compile(rule.DescriptionRegex)
+			if err != nil {
+				continue
+			}
+			compiled.re = re
+		}
+		rs.rules = append(rs.rules, compiled)
+	}
+
+	var categories []models.Category
+	if err := db.Where("ledger_id = ?", ledgerID).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		rs.categories[category.ID] = category.Type
+	}
+	return rs, nil
+}
+
+// apply 按顺序对交易执行所有匹配的规则。条件始终基于交易原始的描述判断；
+// 规则设置的分类与交易类型不一致时忽略该分类
+func (rs *ruleSet) apply(transaction models.Transaction) ruleResult {
+	var result ruleResult
+	seen := make(map[string]bool)
+	for _, rule := range rs.rules {
+		match, ok := rule.match(transaction)
+		if !ok {
+			continue
+		}
+		result.Rules = append(result.Rules, rule.ID)
+
+		if result.CategoryID == 0 && rule.CategoryID != 0 && rs.categories[rule.CategoryID] == transaction.Type {
+			result.CategoryID = rule.CategoryID
+		}
+		if result.Description == "" && rule.SetDescription != "" {
+			result.Description = rule.SetDescription
+			if rule.re != nil {
+				result.Description = string(rule.re.ExpandString(nil, rule.SetDescription, transaction.Description, match))
+			}
+		}
+		for _, tag := range rule.AddTags {
+			if !seen[tag] {
+				seen[tag] = true
+				result.Tags = append(result.Tags, tag)
+			}
+		}
+	}
+	return result
+}
+
+// diff 计算对已有交易重新应用规则后的修改，没有修改时返回 false
+func (rs *ruleSet) diff(transaction models.Transaction) (ruleChange, bool) {
+	result := rs.apply(transaction)
+	change := ruleChange{TransactionID: transaction.ID, Date: transaction.Date, Rules: result.Rules}
+
+	// 拆分交易的分类由各拆分行决定，规则不修改
+	if result.CategoryID != 0 && result.CategoryID != transaction.CategoryID && len(transaction.Splits) == 0 {
+		change.OldCategoryID = transaction.CategoryID
+		change.NewCategoryID = result.CategoryID
+	}
+	if result.Description != "" && result.Description != transaction.Description {
+		change.OldDescription = transaction.Description
+		change.NewDescription = result.Description
+	}
+	existing := make(map[string]bool)
+	for _, tag := range transaction.Tags {
+		existing[tag.Name] = true
+	}
+	for _, tag := range result.Tags {
+		if !existing[tag] {
+			change.AddedTags = append(change.AddedTags, tag)
+		}
+	}
+
+	changed := change.NewCategoryID != 0 || change.NewDescription != "" || len(change.AddedTags) > 0
+	return change, changed
+}
+
+// match 判断交易是否满足规则的所有条件，返回正则表达式匹配到的分组位置
+func (r compiledRule) match(transaction models.Transaction) ([]int, bool) {
+	if r.Type != "" && r.Type != transaction.Type {
+		return nil, false
+	}
+	if r.AccountID != 0 && r.AccountID != transaction.AccountID {
+		return nil, false
+	}
+	if r.MinAmount > 0 && transaction.Amount < r.MinAmount {
+		return nil, false
+	}
+	if r.MaxAmount > 0 && transaction.Amount > r.MaxAmount {
+		return nil, false
+	}
+	if r.DescriptionContains != "" &&
+		!strings.Contains(strings.ToLower(transaction.Description), strings.ToLower(r.DescriptionContains)) {
+		return nil, false
+	}
+	if r.re == nil {
+		return nil, true
+	}
+	match := r.re.FindStringSubmatchIndex(transaction.Description)
+	return match, match != nil
+}
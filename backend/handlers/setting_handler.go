@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance/middleware"
+	"personal-finance/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type SettingHandler struct {
+	DB *gorm.DB
+}
+
+// GetSettings 获取当前账本的设置
+func (h *SettingHandler) GetSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"base_currency": baseCurrency(h.DB, middleware.CurrentLedgerID(c)),
+	})
+}
+
+// UpdateSettings 更新当前账本的设置
+func (h *SettingHandler) UpdateSettings(c *gin.Context) {
+	var input struct {
+		BaseCurrency string `json:"base_currency" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currency, err := normalizeCurrency(input.BaseCurrency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 复合主键中的账本编号可能为零值，gorm 的 Save 会将其当作未设置，这里直接写入
+	if err := h.DB.Exec("INSERT OR REPLACE INTO settings (ledger_id, name, value) VALUES (?, ?, ?)",
+		middleware.CurrentLedgerID(c), models.SettingBaseCurrency, currency).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"base_currency": currency,
+	})
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"personal-finance/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type StatementHandler struct {
+	DB *gorm.DB
+}
+
+const (
+	defaultStatementCount = 6
+	maxStatementCount     = 60
+	// defaultGraceDays 未设置还款日时，到期日按账单日后的天数计算
+	defaultGraceDays = 20
+	// minimumPaymentPercent 最低还款额占账单欠款的百分比
+	minimumPaymentPercent = 10
+)
+
+// creditCardStatement 信用卡的一期账单。金额均为欠款方向（账户余额取反），为负表示有溢缴款
+type creditCardStatement struct {
+	StartDate        string       `json:"start_date"`
+	ClosingDate      string       `json:"closing_date"`
+	DueDate          string       `json:"due_date"`
+	OpeningBalance   models.Money `json:"opening_balance"` // 上一账单日的欠款
+	Charges          models.Money `json:"charges"`         // 消费及转出
+	Credits          models.Money `json:"credits"`         // 退款等收入
+	Payments         models.Money `json:"payments"`        // 本期内转入的还款
+	StatementBalance models.Money `json:"statement_balance"`
+	MinimumPayment   models.Money `json:"minimum_payment"`
+	PaidAmount       models.Money `json:"paid_amount"` // 账单日之后、下一账单日（含）之前转入的还款
+	// Status：open 尚未出账，paid 已还清，due 待还款，minimum_paid 逾期但已还最低还款额，overdue 逾期
+	Status              string               `json:"status"`
+	Transactions        []models.Transaction `json:"transactions,omitempty"`
+	PaymentTransactions []models.Transaction `json:"payment_transactions,omitempty"`
+}
+
+// GetStatements 获取信用卡最近 count 期（默认 6 期，含尚未出账的当期）账单，最近的在前
+func (h *StatementHandler) GetStatements(c *gin.Context) {
+	account, transactions, ok := h.loadCard(c)
+	if !ok {
+		return
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(defaultStatementCount)))
+	if err != nil || count < 1 || count > maxStatementCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("count must be between 1 and %d", maxStatementCount)})
+		return
+	}
+
+	today := time.Now().Format("2006-01-02")
+	closing := currentClosingDate(account.StatementDay, today)
+	statements := []creditCardStatement{}
+	for k := 0; k < count; k++ {
+		closingDate := statementClosingDate(closing, account.StatementDay, -k)
+		// 早于第一笔交易的账单没有意义，但至少返回当期
+		if k > 0 && (len(transactions) == 0 || closingDate < transactions[0].Date) {
+			break
+		}
+		statements = append(statements, buildStatement(account, transactions, closingDate, today, false))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"account":    account,
+		"statements": statements,
+	})
+}
+
+// GetStatement 获取账单日为 closing_date 的一期账单及其交易明细
+func (h *StatementHandler) GetStatement(c *gin.Context) {
+	account, transactions, ok := h.loadCard(c)
+	if !ok {
+		return
+	}
+
+	closing, err := time.Parse("2006-01-02", c.Param("closing_date"))
+	if err != nil || !closing.Equal(dayOfMonth(closing.Year(), closing.Month(), account.StatementDay)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Statement not found"})
+		return
+	}
+
+	statement := buildStatement(account, transactions, closing.Format("2006-01-02"), time.Now().Format("2006-01-02"), true)
+	c.JSON(http.StatusOK, gin.H{
+		"account":   account,
+		"statement": statement,
+	})
+}
+
+// loadCard 载入路径中的信用卡账户及其全部交易（按日期排序），账户不是设置了账单日的信用卡时返回错误
+func (h *StatementHandler) loadCard(c *gin.Context) (models.Account, []models.Transaction, bool) {
+	var account models.Account
+	id, ok := pathID(c, "id", "account")
+	if !ok {
+		return account, nil, false
+	}
+	if err := h.DB.Scopes(ledgerScope(c)).First(&account, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return account, nil, false
+	}
+	if account.Type != models.AccountCreditCard {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Statements are only available for credit cards"})
+		return account, nil, false
+	}
+	if account.StatementDay == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Statement day is not set"})
+		return account, nil, false
+	}
+
+	var transactions []models.Transaction
+	if err := h.DB.Scopes(ledgerScope(c)).Preload("Category").
+		Where("account_id = ? OR to_account_id = ?", account.ID, account.ID).
+		Order("date, id").Find(&transactions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return account, nil, false
+	}
+	return account, transactions, true
+}
+
+// buildStatement 计算账单日为 closingDate 的账单。历史余额由当前余额倒推：
+// 某日的余额等于当前余额减去该日之后所有交易的影响
+func buildStatement(account models.Account, transactions []models.Transaction, closingDate, today string, details bool) creditCardStatement {
+	closing, _ := time.Parse("2006-01-02", closingDate)
+	previous := statementClosingDate(closingDate, account.StatementDay, -1)
+	next := statementClosingDate(closingDate, account.StatementDay, 1)
+	statement := creditCardStatement{
+		StartDate:   mustParseDate(previous).AddDate(0, 0, 1).Format("2006-01-02"),
+		ClosingDate: closingDate,
+		DueDate:     statementDueDate(closing, account.PaymentDueDay).Format("2006-01-02"),
+	}
+
+	var afterPrevious models.Money
+	for _, transaction := range transactions {
+		delta := accountDelta(transaction, account.ID)
+		if transaction.Date > previous {
+			afterPrevious += delta
+		}
+		switch {
+		case transaction.Date > previous && transaction.Date <= closingDate:
+			switch {
+			case transaction.ToAccountID == account.ID:
+				statement.Payments += delta
+			case delta > 0:
+				statement.Credits += delta
+			default:
+				statement.Charges -= delta
+			}
+			if details {
+				statement.Transactions = append(statement.Transactions, transaction)
+			}
+		case transaction.Date > closingDate && transaction.Date <= next && transaction.ToAccountID == account.ID:
+			statement.PaidAmount += delta
+			if details {
+				statement.PaymentTransactions = append(statement.PaymentTransactions, transaction)
+			}
+		}
+	}
+
+	statement.OpeningBalance = -(account.Balance - afterPrevious)
+	statement.StatementBalance = statement.OpeningBalance + statement.Charges - statement.Credits - statement.Payments
+	statement.MinimumPayment = minimumPayment(statement.StatementBalance)
+
+	switch {
+	case closingDate >= today:
+		statement.Status = "open"
+	case statement.PaidAmount >= statement.StatementBalance:
+		statement.Status = "paid"
+	case today <= statement.DueDate:
+		statement.Status = "due"
+	case statement.PaidAmount >= statement.MinimumPayment:
+		statement.Status = "minimum_paid"
+	default:
+		statement.Status = "overdue"
+	}
+	return statement
+}
+
+// minimumPayment 按账单欠款的固定比例计算最低还款额，向上取整到分
+func minimumPayment(balance models.Money) models.Money {
+	if balance <= 0 {
+		return 0
+	}
+	return (balance*minimumPaymentPercent + 99) / 100
+}
+
+// currentClosingDate 返回 today 当天或之后最近的账单日，即当期账单的账单日
+func currentClosingDate(statementDay int, today string) string {
+	day := mustParseDate(today)
+	closing := dayOfMonth(day.Year(), day.Month(), statementDay)
+	if closing.Before(day) {
+		closing = dayOfMonth(day.Year(), day.Month()+1, statementDay)
+	}
+	return closing.Format("2006-01-02")
+}
+
+// statementClosingDate 返回 closingDate 之后第 n 期（n 为负时为之前）的账单日
+func statementClosingDate(closingDate string, statementDay, n int) string {
+	closing := mustParseDate(closingDate)
+	return dayOfMonth(closing.Year(), closing.Month()+time.Month(n), statementDay).Format("2006-01-02")
+}
+
+// statementDueDate 返回账单的到期还款日：账单日之后第一个还款日，未设置还款日时为账单日后 defaultGraceDays 天
+func statementDueDate(closing time.Time, dueDay int) time.Time {
+	if dueDay == 0 {
+		return closing.AddDate(0, 0, defaultGraceDays)
+	}
+	due := dayOfMonth(closing.Year(), closing.Month(), dueDay)
+	if !due.After(closing) {
+		due = dayOfMonth(closing.Year(), closing.Month()+1, dueDay)
+	}
+	return due
+}
+
+// dayOfMonth 返回某月的第 day 天，当月没有该日时取月末
+func dayOfMonth(year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// mustParseDate 解析已校验过的 YYYY-MM-DD 日期
+func mustParseDate(date string) time.Time {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
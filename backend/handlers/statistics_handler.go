@@ -2,8 +2,9 @@ package handlers
 
 import (
 	"net/http"
+	"personal-finance/middleware"
 	"personal-finance/models"
-	"strconv"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,7 +15,7 @@ type StatisticsHandler struct {
 	DB *gorm.DB
 }
 
-// GetStatistics 获取统计数据
+// GetStatistics 获取统计数据，rollup=true 时上级分类的金额包含所有下级分类
 func (h *StatisticsHandler) GetStatistics(c *gin.Context) {
 	// 获取查询参数
 	startDate := c.Query("start_date")
@@ -30,303 +31,402 @@ func (h *StatisticsHandler) GetStatistics(c *gin.Context) {
 		endDate = time.Now().Format("2006-01-02")
 	}
 
-	// 查询总收入和支出
-	var stats models.Statistics
-	h.DB.Model(&models.Transaction{}).
-		Where("date(created_at) BETWEEN ? AND ? AND type = ?", startDate, endDate, "income").
-		Select("COALESCE(SUM(amount), 0)").Row().
-		Scan(&stats.TotalIncome)
-
-	h.DB.Model(&models.Transaction{}).
-		Where("date(created_at) BETWEEN ? AND ? AND type = ?", startDate, endDate, "expense").
-		Select("COALESCE(SUM(amount), 0)").Row().
-		Scan(&stats.TotalExpense)
-
-	stats.NetAmount = stats.TotalIncome - stats.TotalExpense
-
-	// 按分类统计
-	rows, err := h.DB.Table("transactions").
-		Select("categories.id, categories.name, SUM(transactions.amount) as amount").
-		Joins("JOIN categories ON transactions.category_id = categories.id").
-		Where("date(transactions.created_at) BETWEEN ? AND ?", startDate, endDate).
-		Group("categories.id, categories.name").
-		Rows()
-
+	cv, err := newCurrencyConverter(h.DB, middleware.CurrentLedgerID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var stat models.CategoryStatistics
-		rows.Scan(&stat.CategoryID, &stat.CategoryName, &stat.Amount)
-		
-		// 计算百分比
-		if stat.Amount > 0 {
-			stat.Percentage = (stat.Amount / stats.TotalExpense) * 100
-		}
-		
-		stats.ByCategory = append(stats.ByCategory, stat)
+	// 载入区间内的收支记录，按交易日期汇率换算为本位币
+	entries, err := loadStatEntries(h.DB.Scopes(ledgerScope(c)).Where("date BETWEEN ? AND ?", startDate, endDate), cv)
+	if err != nil {
+		c.JSON(conversionStatus(err), gin.H{"error": err.Error()})
+		return
 	}
 
-	// 按月份统计
-	rows, err = h.DB.Table("transactions").
-		Select("strftime('%Y', created_at) as year, strftime('%m', created_at) as month, " +
-			"SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END) as income, " +
-			"SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END) as expense").
-		Where("created_at BETWEEN ? AND ?", startDate, endDate).
-		Group("year, month").
-		Order("year DESC, month DESC").
-		Rows()
-
-	if err != nil {
+	var categories []models.Category
+	if err := h.DB.Scopes(ledgerScope(c)).Find(&categories).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var stat models.MonthlyStatistics
-		rows.Scan(&stat.Year, &stat.Month, &stat.Income, &stat.Expense)
-		stat.NetAmount = stat.Income - stat.Expense
-		stats.ByMonth = append(stats.ByMonth, stat)
+	parents := make(map[uint]uint)
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
 	}
+	rollup := c.Query("rollup") == "true"
 
-	c.JSON(http.StatusOK, stats)
-}
+	// 汇总总收支、分类、月份和成员
+	var stats models.Statistics
+	byCategory := make(map[uint]models.Money)
+	byMonth := make(map[string]*models.MonthlyStatistics)
+	byMember := make(map[uint]*models.MemberStatistics)
+	for _, entry := range entries {
+		member, ok := byMember[entry.CreatedBy]
+		if !ok {
+			member = &models.MemberStatistics{UserID: entry.CreatedBy}
+			byMember[entry.CreatedBy] = member
+		}
 
-// GetBudgetOverview 获取预算概览
-// CreateBudget 创建预算
-func (h *StatisticsHandler) CreateBudget(c *gin.Context) {
-	var input models.BudgetInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
+		month, ok := byMonth[entry.Date[:7]]
+		if !ok {
+			date, _ := time.Parse("2006-01-02", entry.Date)
+			month = &models.MonthlyStatistics{Year: date.Year(), Month: int(date.Month())}
+			byMonth[entry.Date[:7]] = month
+		}
 
-	// 验证分类是否存在
-	var category models.Category
-	if err := h.DB.First(&category, input.CategoryID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found"})
-		return
+		if entry.Type == "income" {
+			stats.TotalIncome += entry.Amount
+			month.Income += entry.Amount
+			member.Income += entry.Amount
+		} else {
+			stats.TotalExpense += entry.Amount
+			month.Expense += entry.Amount
+			member.Expense += entry.Amount
+		}
+		byCategory[entry.CategoryID] += entry.Amount
+		if rollup {
+			for _, ancestor := range categoryAncestors(parents, entry.CategoryID) {
+				byCategory[ancestor] += entry.Amount
+			}
+		}
 	}
+	stats.NetAmount = stats.TotalIncome - stats.TotalExpense
 
-	// 验证日期格式
-	startDate, err := time.Parse("2006-01-02", input.StartDate)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format. Use YYYY-MM-DD"})
-		return
+	// 按分类统计，百分比相对于同类型（收入或支出）的总额
+	for _, category := range categories {
+		amount, ok := byCategory[category.ID]
+		if !ok {
+			continue
+		}
+		stat := models.CategoryStatistics{
+			CategoryID:   category.ID,
+			CategoryName: category.Name,
+			ParentID:     category.ParentID,
+			Amount:       amount,
+		}
+		total := stats.TotalExpense
+		if category.Type == "income" {
+			total = stats.TotalIncome
+		}
+		if total > 0 {
+			stat.Percentage = float64(amount) / float64(total) * 100
+		}
+		stats.ByCategory = append(stats.ByCategory, stat)
 	}
 
-	endDate, err := time.Parse("2006-01-02", input.EndDate)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format. Use YYYY-MM-DD"})
-		return
+	// 按月份统计，最近的月份在前
+	months := make([]string, 0, len(byMonth))
+	for key := range byMonth {
+		months = append(months, key)
 	}
-
-	// 验证日期范围
-	if endDate.Before(startDate) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "End date must be after start date"})
-		return
+	sort.Sort(sort.Reverse(sort.StringSlice(months)))
+	for _, key := range months {
+		stat := byMonth[key]
+		stat.NetAmount = stat.Income - stat.Expense
+		stats.ByMonth = append(stats.ByMonth, *stat)
 	}
 
-	// 验证金额
-	if input.Amount <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount must be greater than 0"})
+	// 按记账成员统计，按用户编号排序；已退出账本的成员仍保留其记录
+	var users []models.User
+	userIDs := make([]uint, 0, len(byMember))
+	for id := range byMember {
+		userIDs = append(userIDs, id)
+	}
+	if err := h.DB.Where("id IN (?)", userIDs).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-
-	// 创建预算
-	budget := models.Budget{
-		CategoryID: input.CategoryID,
-		Amount:     input.Amount,
-		StartDate:  input.StartDate,
-		EndDate:    input.EndDate,
+	for _, user := range users {
+		byMember[user.ID].Username = user.Username
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+	for _, id := range userIDs {
+		stats.ByMember = append(stats.ByMember, *byMember[id])
 	}
 
-	if err := h.DB.Create(&budget).Error; err != nil {
+	// 按标签统计，带多个标签的交易计入每个标签，按标签编号排序
+	if stats.ByTag, err = tagStatistics(h.DB, middleware.CurrentLedgerID(c), entries); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 加载分类信息
-	h.DB.Model(&budget).Related(&budget.Category)
-
-	c.JSON(http.StatusCreated, budget)
+	c.JSON(http.StatusOK, stats)
 }
 
-// GetBudgets 获取预算列表
-func (h *StatisticsHandler) GetBudgets(c *gin.Context) {
-	var budgets []models.Budget
-
-	query := h.DB.Preload("Category")
-
-	// 支持按分类ID筛选
-	if categoryID := c.Query("category_id"); categoryID != "" {
-		query = query.Where("category_id = ?", categoryID)
+// tagStatistics 汇总 entries 在账本各标签下的收支
+func tagStatistics(db *gorm.DB, ledgerID uint, entries []statEntry) ([]models.TagStatistics, error) {
+	rows, err := db.Table("transaction_tags").
+		Select("transaction_tags.transaction_id, tags.id, tags.name").
+		Joins("JOIN tags ON tags.id = transaction_tags.tag_id").
+		Where("tags.ledger_id = ?", ledgerID).
+		Rows()
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	// 支持按日期范围筛选
-	if startDate := c.Query("start_date"); startDate != "" {
-		query = query.Where("start_date >= ?", startDate)
-	}
-	if endDate := c.Query("end_date"); endDate != "" {
-		query = query.Where("end_date <= ?", endDate)
+	links := make(map[uint][]uint)
+	names := make(map[uint]string)
+	for rows.Next() {
+		var transactionID, tagID uint
+		var name string
+		if err := rows.Scan(&transactionID, &tagID, &name); err != nil {
+			return nil, err
+		}
+		links[transactionID] = append(links[transactionID], tagID)
+		names[tagID] = name
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	byTag := make(map[uint]*models.TagStatistics)
+	for _, entry := range entries {
+		for _, tagID := range links[entry.TransactionID] {
+			stat, ok := byTag[tagID]
+			if !ok {
+				stat = &models.TagStatistics{TagID: tagID, TagName: names[tagID]}
+				byTag[tagID] = stat
+			}
+			if entry.Type == "income" {
+				stat.Income += entry.Amount
+			} else {
+				stat.Expense += entry.Amount
+			}
+		}
 	}
 
-	if err := query.Find(&budgets).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	tagIDs := make([]uint, 0, len(byTag))
+	for id := range byTag {
+		tagIDs = append(tagIDs, id)
 	}
+	sort.Slice(tagIDs, func(i, j int) bool { return tagIDs[i] < tagIDs[j] })
+	var result []models.TagStatistics
+	for _, id := range tagIDs {
+		result = append(result, *byTag[id])
+	}
+	return result, nil
+}
 
-	c.JSON(http.StatusOK, budgets)
+// statEntry 统计用的一条收支记录，金额已换算为本位币
+type statEntry struct {
+	TransactionID uint
+	Type          string
+	CategoryID    uint
+	CreatedBy     uint
+	Date          string
+	Amount        models.Money
 }
 
-// UpdateBudget 更新预算
-func (h *StatisticsHandler) UpdateBudget(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+// loadStatEntries 载入 query 条件下的收支交易（不含转账），并按交易日期的汇率换算为本位币。
+// 拆分交易展开为每个拆分行一条记录，分别计入各自的分类
+func loadStatEntries(query *gorm.DB, cv *currencyConverter) ([]statEntry, error) {
+	rows, err := query.Model(&models.Transaction{}).
+		Where("type IN (?)", []string{"income", "expense"}).
+		Select("id, type, category_id, COALESCE(created_by, 0), date, amount, COALESCE(currency, '')").
+		Rows()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget ID"})
-		return
+		return nil, err
 	}
+	defer rows.Close()
 
-	var budget models.Budget
-	if err := h.DB.First(&budget, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
-		return
+	var entries []statEntry
+	currencies := make(map[uint]string)
+	for rows.Next() {
+		var entry statEntry
+		var currency string
+		if err := rows.Scan(&entry.TransactionID, &entry.Type, &entry.CategoryID, &entry.CreatedBy, &entry.Date, &entry.Amount, &currency); err != nil {
+			return nil, err
+		}
+		if entry.Amount, err = cv.toBase(entry.Amount, currency, entry.Date); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+		currencies[entry.TransactionID] = currency
 	}
-
-	// 绑定更新数据
-	var input models.BudgetInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	// 验证分类是否存在
-	if input.CategoryID != budget.CategoryID {
-		var category models.Category
-		if err := h.DB.First(&category, input.CategoryID).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found"})
-			return
+	splits, err := loadSplits(query.New(), entries)
+	if err != nil || len(splits) == 0 {
+		return entries, err
+	}
+	expanded := make([]statEntry, 0, len(entries))
+	for _, entry := range entries {
+		lines := splits[entry.TransactionID]
+		if len(lines) == 0 {
+			expanded = append(expanded, entry)
+			continue
+		}
+		// 各行分别换算，最后一行取差额，使合计与整笔交易换算的结果一致
+		remaining := entry.Amount
+		for i, line := range lines {
+			split := entry
+			split.CategoryID = line.CategoryID
+			if i == len(lines)-1 {
+				split.Amount = remaining
+			} else if split.Amount, err = cv.toBase(line.Amount, currencies[entry.TransactionID], entry.Date); err != nil {
+				return nil, err
+			}
+			remaining -= split.Amount
+			expanded = append(expanded, split)
 		}
 	}
+	return expanded, nil
+}
 
-	// 验证日期格式
-	startDate, err := time.Parse("2006-01-02", input.StartDate)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format. Use YYYY-MM-DD"})
-		return
-	}
+// splitBatchSize 按交易编号批量查询拆分行时每批的数量，避免超出 SQLite 的参数上限
+const splitBatchSize = 500
 
-	endDate, err := time.Parse("2006-01-02", input.EndDate)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format. Use YYYY-MM-DD"})
-		return
+// loadSplits 载入 entries 中各交易的拆分行，按交易编号分组
+func loadSplits(db *gorm.DB, entries []statEntry) (map[uint][]models.TransactionSplit, error) {
+	result := make(map[uint][]models.TransactionSplit)
+	for start := 0; start < len(entries); start += splitBatchSize {
+		end := start + splitBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		ids := make([]uint, 0, end-start)
+		for _, entry := range entries[start:end] {
+			ids = append(ids, entry.TransactionID)
+		}
+		var splits []models.TransactionSplit
+		if err := db.Where("transaction_id IN (?)", ids).Order("id").Find(&splits).Error; err != nil {
+			return nil, err
+		}
+		for _, split := range splits {
+			result[split.TransactionID] = append(result[split.TransactionID], split)
+		}
 	}
+	return result, nil
+}
 
-	// 验证日期范围
-	if endDate.Before(startDate) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "End date must be after start date"})
-		return
+// budgetEntries 载入日期范围内计入预算的支出（本位币）。categoryIDs 为 nil 时不限分类，拆分交易只保留
+// 分类匹配的拆分行；tagID 非 0 时只统计带该标签的支出。db 需限定在预算所属账本
+func budgetEntries(db *gorm.DB, cv *currencyConverter, categoryIDs []uint, tagID uint, startDate, endDate string) ([]statEntry, error) {
+	query := db.Where("type = ? AND date BETWEEN ? AND ?", "expense", startDate, endDate)
+	if categoryIDs != nil {
+		query = query.Where("category_id IN (?) OR id IN (SELECT transaction_id FROM transaction_splits WHERE category_id IN (?))", categoryIDs, categoryIDs)
 	}
-
-	// 验证金额
-	if input.Amount <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount must be greater than 0"})
-		return
+	if tagID != 0 {
+		query = query.Where("id IN (SELECT transaction_id FROM transaction_tags WHERE tag_id = ?)", tagID)
 	}
-
-	// 更新预算字段
-	budget.CategoryID = input.CategoryID
-	budget.Amount = input.Amount
-	budget.StartDate = input.StartDate
-	budget.EndDate = input.EndDate
-
-	if err := h.DB.Save(&budget).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	entries, err := loadStatEntries(query, cv)
+	if err != nil || categoryIDs == nil {
+		return entries, err
 	}
 
-	// 加载分类信息
-	h.DB.Model(&budget).Related(&budget.Category)
-
-	c.JSON(http.StatusOK, budget)
+	scope := make(map[uint]bool, len(categoryIDs))
+	for _, id := range categoryIDs {
+		scope[id] = true
+	}
+	var result []statEntry
+	for _, entry := range entries {
+		if scope[entry.CategoryID] {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
 }
 
-// DeleteBudget 删除预算
-func (h *StatisticsHandler) DeleteBudget(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+// budgetExpense 计算日期范围内计入预算的实际支出（本位币），条件同 budgetEntries
+func budgetExpense(db *gorm.DB, cv *currencyConverter, categoryIDs []uint, tagID uint, startDate, endDate string) (models.Money, error) {
+	entries, err := budgetEntries(db, cv, categoryIDs, tagID, startDate, endDate)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget ID"})
-		return
+		return 0, err
 	}
 
-	var budget models.Budget
-	if err := h.DB.First(&budget, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
-		return
-	}
-
-	// 删除预算
-	if err := h.DB.Delete(&budget).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	var total models.Money
+	for _, entry := range entries {
+		total += entry.Amount
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Budget deleted successfully"})
+	return total, nil
 }
 
-// GetBudgetOverview 获取预算概览
+// GetBudgetOverview 获取预算概览，rollup=true 时预算的实际支出包含下级分类。
+// 一次性预算统计本月的支出，周期预算统计今天所在周期的支出，可用金额包含之前各期的结转
 func (h *StatisticsHandler) GetBudgetOverview(c *gin.Context) {
 	currentDate := time.Now()
 	startOfMonth := time.Date(currentDate.Year(), currentDate.Month(), 1, 0, 0, 0, 0, currentDate.Location())
 	endOfMonth := startOfMonth.AddDate(0, 1, -1)
+	monthStart := startOfMonth.Format("2006-01-02")
+	monthEnd := endOfMonth.Format("2006-01-02")
 
 	type BudgetOverview struct {
 		models.Budget
-		ActualExpense   float64 `json:"actual_expense"`
-		Remaining       float64 `json:"remaining"`
-		PercentageUsed  float64 `json:"percentage_used"`
+		PeriodStart     string       `json:"period_start"`
+		PeriodEnd       string       `json:"period_end"`
+		CarriedOver     models.Money `json:"carried_over"`
+		Available       models.Money `json:"available"`
+		ActualExpense   models.Money `json:"actual_expense"`
+		Remaining       models.Money `json:"remaining"`
+		PercentageUsed  float64      `json:"percentage_used"`
 	}
 
-	var budgets []BudgetOverview
-
-	rows, err := h.DB.Table("budgets").
-		Select("budgets.*, categories.name as category_name, " +
-			"COALESCE((SELECT SUM(amount) FROM transactions " +
-			"WHERE category_id = budgets.category_id " +
-			"AND type = 'expense' " +
-			"AND created_at BETWEEN ? AND ?), 0) as actual_expense", startOfMonth, endOfMonth).
-		Joins("JOIN categories ON budgets.category_id = categories.id").
-		Where("budgets.start_date <= ? AND budgets.end_date >= ?", endOfMonth, startOfMonth).
-		Rows()
+	var budgets []models.Budget
+	if err := h.DB.Scopes(ledgerScope(c), plainBudgets).Preload("Category").Preload("Tag").
+		Where("start_date <= ? AND (end_date >= ? OR end_date = '')", monthEnd, monthStart).
+		Find(&budgets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
+	cv, err := newCurrencyConverter(h.DB, middleware.CurrentLedgerID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var budget BudgetOverview
-		if err := h.DB.ScanRows(rows, &budget); err != nil {
-			continue
+	today := currentDate.Format("2006-01-02")
+	var overviews []BudgetOverview
+	for _, budget := range budgets {
+		categoryIDs, err := budgetCategories(h.DB.Scopes(ledgerScope(c)), budget, c.Query("rollup") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
-		
-		budget.Remaining = budget.Amount - budget.ActualExpense
-		if budget.Amount > 0 {
-			budget.PercentageUsed = (budget.ActualExpense / budget.Amount) * 100
+
+		// 周期预算统计今天所在的周期（含之前各期的结转），一次性预算统计本月
+		status := budgetPeriodStatus{StartDate: monthStart, EndDate: monthEnd, Planned: budget.Amount}
+		if budget.Period != "" {
+			n := budgetPeriodIndex(budget, today)
+			if _, _, ok := budgetPeriod(budget, n); !ok {
+				continue
+			}
+			statuses, err := budgetPeriodStatuses(h.DB.Scopes(ledgerScope(c)), cv, categoryIDs, budget, n)
+			if err != nil {
+				c.JSON(conversionStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+			status = statuses[n]
+		} else {
+			if status.ActualExpense, err = budgetExpense(h.DB.Scopes(ledgerScope(c)), cv, categoryIDs, budget.TagID, monthStart, monthEnd); err != nil {
+				c.JSON(conversionStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+			status.settle(0)
+		}
+
+		overview := BudgetOverview{
+			Budget:         budget,
+			PeriodStart:    status.StartDate,
+			PeriodEnd:      status.EndDate,
+			CarriedOver:    status.CarriedOver,
+			Available:      status.Available,
+			ActualExpense:  status.ActualExpense,
+			Remaining:      status.Remaining,
+			PercentageUsed: status.PercentageUsed,
 		}
-		budgets = append(budgets, budget)
+		overviews = append(overviews, overview)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"current_month": gin.H{
-			"start_date": startOfMonth.Format("2006-01-02"),
-			"end_date":   endOfMonth.Format("2006-01-02"),
+			"start_date": monthStart,
+			"end_date":   monthEnd,
 		},
-		"budgets": budgets,
+		"base_currency": cv.base,
+		"budgets":       overviews,
 	})
 }
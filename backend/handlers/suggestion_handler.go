@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance/classifier"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// SuggestionHandler 根据账本的历史交易推荐分类。每个账本的模型常驻内存，
+// 每次请求前只同步上次之后新增、修改或删除的交易
+type SuggestionHandler struct {
+	DB *gorm.DB
+
+	mu     sync.Mutex
+	models map[uint]*suggestionModel
+}
+
+const maxSuggestions = 5
+
+// suggestionModel 一个账本的分类模型及其同步进度
+type suggestionModel struct {
+	nb       *classifier.NaiveBayes
+	syncedAt time.Time
+}
+
+// categorySuggestion 推荐的分类
+type categorySuggestion struct {
+	CategoryID   uint    `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	Confidence   float64 `json:"confidence"`
+}
+
+// SuggestCategory 为草稿交易（描述、金额、账户，可选类型）推荐分类，按置信度从高到低返回
+func (h *SuggestionHandler) SuggestCategory(c *gin.Context) {
+	var input struct {
+		Description string       `json:"description"`
+		Amount      models.Money `json:"amount"`
+		AccountID   uint         `json:"account_id"`
+		Type        string       `json:"type"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Type != "" && input.Type != "income" && input.Type != "expense" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Type must be 'income' or 'expense'"})
+		return
+	}
+
+	var categories []models.Category
+	if err := h.DB.Scopes(ledgerScope(c)).Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	names := make(map[uint]string)
+	allowed := make(map[uint]bool)
+	for _, category := range categories {
+		names[category.ID] = category.Name
+		if input.Type == "" || category.Type == input.Type {
+			allowed[category.ID] = true
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	model, err := h.model(middleware.CurrentLedgerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	features := classifier.Features(input.Description, int64(input.Amount), input.AccountID)
+	suggestions := []categorySuggestion{}
+	for _, prediction := range model.nb.Predict(features, allowed) {
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+		suggestions = append(suggestions, categorySuggestion{
+			CategoryID:   prediction.Label,
+			CategoryName: names[prediction.Label],
+			Confidence:   prediction.Confidence,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions": suggestions,
+		"trained_on":  model.nb.Len(),
+	})
+}
+
+// GetSuggestionMetrics 用留一法评估当前模型的准确率，候选分类限定为与实际分类同类型的分类
+func (h *SuggestionHandler) GetSuggestionMetrics(c *gin.Context) {
+	var categories []models.Category
+	if err := h.DB.Scopes(ledgerScope(c)).Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byType := make(map[string]map[uint]bool)
+	types := make(map[uint]string)
+	for _, category := range categories {
+		if byType[category.Type] == nil {
+			byType[category.Type] = make(map[uint]bool)
+		}
+		byType[category.Type][category.ID] = true
+		types[category.ID] = category.Type
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	model, err := h.model(middleware.CurrentLedgerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.nb.Evaluate(func(label uint) map[uint]bool {
+		return byType[types[label]]
+	}))
+}
+
+// model 返回账本的模型，首次使用时用全部历史交易训练，之后增量同步。调用方需持有 h.mu
+func (h *SuggestionHandler) model(ledgerID uint) (*suggestionModel, error) {
+	if h.models == nil {
+		h.models = make(map[uint]*suggestionModel)
+	}
+	model, ok := h.models[ledgerID]
+	if !ok {
+		model = &suggestionModel{nb: classifier.New()}
+		h.models[ledgerID] = model
+	}
+	if err := model.sync(h.DB, ledgerID); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// sync 训练上次同步之后新增或修改的交易，并移除已删除的交易。已归类的收支交易作为样本，
+// 改为转账或取消分类的交易从模型中移除
+func (m *suggestionModel) sync(db *gorm.DB, ledgerID uint) error {
+	now := time.Now()
+	query := db.Model(&models.Transaction{}).Where("ledger_id = ?", ledgerID)
+	if !m.syncedAt.IsZero() {
+		// 留出一秒余量，重复训练同一笔交易不影响结果
+		query = query.Where("updated_at >= ?", m.syncedAt.Add(-time.Second))
+	}
+	rows, err := query.Select("id, type, COALESCE(category_id, 0), COALESCE(description, ''), amount, account_id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, categoryID, accountID uint
+		var transactionType, description string
+		var amount models.Money
+		if err := rows.Scan(&id, &transactionType, &categoryID, &description, &amount, &accountID); err != nil {
+			return err
+		}
+		if categoryID == 0 || (transactionType != "income" && transactionType != "expense") {
+			m.nb.Forget(id)
+			continue
+		}
+		m.nb.Train(id, categoryID, classifier.Features(description, int64(amount), accountID))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// 样本数与数据库不一致说明有交易被删除
+	var count int
+	if err := db.Model(&models.Transaction{}).
+		Where("ledger_id = ? AND type IN (?) AND category_id > 0", ledgerID, []string{"income", "expense"}).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count != m.nb.Len() {
+		var ids []uint
+		if err := db.Model(&models.Transaction{}).Where("ledger_id = ?", ledgerID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		existing := make(map[uint]bool, len(ids))
+		for _, id := range ids {
+			existing[id] = true
+		}
+		for _, id := range m.nb.IDs() {
+			if !existing[id] {
+				m.nb.Forget(id)
+			}
+		}
+	}
+
+	m.syncedAt = now
+	return nil
+}
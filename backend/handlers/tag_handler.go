@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type TagHandler struct {
+	DB *gorm.DB
+}
+
+// tagUsage 标签及其使用次数
+type tagUsage struct {
+	models.Tag
+	TransactionCount int `json:"transaction_count"`
+}
+
+// CreateTag 创建标签，标签名在账本内唯一
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	var tag models.Tag
+	if err := c.ShouldBindJSON(&tag); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag.LedgerID = middleware.CurrentLedgerID(c)
+	if err := validateTag(h.DB, &tag); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.DB.Create(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// GetTags 获取所有标签及其使用次数，按名称排序
+func (h *TagHandler) GetTags(c *gin.Context) {
+	var tags []models.Tag
+	if err := h.DB.Scopes(ledgerScope(c)).Order("name").Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	counts := make(map[uint]int)
+	rows, err := h.DB.Table("transaction_tags").
+		Select("tag_id, COUNT(*)").
+		Where("tag_id IN (SELECT id FROM tags WHERE ledger_id = ?)", middleware.CurrentLedgerID(c)).
+		Group("tag_id").
+		Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tagID uint
+		var count int
+		if err := rows.Scan(&tagID, &count); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		counts[tagID] = count
+	}
+
+	result := make([]tagUsage, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, tagUsage{Tag: tag, TransactionCount: counts[tag.ID]})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// UpdateTag 重命名标签
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	id, ok := pathID(c, "id", "tag")
+	if !ok {
+		return
+	}
+	var tag models.Tag
+
+	if err := h.DB.Scopes(ledgerScope(c)).First(&tag, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	var input models.Tag
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag.Name = input.Name
+	if err := validateTag(h.DB, &tag); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.DB.Save(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag 删除标签并从所有交易上移除；仍有预算使用该标签时不能删除
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	id, ok := pathID(c, "id", "tag")
+	if !ok {
+		return
+	}
+	var tag models.Tag
+
+	if err := h.DB.Scopes(ledgerScope(c)).First(&tag, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	var budgetCount int64
+	h.DB.Model(&models.Budget{}).Where("tag_id = ?", tag.ID).Count(&budgetCount)
+	if budgetCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete tag with associated budgets"})
+		return
+	}
+
+	tx := h.DB.Begin()
+	if err := tx.Exec("DELETE FROM transaction_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Delete(&tag).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
+
+// validateTag 校验标签名非空且在账本内唯一
+func validateTag(db *gorm.DB, tag *models.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		return &requestError{http.StatusBadRequest, "Name is required"}
+	}
+
+	var count int64
+	db.Model(&models.Tag{}).Where("ledger_id = ? AND name = ? AND id <> ?", tag.LedgerID, tag.Name, tag.ID).Count(&count)
+	if count > 0 {
+		return &requestError{http.StatusConflict, "Tag already exists"}
+	}
+	return nil
+}
+
+// findOrCreateTags 按名称查找账本中的标签，不存在的自动创建；名称去除首尾空白后去重
+func findOrCreateTags(tx *gorm.DB, ledgerID uint, names []string) ([]models.Tag, error) {
+	var tags []models.Tag
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var tag models.Tag
+		if err := tx.Where(models.Tag{LedgerID: ledgerID, Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// tagTransaction 为已保存的交易追加标签，已有的标签不会重复添加
+func tagTransaction(tx *gorm.DB, transaction *models.Transaction, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	tags, err := findOrCreateTags(tx, transaction.LedgerID, names)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(transaction).Association("Tags").Append(tags).Error; err != nil {
+		return err
+	}
+	return tx.Model(transaction).Association("Tags").Find(&transaction.Tags).Error
+}
+
+// replaceTransactionTags 将已保存交易的标签替换为 TagIDs 指定的标签，
+// 标签须已由 validateTransaction 校验属于交易所在的账本
+func replaceTransactionTags(tx *gorm.DB, transaction *models.Transaction) error {
+	if len(transaction.TagIDs) == 0 {
+		transaction.Tags = nil
+		return tx.Model(transaction).Association("Tags").Clear().Error
+	}
+
+	var tags []models.Tag
+	if err := tx.Where("id IN (?)", transaction.TagIDs).Find(&tags).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(transaction).Association("Tags").Replace(tags).Error; err != nil {
+		return err
+	}
+	transaction.Tags = tags
+	return nil
+}
@@ -1,15 +1,21 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"personal-finance/middleware"
 	"personal-finance/models"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 )
 
 type TransactionHandler struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Alerts *BudgetAlerter // 为空时不检查预算提醒
 }
 
 // CreateTransaction 创建新交易
@@ -20,48 +26,100 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 		return
 	}
 
+	h.create(c, &transaction)
+}
+
+// CreateTransfer 在两个账户之间转账：从转出账户扣款并记入转入账户
+func (h *TransactionHandler) CreateTransfer(c *gin.Context) {
+	var input struct {
+		FromAccountID uint         `json:"from_account_id" binding:"required"`
+		ToAccountID   uint         `json:"to_account_id" binding:"required"`
+		Amount        models.Money `json:"amount" binding:"required"`
+		ToAmount      models.Money `json:"to_amount"` // 跨币种转账时转入账户收到的金额，可选
+		Date          string       `json:"date"`
+		Description   string       `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transaction := models.Transaction{
+		AccountID:   input.FromAccountID,
+		ToAccountID: input.ToAccountID,
+		Amount:      input.Amount,
+		ToAmount:    input.ToAmount,
+		Type:        "transfer",
+		Date:        input.Date,
+		Description: input.Description,
+	}
+	h.create(c, &transaction)
+}
+
+// create 校验并保存交易，在同一个数据库事务中更新账户余额
+func (h *TransactionHandler) create(c *gin.Context, transaction *models.Transaction) {
+	// 校验业务日期，未提供时默认为今天
+	date, err := normalizeDate(transaction.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
+	transaction.Date = date
+	transaction.LedgerID = middleware.CurrentLedgerID(c)
+	transaction.CreatedBy = middleware.CurrentUserID(c)
+
+	// 按规则补全分类、改写描述并追加标签，手动选择的分类优先
+	rules, err := loadRuleSet(h.DB, transaction.LedgerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	result := rules.apply(*transaction)
+	if transaction.CategoryID == 0 {
+		transaction.CategoryID = result.CategoryID
+	}
+	if result.Description != "" {
+		transaction.Description = result.Description
+	}
+	transaction.Tags = nil
+
 	// 开始事务
 	tx := h.DB.Begin()
 
-	// 查找相关账户
-	var account models.Account
-	if err := tx.First(&account, transaction.AccountID).Error; err != nil {
+	// 检查账户、分类及交易类型
+	if err := validateTransaction(tx, transaction); err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		respondError(c, err)
 		return
 	}
 
-	// 检查分类是否存在
-	var category models.Category
-	if err := tx.First(&category, transaction.CategoryID).Error; err != nil {
+	// 更新账户余额
+	account, err := applyTransaction(tx, *transaction, 1)
+	if err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 检查分类类型是否与交易类型匹配
-	if category.Type != transaction.Type {
+	// 创建交易记录，先关联手动选择的标签，再追加规则的标签
+	if err := tx.Create(transaction).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Category type does not match transaction type"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-
-	// 更新账户余额
-	if transaction.Type == "expense" {
-		account.Balance -= transaction.Amount
-	} else if transaction.Type == "income" {
-		account.Balance += transaction.Amount
+	if len(transaction.TagIDs) > 0 {
+		if err := replaceTransactionTags(tx, transaction); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 	}
-
-	// 保存更新后的账户信息
-	if err := tx.Save(&account).Error; err != nil {
+	if err := tagTransaction(tx, transaction, result.Tags); err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-
-	// 创建交易记录
-	if err := tx.Create(&transaction).Error; err != nil {
+	if err := saveSplits(tx, transaction); err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -69,6 +127,9 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 
 	// 提交事务
 	tx.Commit()
+	if transaction.Type == "expense" {
+		h.Alerts.Check(transaction.LedgerID, transaction.Date)
+	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"transaction": transaction,
@@ -76,15 +137,88 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 	})
 }
 
-// GetTransactions 获取交易记录
+// GetTransactions 分页获取交易记录，支持筛选和排序
 func (h *TransactionHandler) GetTransactions(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page"})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("page_size must be between 1 and %d", maxPageSize)})
+		return
+	}
+
+	query, err := filterTransactions(c, h.DB.Model(&models.Transaction{}).Scopes(ledgerScope(c)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := transactionOrder(c.DefaultQuery("sort_by", "date"), c.DefaultQuery("order", "desc"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 统计筛选后的总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	var transactions []models.Transaction
-	
-	query := h.DB.Preload("Account").Preload("Category").Order("created_at desc")
-	
-	// 支持按账户ID筛选
+	if err := query.Preload("Account").Preload("Category").Preload("Tags").Preload("Splits", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Splits.Category").
+		Order(order).
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&transactions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": transactions,
+		"total":        total,
+		"page":         page,
+		"page_size":    pageSize,
+	})
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 200
+)
+
+// transactionSortColumns 允许排序的字段
+var transactionSortColumns = map[string]string{
+	"date":       "date",
+	"amount":     "amount",
+	"created_at": "created_at",
+}
+
+// transactionOrder 生成排序子句，同值时按 id 保持稳定的分页顺序
+func transactionOrder(sortBy, order string) (string, error) {
+	column, ok := transactionSortColumns[sortBy]
+	if !ok {
+		return "", fmt.Errorf("sort_by must be one of date, amount, created_at")
+	}
+	order = strings.ToLower(order)
+	if order != "asc" && order != "desc" {
+		return "", fmt.Errorf("order must be asc or desc")
+	}
+	return fmt.Sprintf("%s %s, id %s", column, order, order), nil
+}
+
+// filterTransactions 根据查询参数为交易查询添加筛选条件
+func filterTransactions(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	// 支持按账户ID筛选，转账会同时出现在转出和转入账户中
 	if accountID := c.Query("account_id"); accountID != "" {
-		query = query.Where("account_id = ?", accountID)
+		query = query.Where("account_id = ? OR to_account_id = ?", accountID, accountID)
 	}
 
 	// 支持按类型筛选
@@ -92,10 +226,471 @@ func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 		query = query.Where("type = ?", transType)
 	}
 
-	if err := query.Find(&transactions).Error; err != nil {
+	// 支持按日期范围筛选
+	if startDate := c.Query("start_date"); startDate != "" {
+		if _, err := time.Parse("2006-01-02", startDate); err != nil {
+			return nil, fmt.Errorf("invalid start_date format. Use YYYY-MM-DD")
+		}
+		query = query.Where("date >= ?", startDate)
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		if _, err := time.Parse("2006-01-02", endDate); err != nil {
+			return nil, fmt.Errorf("invalid end_date format. Use YYYY-MM-DD")
+		}
+		query = query.Where("date <= ?", endDate)
+	}
+
+	// 支持按多个分类筛选：category_id=1,2 或 category_id=1&category_id=2，拆分交易的任一拆分行匹配即可
+	categoryIDs, err := queryIDs(c, "category_id")
+	if err != nil {
+		return nil, err
+	}
+	if len(categoryIDs) > 0 {
+		query = query.Where("category_id IN (?) OR id IN (SELECT transaction_id FROM transaction_splits WHERE category_id IN (?))", categoryIDs, categoryIDs)
+	}
+
+	// 支持按多个标签筛选，带有其中任一标签的交易都会返回
+	tagIDs, err := queryIDs(c, "tag_id")
+	if err != nil {
+		return nil, err
+	}
+	if len(tagIDs) > 0 {
+		query = query.Where("id IN (SELECT transaction_id FROM transaction_tags WHERE tag_id IN (?))", tagIDs)
+	}
+
+	// 支持按金额范围筛选
+	if minAmount := c.Query("min_amount"); minAmount != "" {
+		amount, err := models.ParseMoney(minAmount)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("amount >= ?", amount)
+	}
+	if maxAmount := c.Query("max_amount"); maxAmount != "" {
+		amount, err := models.ParseMoney(maxAmount)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("amount <= ?", amount)
+	}
+
+	// 支持按描述模糊搜索
+	if description := c.Query("description"); description != "" {
+		query = query.Where("description LIKE ? ESCAPE '\\'", "%"+likeEscaper.Replace(description)+"%")
+	}
+
+	return query, nil
+}
+
+// queryIDs 解析可重复、可逗号分隔的编号查询参数，如 tag_id=1,2 或 tag_id=1&tag_id=2
+func queryIDs(c *gin.Context, name string) ([]uint64, error) {
+	var ids []uint64
+	for _, value := range c.QueryArray(name) {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			id, err := strconv.ParseUint(part, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", name, part)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// likeEscaper 转义 LIKE 模式中的通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// UpdateTransaction 更新交易记录，并同步调整相关账户余额
+func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
+	id, ok := pathID(c, "id", "transaction")
+	if !ok {
+		return
+	}
+
+	var input models.Transaction
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 开始事务
+	tx := h.DB.Begin()
+
+	var transaction models.Transaction
+	if err := tx.Scopes(ledgerScope(c)).First(&transaction, id).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+
+	// 未提供业务日期时保留原日期
+	if input.Date == "" {
+		input.Date = transaction.Date
+	}
+	date, err := normalizeDate(input.Date)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	// 更新字段
+	updated := transaction
+	updated.AccountID = input.AccountID
+	updated.ToAccountID = input.ToAccountID
+	updated.Amount = input.Amount
+	updated.ToAmount = input.ToAmount
+	updated.Type = input.Type
+	updated.CategoryID = input.CategoryID
+	updated.Description = input.Description
+	updated.Date = date
+	updated.TagIDs = input.TagIDs
+
+	// 未提供 splits 时保留原有拆分行，新的金额仍须与其合计一致；空数组取消拆分
+	updated.Splits = input.Splits
+	if input.Splits == nil {
+		if err := tx.Where("transaction_id = ?", transaction.ID).Order("id").Find(&updated.Splits).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 检查新的账户、分类及交易类型
+	if err := validateTransaction(tx, &updated); err != nil {
+		tx.Rollback()
+		respondError(c, err)
+		return
+	}
+
+	// 已对账锁定的交易不能修改账户、金额、类型或日期；进行中对账里的勾选随之取消
+	if affectsBalance(transaction, updated) {
+		locked, err := transactionLocked(tx, transaction.ID)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if locked {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": "Transaction is reconciled and locked"})
+			return
+		}
+		if err := tx.Where("transaction_id = ?", transaction.ID).Delete(&models.ClearedTransaction{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 撤销原交易对余额的影响，再应用新交易（账户、类型或分类都可能已变更）
+	if _, err := applyTransaction(tx, transaction, -1); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	account, err := applyTransaction(tx, updated, 1)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Save(&updated).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 提供 tag_ids 时替换标签（空数组清除所有标签），否则保留原有标签
+	if input.TagIDs != nil {
+		if err := replaceTransactionTags(tx, &updated); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	} else if err := tx.Model(&updated).Association("Tags").Find(&updated.Tags).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Splits != nil {
+		if err := tx.Where("transaction_id = ?", updated.ID).Delete(&models.TransactionSplit{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := saveSplits(tx, &updated); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 提交事务
+	tx.Commit()
+	if updated.Type == "expense" {
+		h.Alerts.Check(updated.LedgerID, updated.Date)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transaction": updated,
+		"new_balance": account.Balance,
+	})
+}
+
+// DeleteTransaction 删除交易记录，并撤销其对账户余额的影响
+func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
+	id, ok := pathID(c, "id", "transaction")
+	if !ok {
+		return
+	}
+
+	// 开始事务
+	tx := h.DB.Begin()
+
+	var transaction models.Transaction
+	if err := tx.Scopes(ledgerScope(c)).First(&transaction, id).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+
+	// 已对账锁定的交易不能删除
+	locked, err := transactionLocked(tx, transaction.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if locked {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Transaction is reconciled and locked"})
+		return
+	}
+
+	account, err := applyTransaction(tx, transaction, -1)
+	if err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, transactions)
+	if err := tx.Model(&transaction).Association("Tags").Clear().Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Where("transaction_id = ?", transaction.ID).Delete(&models.TransactionSplit{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Where("transaction_id = ?", transaction.ID).Delete(&models.ClearedTransaction{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Delete(&transaction).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 提交事务
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Transaction deleted successfully",
+		"new_balance": account.Balance,
+	})
+}
+
+// validateTransaction 在事务 tx 中检查交易引用的账户、分类和标签，它们都必须属于交易所在的账本。
+// 收支交易必须有与类型匹配的分类，拆分交易的每一行都是如此；转账必须指向另一个账户且不带分类。
+func validateTransaction(tx *gorm.DB, transaction *models.Transaction) error {
+	if transaction.Amount <= 0 {
+		return &requestError{http.StatusBadRequest, "Amount must be greater than 0"}
+	}
+
+	var account models.Account
+	owned := tx.Where("ledger_id = ?", transaction.LedgerID)
+	if err := owned.First(&account, transaction.AccountID).Error; err != nil {
+		return &requestError{http.StatusNotFound, "Account not found"}
+	}
+	// 交易币种始终与账户一致
+	transaction.Currency = account.Currency
+
+	switch transaction.Type {
+	case "transfer":
+		if len(transaction.Splits) > 0 {
+			return &requestError{http.StatusBadRequest, "Transfers cannot be split"}
+		}
+		if transaction.ToAccountID == transaction.AccountID {
+			return &requestError{http.StatusBadRequest, "Cannot transfer to the same account"}
+		}
+		var toAccount models.Account
+		if err := owned.First(&toAccount, transaction.ToAccountID).Error; err != nil {
+			return &requestError{http.StatusNotFound, "Target account not found"}
+		}
+		transaction.CategoryID = 0
+
+		// 同币种转账的转入金额等于转出金额；跨币种未指定时按当日汇率换算
+		if toAccount.Currency == account.Currency {
+			transaction.ToAmount = transaction.Amount
+		} else if transaction.ToAmount <= 0 {
+			cv, err := newCurrencyConverter(tx, transaction.LedgerID)
+			if err != nil {
+				return err
+			}
+			if transaction.ToAmount, err = cv.convert(transaction.Amount, account.Currency, toAccount.Currency, transaction.Date); err != nil {
+				return &requestError{http.StatusBadRequest, err.Error()}
+			}
+		}
+	case "income", "expense":
+		if len(transaction.Splits) > 0 {
+			if err := validateSplits(owned, transaction); err != nil {
+				return err
+			}
+		}
+		var category models.Category
+		if err := owned.First(&category, transaction.CategoryID).Error; err != nil {
+			return &requestError{http.StatusNotFound, "Category not found"}
+		}
+		if category.Type != transaction.Type {
+			return &requestError{http.StatusBadRequest, "Category type does not match transaction type"}
+		}
+		transaction.ToAccountID = 0
+		transaction.ToAmount = 0
+	default:
+		return &requestError{http.StatusBadRequest, "Transaction type must be 'income', 'expense' or 'transfer'"}
+	}
+
+	if len(transaction.TagIDs) > 0 {
+		var count int
+		if err := tx.Model(&models.Tag{}).Where("ledger_id = ? AND id IN (?)", transaction.LedgerID, transaction.TagIDs).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != len(uniqueIDs(transaction.TagIDs)) {
+			return &requestError{http.StatusNotFound, "Tag not found"}
+		}
+	}
+	return nil
+}
+
+// validateSplits 检查拆分行的分类和金额，各行金额之和必须等于交易金额。
+// 交易本身的分类取第一条拆分行的分类
+func validateSplits(owned *gorm.DB, transaction *models.Transaction) error {
+	var total models.Money
+	for _, split := range transaction.Splits {
+		if split.Amount <= 0 {
+			return &requestError{http.StatusBadRequest, "Split amount must be greater than 0"}
+		}
+		var category models.Category
+		if err := owned.First(&category, split.CategoryID).Error; err != nil {
+			return &requestError{http.StatusNotFound, "Category not found"}
+		}
+		if category.Type != transaction.Type {
+			return &requestError{http.StatusBadRequest, "Category type does not match transaction type"}
+		}
+		total += split.Amount
+	}
+	if total != transaction.Amount {
+		return &requestError{http.StatusBadRequest, fmt.Sprintf("Split amounts add up to %s, expected %s", total, transaction.Amount)}
+	}
+	transaction.CategoryID = transaction.Splits[0].CategoryID
+	return nil
+}
+
+// saveSplits 在事务 tx 中保存已创建交易的拆分行
+func saveSplits(tx *gorm.DB, transaction *models.Transaction) error {
+	for i := range transaction.Splits {
+		split := &transaction.Splits[i]
+		split.ID = 0
+		split.TransactionID = transaction.ID
+		split.Category = nil
+		if err := tx.Create(split).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uniqueIDs 返回去重后的编号
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool)
+	var result []uint
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// balanceDelta 返回交易对其账户（转账时为转出账户）余额的影响：收入为正，支出和转账为负
+func balanceDelta(transaction models.Transaction) models.Money {
+	switch transaction.Type {
+	case "income":
+		return transaction.Amount
+	case "expense", "transfer":
+		return -transaction.Amount
+	}
+	return 0
+}
+
+// accountDelta 返回交易对指定账户余额的影响，转账对转入账户记入 ToAmount
+func accountDelta(transaction models.Transaction, accountID uint) models.Money {
+	switch accountID {
+	case transaction.AccountID:
+		return balanceDelta(transaction)
+	case transaction.ToAccountID:
+		if transaction.Type == "transfer" {
+			return transaction.ToAmount
+		}
+	}
+	return 0
+}
+
+// applyTransaction 在事务 tx 中按 sign（1 为记入，-1 为撤销）将交易的影响
+// 应用到相关账户余额，并删除交易日期及之后已失效的余额快照，返回交易所属账户更新后的状态
+func applyTransaction(tx *gorm.DB, transaction models.Transaction, sign models.Money) (models.Account, error) {
+	account, err := adjustBalance(tx, transaction.AccountID, sign*balanceDelta(transaction))
+	if err != nil {
+		return account, err
+	}
+	if transaction.Type == "transfer" {
+		if _, err := adjustBalance(tx, transaction.ToAccountID, sign*transaction.ToAmount); err != nil {
+			return account, err
+		}
+	}
+	return account, invalidateSnapshots(tx, transaction.Date, transaction.AccountID, transaction.ToAccountID)
+}
+
+// adjustBalance 在事务 tx 中将账户余额调整 delta，并返回更新后的账户
+func adjustBalance(tx *gorm.DB, accountID uint, delta models.Money) (models.Account, error) {
+	var account models.Account
+	if err := tx.First(&account, accountID).Error; err != nil {
+		return account, err
+	}
+	account.Balance += delta
+	if err := tx.Save(&account).Error; err != nil {
+		return account, err
+	}
+	return account, nil
+}
+
+// normalizeDate 校验 YYYY-MM-DD 格式的日期，空值返回今天
+func normalizeDate(date string) (string, error) {
+	if date == "" {
+		return time.Now().Format("2006-01-02"), nil
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return "", err
+	}
+	return date, nil
 }
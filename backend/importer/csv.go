@@ -0,0 +1,256 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"personal-finance/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// 未指定日期格式时依次尝试的常见格式
+var defaultDateFormats = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"2006.01.02",
+	"20060102",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"2006/1/2",
+	"2006-1-2",
+	"01/02/2006",
+}
+
+// ValidateProfile 检查列映射配置是否完整
+func ValidateProfile(profile models.ImportProfile) error {
+	if _, err := decoderFor(profile.Encoding); err != nil {
+		return err
+	}
+	if len([]rune(profile.Delimiter)) > 1 {
+		return fmt.Errorf("delimiter must be a single character")
+	}
+	if profile.SkipRows < 0 {
+		return fmt.Errorf("skip_rows cannot be negative")
+	}
+	if profile.DateColumn == "" {
+		return fmt.Errorf("date_column is required")
+	}
+	switch profile.SignConvention {
+	case "", "signed", "inverted":
+		if profile.AmountColumn == "" {
+			return fmt.Errorf("amount_column is required")
+		}
+	case "debit_credit":
+		if profile.DebitColumn == "" || profile.CreditColumn == "" {
+			return fmt.Errorf("debit_column and credit_column are required for debit_credit")
+		}
+	default:
+		return fmt.Errorf("sign_convention must be signed, inverted or debit_credit")
+	}
+	return nil
+}
+
+// ParseCSV 按配置解析 CSV 对账单。单行的格式问题记录在 Row.Errors 中，
+// 只有文件本身无法读取时才返回 error。
+func ParseCSV(r io.Reader, profile models.ImportProfile) ([]Row, error) {
+	if err := ValidateProfile(profile); err != nil {
+		return nil, err
+	}
+
+	decoder, _ := decoderFor(profile.Encoding)
+	reader := csv.NewReader(transform.NewReader(r, decoder))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if profile.Delimiter != "" {
+		reader.Comma = []rune(profile.Delimiter)[0]
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	line := profile.SkipRows
+	if line > len(records) {
+		line = len(records)
+	}
+	records = records[line:]
+
+	var header []string
+	if profile.HasHeader && len(records) > 0 {
+		header = records[0]
+		records = records[1:]
+		line++
+	}
+
+	columns := columnResolver{header: header}
+	dateCol := columns.index(profile.DateColumn)
+	amountCol := columns.index(profile.AmountColumn)
+	debitCol := columns.index(profile.DebitColumn)
+	creditCol := columns.index(profile.CreditColumn)
+	descCol := columns.index(profile.DescriptionColumn)
+	if columns.err != nil {
+		return nil, columns.err
+	}
+
+	var rows []Row
+	for _, record := range records {
+		line++
+		if isBlank(record) {
+			continue
+		}
+
+		row := Row{Line: line, Description: field(record, descCol)}
+
+		date, err := parseDate(field(record, dateCol), profile.DateFormat)
+		if err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		}
+		row.Date = date
+
+		var amount models.Money
+		if profile.SignConvention == "debit_credit" {
+			amount, err = debitCreditAmount(field(record, debitCol), field(record, creditCol))
+		} else {
+			amount, err = parseAmount(field(record, amountCol))
+			if profile.SignConvention == "inverted" {
+				amount = -amount
+			}
+		}
+		if err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		} else if amount == 0 {
+			row.Errors = append(row.Errors, "amount is zero")
+		}
+
+		row.setAmount(amount)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// decoderFor 返回文件编码对应的解码器，UTF-8 会去除 BOM
+func decoderFor(name string) (transform.Transformer, error) {
+	var enc encoding.Encoding
+	switch strings.ToLower(name) {
+	case "", "utf-8", "utf8":
+		enc = unicode.UTF8BOM
+	case "gbk":
+		enc = simplifiedchinese.GBK
+	case "gb18030":
+		enc = simplifiedchinese.GB18030
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", name)
+	}
+	return enc.NewDecoder(), nil
+}
+
+// columnResolver 将表头名称或从 1 开始的列序号解析为列下标
+type columnResolver struct {
+	header []string
+	err    error
+}
+
+func (c *columnResolver) index(ref string) int {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || c.err != nil {
+		return -1
+	}
+	for i, name := range c.header {
+		if strings.TrimSpace(name) == ref {
+			return i
+		}
+	}
+	if n, err := strconv.Atoi(ref); err == nil && n >= 1 {
+		return n - 1
+	}
+	c.err = fmt.Errorf("column %q not found", ref)
+	return -1
+}
+
+func field(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseDate 按指定格式（为空时按常见格式）解析日期，返回 YYYY-MM-DD
+func parseDate(value, layout string) (string, error) {
+	if value == "" {
+		return "", fmt.Errorf("date is empty")
+	}
+	layouts := defaultDateFormats
+	if layout != "" {
+		layouts = []string{layout}
+	}
+	for _, l := range layouts {
+		if t, err := time.Parse(l, value); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q", value)
+}
+
+// parseAmount 解析银行导出的金额，容忍货币符号、千分位和括号表示的负数
+func parseAmount(value string) (models.Money, error) {
+	cleaned := strings.NewReplacer(",", "", "¥", "", "￥", "", "$", "", " ", "", " ", "").Replace(value)
+	negative := false
+	if strings.HasPrefix(cleaned, "(") && strings.HasSuffix(cleaned, ")") {
+		negative = true
+		cleaned = cleaned[1 : len(cleaned)-1]
+	}
+	if cleaned == "" {
+		return 0, fmt.Errorf("amount is empty")
+	}
+	amount, err := models.ParseMoney(cleaned)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// debitCreditAmount 合并支出列和收入列，支出为负
+func debitCreditAmount(debit, credit string) (models.Money, error) {
+	var amount models.Money
+	if debit != "" {
+		value, err := parseAmount(debit)
+		if err != nil {
+			return 0, err
+		}
+		if value < 0 {
+			value = -value
+		}
+		amount -= value
+	}
+	if credit != "" {
+		value, err := parseAmount(credit)
+		if err != nil {
+			return 0, err
+		}
+		if value < 0 {
+			value = -value
+		}
+		amount += value
+	}
+	return amount, nil
+}
@@ -0,0 +1,33 @@
+// Package importer 将银行导出的对账单文件解析为待导入的交易行
+package importer
+
+import (
+	"personal-finance/models"
+)
+
+// Row 对账单中的一条记录，Errors 非空时该行不能导入
+type Row struct {
+	Line        int          `json:"line"`
+	Date        string       `json:"date"`
+	Amount      models.Money `json:"amount"`
+	Type        string       `json:"type"` // income 或 expense
+	Description string       `json:"description"`
+	ExternalID  string       `json:"external_id,omitempty"` // 银行提供的唯一编号，如 OFX 的 FITID
+	Duplicate   bool         `json:"duplicate,omitempty"`   // 已导入过，确认导入时跳过
+	Errors      []string     `json:"errors,omitempty"`
+}
+
+// setAmount 按金额符号设置交易类型：正数为收入，负数为支出
+func (r *Row) setAmount(amount models.Money) {
+	r.Type = "income"
+	r.Amount = amount
+	if amount < 0 {
+		r.Type = "expense"
+		r.Amount = -amount
+	}
+}
+
+// Valid 返回该行是否可以导入
+func (r Row) Valid() bool {
+	return len(r.Errors) == 0
+}
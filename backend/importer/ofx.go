@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseOFX 解析 OFX/QFX 对账单中的交易（STMTTRN），同时支持 SGML 格式的 OFX 1.x
+// 和 XML 格式的 OFX 2.x。FITID 作为外部编号返回，用于识别重复导入。
+func ParseOFX(r io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	content := string(data)
+	if !strings.Contains(strings.ToUpper(content), "<OFX>") {
+		return nil, fmt.Errorf("not an OFX file")
+	}
+
+	var rows []Row
+	var current map[string]string
+	line := 1
+	for _, tok := range tokenizeOFX(content) {
+		line += tok.newlines
+		switch {
+		case tok.name == "STMTTRN":
+			current = map[string]string{"_line": fmt.Sprint(line)}
+		case tok.name == "/STMTTRN" && current != nil:
+			rows = append(rows, ofxRow(current))
+			current = nil
+		case current != nil && !strings.HasPrefix(tok.name, "/") && tok.value != "":
+			current[tok.name] = tok.value
+		}
+	}
+	return rows, nil
+}
+
+// ofxToken 一个标签及其后紧跟的文本
+type ofxToken struct {
+	name     string
+	value    string
+	newlines int
+}
+
+// tokenizeOFX 将 OFX 内容拆成标签序列。SGML 格式的叶子元素没有结束标签，
+// 其值即为标签后到下一个 '<' 之间的文本。
+func tokenizeOFX(content string) []ofxToken {
+	var tokens []ofxToken
+	for {
+		start := strings.IndexByte(content, '<')
+		if start < 0 {
+			return tokens
+		}
+		newlines := strings.Count(content[:start], "\n")
+		content = content[start+1:]
+
+		end := strings.IndexByte(content, '>')
+		if end < 0 {
+			return tokens
+		}
+		name := strings.ToUpper(strings.TrimSpace(content[:end]))
+		content = content[end+1:]
+
+		next := strings.IndexByte(content, '<')
+		if next < 0 {
+			next = len(content)
+		}
+		value := strings.TrimSpace(content[:next])
+
+		// 跳过 XML 声明和 OFX 处理指令
+		if strings.HasPrefix(name, "?") || strings.HasPrefix(name, "!") {
+			continue
+		}
+		tokens = append(tokens, ofxToken{name: name, value: value, newlines: newlines})
+	}
+}
+
+func ofxRow(fields map[string]string) Row {
+	row := Row{ExternalID: fields["FITID"]}
+	fmt.Sscan(fields["_line"], &row.Line)
+
+	row.Description = fields["NAME"]
+	if memo := fields["MEMO"]; memo != "" {
+		if row.Description == "" {
+			row.Description = memo
+		} else if memo != row.Description {
+			row.Description += " " + memo
+		}
+	}
+
+	// DTPOSTED 格式为 YYYYMMDD[HHMMSS[.XXX]][[gmt offset:tz name]]
+	posted := fields["DTPOSTED"]
+	if len(posted) >= 8 {
+		posted = posted[:8]
+	}
+	date, err := parseDate(posted, "20060102")
+	if err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	}
+	row.Date = date
+
+	amount, err := parseAmount(fields["TRNAMT"])
+	if err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	} else if amount == 0 {
+		row.Errors = append(row.Errors, "amount is zero")
+	}
+	row.setAmount(amount)
+
+	if row.ExternalID == "" {
+		row.Errors = append(row.Errors, "FITID is missing")
+	}
+	return row
+}
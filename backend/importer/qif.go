@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 未指定日期格式时 QIF 日期按美式 月/日/年 解析
+var qifDateFormats = []string{
+	"01/02/2006",
+	"1/2/2006",
+	"01/02/06",
+	"1/2/06",
+	"2006-01-02",
+}
+
+// ParseQIF 解析 QIF 文件中的银行或信用卡交易。dateFormat 为空时按美式日期解析，
+// 也可传入如 "02/01/2006" 以解析 日/月/年。
+func ParseQIF(r io.Reader, dateFormat string) ([]Row, error) {
+	scanner := bufio.NewScanner(r)
+
+	var rows []Row
+	var fields map[byte]string
+	start, line := 0, 0
+	sawType := false
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "!") {
+			header := strings.ToLower(text)
+			if strings.HasPrefix(header, "!type:") {
+				sawType = true
+				kind := strings.TrimSpace(header[len("!type:"):])
+				if kind != "bank" && kind != "ccard" && kind != "cash" && kind != "oth a" && kind != "oth l" {
+					return nil, fmt.Errorf("unsupported QIF type %q", text[len("!type:"):])
+				}
+			}
+			continue
+		}
+
+		if text == "^" {
+			if fields != nil {
+				rows = append(rows, qifRow(start, fields, dateFormat))
+			}
+			fields = nil
+			continue
+		}
+
+		if fields == nil {
+			fields = make(map[byte]string)
+			start = line
+		}
+		code, value := text[0], strings.TrimSpace(text[1:])
+		// 拆分明细（S/E/$）不单独导入
+		if _, exists := fields[code]; !exists {
+			fields[code] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !sawType {
+		return nil, fmt.Errorf("not a QIF file")
+	}
+	if fields != nil {
+		rows = append(rows, qifRow(start, fields, dateFormat))
+	}
+	return rows, nil
+}
+
+func qifRow(line int, fields map[byte]string, dateFormat string) Row {
+	row := Row{Line: line}
+
+	row.Description = fields['P']
+	if memo := fields['M']; memo != "" {
+		if row.Description == "" {
+			row.Description = memo
+		} else {
+			row.Description += " " + memo
+		}
+	}
+
+	// Quicken 用撇号表示 2000 年以后的年份，如 1/15'25
+	raw := strings.ReplaceAll(strings.ReplaceAll(fields['D'], "'", "/"), " ", "")
+	date, err := parseQIFDate(raw, dateFormat)
+	if err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	}
+	row.Date = date
+
+	value := fields['T']
+	if value == "" {
+		value = fields['U']
+	}
+	amount, err := parseAmount(value)
+	if err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	} else if amount == 0 {
+		row.Errors = append(row.Errors, "amount is zero")
+	}
+	row.setAmount(amount)
+	return row
+}
+
+func parseQIFDate(value, layout string) (string, error) {
+	if layout != "" {
+		return parseDate(value, layout)
+	}
+	for _, l := range qifDateFormats {
+		if date, err := parseDate(value, l); err == nil {
+			return date, nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q", value)
+}
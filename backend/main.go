@@ -8,36 +8,12 @@ import (
 	"personal-finance/handlers"
 	"personal-finance/middleware"
 	"personal-finance/models"
+	"personal-finance/notifier"
+	"personal-finance/scheduler"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jinzhu/gorm"
 )
 
-// seedDefaultCategories 初始化默认收支分类
-func seedDefaultCategories(db *gorm.DB) {
-	var count int64
-	db.Model(&models.Category{}).Count(&count)
-	if count > 0 {
-		return
-	}
-	defaultCategories := []models.Category{
-		{Name: "工资", Type: "income"},
-		{Name: "奖金", Type: "income"},
-		{Name: "投资收益", Type: "income"},
-		{Name: "其他收入", Type: "income"},
-		{Name: "餐饮", Type: "expense"},
-		{Name: "交通", Type: "expense"},
-		{Name: "购物", Type: "expense"},
-		{Name: "住房", Type: "expense"},
-		{Name: "医疗", Type: "expense"},
-		{Name: "其他支出", Type: "expense"},
-	}
-	for _, c := range defaultCategories {
-		db.Create(&c)
-	}
-	log.Println("已初始化默认收支分类")
-}
-
 func main() {
 	// 加载配置
 	cfg := config.LoadConfig()
@@ -55,10 +31,68 @@ func main() {
 		&models.Category{},
 		&models.Transaction{},
 		&models.Budget{},
+		&models.ExchangeRate{},
+		&models.Setting{},
+		&models.ImportProfile{},
+		&models.RecurringTransaction{},
+		&models.User{},
+		&models.Session{},
+		&models.Ledger{},
+		&models.LedgerMember{},
+		&models.Rule{},
+		&models.Tag{},
+		&models.TransactionSplit{},
+		&models.BudgetAlert{},
+		&models.EnvelopeTransfer{},
+		&models.Reconciliation{},
+		&models.ClearedTransaction{},
+		&models.BalanceAudit{},
+		&models.BalanceSnapshot{},
 	)
 
-	// 初始化默认分类（如果不存在）
-	seedDefaultCategories(db)
+	// 执行数据迁移
+	database.RunMigrations(db, cfg)
+
+	// 按配置在启动时校验账户余额
+	switch cfg.BalanceCheck {
+	case "report", "repair":
+		discrepancies, err := handlers.VerifyBalances(db, cfg.BalanceCheck == "repair")
+		if err != nil {
+			log.Printf("账户余额校验失败: %v", err)
+		}
+		for _, d := range discrepancies {
+			log.Printf("账户 %d（%s）余额 %s 与交易重算结果 %s 不一致", d.AccountID, d.Name, d.RecordedBalance, d.ComputedBalance)
+		}
+		if cfg.BalanceCheck == "repair" && len(discrepancies) > 0 {
+			log.Printf("已修复 %d 个账户的余额", len(discrepancies))
+		}
+	case "", "off":
+	default:
+		log.Printf("Warning: invalid BALANCE_CHECK %q, skipping balance check", cfg.BalanceCheck)
+	}
+
+	// 预算提醒：按配置启用邮件和 Webhook 通知
+	var notifiers notifier.Multi
+	if cfg.SMTPAddr != "" {
+		notifiers = append(notifiers, &notifier.SMTPNotifier{
+			Addr:     cfg.SMTPAddr,
+			From:     cfg.SMTPFrom,
+			To:       cfg.SMTPTo,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+		})
+	}
+	if cfg.AlertWebhookURL != "" {
+		notifiers = append(notifiers, &notifier.WebhookNotifier{URL: cfg.AlertWebhookURL})
+	}
+	alerts := &handlers.BudgetAlerter{DB: db}
+	if len(notifiers) > 0 {
+		alerts.Notifier = notifiers
+	}
+
+	// 启动周期交易调度
+	stopScheduler := scheduler.Start(db, alerts, cfg.SchedulerInterval)
+	defer stopScheduler()
 
 	// 创建路由
 	r := gin.New()
@@ -70,11 +104,12 @@ func main() {
 	// 使用错误处理中间件
 	r.Use(middleware.ErrorHandler())
 
-	// 允许跨域
+	// 只允许配置的前端地址跨域访问
 	r.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		c.Writer.Header().Set("Access-Control-Allow-Origin", cfg.CORSOrigin)
+		c.Writer.Header().Set("Vary", "Origin")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Ledger-ID")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -83,22 +118,80 @@ func main() {
 	})
 
 	// 初始化处理器
+	authHandler := &handlers.AuthHandler{DB: db, SessionTTL: cfg.SessionTTL, BaseCurrency: cfg.BaseCurrency}
 	accountHandler := &handlers.AccountHandler{DB: db}
-	transactionHandler := &handlers.TransactionHandler{DB: db}
+	statementHandler := &handlers.StatementHandler{DB: db}
+	reconciliationHandler := &handlers.ReconciliationHandler{DB: db}
+	transactionHandler := &handlers.TransactionHandler{DB: db, Alerts: alerts}
 	categoryHandler := &handlers.CategoryHandler{DB: db}
 	budgetHandler := &handlers.BudgetHandler{DB: db}
+	envelopeHandler := &handlers.EnvelopeHandler{DB: db}
 	statisticsHandler := &handlers.StatisticsHandler{DB: db}
+	exchangeRateHandler := &handlers.ExchangeRateHandler{DB: db}
+	settingHandler := &handlers.SettingHandler{DB: db}
+	importHandler := &handlers.ImportHandler{DB: db, Alerts: alerts}
+	recurringHandler := &handlers.RecurringHandler{DB: db, Alerts: alerts}
+	ledgerHandler := &handlers.LedgerHandler{DB: db, BaseCurrency: cfg.BaseCurrency}
+	ruleHandler := &handlers.RuleHandler{DB: db, Alerts: alerts}
+	suggestionHandler := &handlers.SuggestionHandler{DB: db}
+	tagHandler := &handlers.TagHandler{DB: db}
 
 	// API 版本前缀
 	v1 := r.Group("/api/v1")
+
+	// 注册和登录无需认证
+	v1.POST("/auth/register", authHandler.Register)
+	v1.POST("/auth/login", authHandler.Login)
+
+	// 其余接口均需登录
+	v1.Use(middleware.AuthRequired(db))
 	{
+		v1.POST("/auth/logout", authHandler.Logout)
+		v1.GET("/auth/me", authHandler.GetCurrentUser)
+
+		// 账本及成员管理路由，权限由处理器按路径中的账本校验
+		ledgers := v1.Group("/ledgers")
+		{
+			ledgers.POST("", ledgerHandler.CreateLedger)
+			ledgers.GET("", ledgerHandler.GetLedgers)
+			ledgers.PUT("/:id", ledgerHandler.UpdateLedger)
+			ledgers.DELETE("/:id", ledgerHandler.DeleteLedger)
+			ledgers.GET("/:id/members", ledgerHandler.GetLedgerMembers)
+			ledgers.POST("/:id/members", ledgerHandler.AddLedgerMember)
+			ledgers.PUT("/:id/members/:user_id", ledgerHandler.UpdateLedgerMember)
+			ledgers.DELETE("/:id/members/:user_id", ledgerHandler.RemoveLedgerMember)
+		}
+	}
+
+	// 数据接口按 X-Ledger-ID 指定的账本隔离，只读成员不能修改
+	v1.Use(middleware.LedgerRequired(db))
+	{
+
 		// 账户相关路由
 		accounts := v1.Group("/accounts")
 		{
 			accounts.POST("", accountHandler.CreateAccount)
 			accounts.GET("", accountHandler.GetAccounts)
+			accounts.GET("/integrity", accountHandler.CheckBalances)
+			accounts.POST("/integrity/repair", accountHandler.RepairBalances)
+			accounts.GET("/integrity/audits", accountHandler.GetBalanceAudits)
+			accounts.GET("/balance-history", accountHandler.GetBalanceHistory)
 			accounts.PUT("/:id", accountHandler.UpdateAccount)
+			accounts.PUT("/:id/opening-balance", accountHandler.SetOpeningBalance)
 			accounts.DELETE("/:id", accountHandler.DeleteAccount)
+			accounts.GET("/:id/statements", statementHandler.GetStatements)
+			accounts.GET("/:id/statements/:closing_date", statementHandler.GetStatement)
+			accounts.POST("/:id/reconciliations", reconciliationHandler.StartReconciliation)
+			accounts.GET("/:id/reconciliations", reconciliationHandler.GetReconciliations)
+		}
+
+		// 对账相关路由
+		reconciliations := v1.Group("/reconciliations")
+		{
+			reconciliations.GET("/:id", reconciliationHandler.GetReconciliation)
+			reconciliations.PUT("/:id/cleared", reconciliationHandler.SetCleared)
+			reconciliations.POST("/:id/finish", reconciliationHandler.FinishReconciliation)
+			reconciliations.DELETE("/:id", reconciliationHandler.CancelReconciliation)
 		}
 
 		// 交易相关路由
@@ -106,6 +199,47 @@ func main() {
 		{
 			transactions.POST("", transactionHandler.CreateTransaction)
 			transactions.GET("", transactionHandler.GetTransactions)
+			transactions.PUT("/:id", transactionHandler.UpdateTransaction)
+			transactions.DELETE("/:id", transactionHandler.DeleteTransaction)
+		}
+
+		// 转账相关路由
+		v1.POST("/transfers", transactionHandler.CreateTransfer)
+
+		// 周期交易相关路由
+		recurring := v1.Group("/recurring-transactions")
+		{
+			recurring.POST("", recurringHandler.CreateRecurringTransaction)
+			recurring.GET("", recurringHandler.GetRecurringTransactions)
+			recurring.PUT("/:id", recurringHandler.UpdateRecurringTransaction)
+			recurring.DELETE("/:id", recurringHandler.DeleteRecurringTransaction)
+			recurring.POST("/run", recurringHandler.RunRecurringTransactions)
+		}
+
+		// 自动归类规则路由
+		rules := v1.Group("/rules")
+		{
+			rules.POST("", ruleHandler.CreateRule)
+			rules.GET("", ruleHandler.GetRules)
+			rules.PUT("/:id", ruleHandler.UpdateRule)
+			rules.DELETE("/:id", ruleHandler.DeleteRule)
+			rules.POST("/apply", ruleHandler.ApplyRules)
+		}
+
+		// 标签路由
+		tags := v1.Group("/tags")
+		{
+			tags.POST("", tagHandler.CreateTag)
+			tags.GET("", tagHandler.GetTags)
+			tags.PUT("/:id", tagHandler.UpdateTag)
+			tags.DELETE("/:id", tagHandler.DeleteTag)
+		}
+
+		// 分类推荐路由
+		suggestions := v1.Group("/suggestions")
+		{
+			suggestions.POST("/category", suggestionHandler.SuggestCategory)
+			suggestions.GET("/metrics", suggestionHandler.GetSuggestionMetrics)
 		}
 
 		// 分类相关路由
@@ -123,16 +257,59 @@ func main() {
 			budgets.POST("", budgetHandler.CreateBudget)
 			budgets.GET("", budgetHandler.GetBudgets)
 			budgets.GET("/:id/status", budgetHandler.GetBudgetStatus)
+			budgets.GET("/:id/history", budgetHandler.GetBudgetHistory)
+			budgets.GET("/:id/alerts", budgetHandler.GetBudgetAlerts)
 			budgets.PUT("/:id", budgetHandler.UpdateBudget)
 			budgets.DELETE("/:id", budgetHandler.DeleteBudget)
 		}
 
+		// 零基（信封）预算路由
+		envelopes := v1.Group("/envelopes")
+		{
+			envelopes.POST("/enable", envelopeHandler.EnableEnvelopes)
+			envelopes.GET("", envelopeHandler.GetEnvelopes)
+			envelopes.POST("/assign", envelopeHandler.AssignEnvelope)
+			envelopes.POST("/move", envelopeHandler.MoveEnvelope)
+			envelopes.GET("/check", envelopeHandler.CheckEnvelopes)
+		}
+
 		// 统计相关路由
 		stats := v1.Group("/statistics")
 		{
 			stats.GET("", statisticsHandler.GetStatistics)
 			stats.GET("/budget-overview", statisticsHandler.GetBudgetOverview)
 		}
+
+		// 汇率相关路由
+		rates := v1.Group("/exchange-rates")
+		{
+			rates.POST("", exchangeRateHandler.CreateExchangeRate)
+			rates.GET("", exchangeRateHandler.GetExchangeRates)
+			rates.PUT("/:id", exchangeRateHandler.UpdateExchangeRate)
+			rates.DELETE("/:id", exchangeRateHandler.DeleteExchangeRate)
+		}
+
+		// 导入相关路由
+		importProfiles := v1.Group("/import-profiles")
+		{
+			importProfiles.POST("", importHandler.CreateImportProfile)
+			importProfiles.GET("", importHandler.GetImportProfiles)
+			importProfiles.PUT("/:id", importHandler.UpdateImportProfile)
+			importProfiles.DELETE("/:id", importHandler.DeleteImportProfile)
+		}
+		imports := v1.Group("/imports")
+		{
+			imports.POST("/csv/preview", importHandler.PreviewCSVImport)
+			imports.POST("/csv", importHandler.ConfirmCSVImport)
+			imports.POST("/ofx/preview", importHandler.PreviewOFXImport)
+			imports.POST("/ofx", importHandler.ConfirmOFXImport)
+			imports.POST("/qif/preview", importHandler.PreviewQIFImport)
+			imports.POST("/qif", importHandler.ConfirmQIFImport)
+		}
+
+		// 系统设置路由
+		v1.GET("/settings", settingHandler.GetSettings)
+		v1.PUT("/settings", settingHandler.UpdateSettings)
 	}
 
 	// 添加健康检查端点
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"personal-finance/auth"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	userIDKey = "user_id"
+	tokenKey  = "token"
+)
+
+// AuthRequired 校验 Authorization: Bearer <token> 请求头，并将当前用户写入上下文
+func AuthRequired(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+
+		session, err := auth.LookupSession(db, token)
+		if err == auth.ErrInvalidSession {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "Authentication required"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		c.Set(userIDKey, session.UserID)
+		c.Set(tokenKey, token)
+		c.Next()
+	}
+}
+
+// CurrentUserID 返回当前登录用户的 ID，未登录时为 0
+func CurrentUserID(c *gin.Context) uint {
+	return c.GetUint(userIDKey)
+}
+
+// CurrentToken 返回当前请求使用的令牌
+func CurrentToken(c *gin.Context) string {
+	return c.GetString(tokenKey)
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"personal-finance/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	ledgerIDKey   = "ledger_id"
+	ledgerRoleKey = "ledger_role"
+)
+
+// roleRank 角色权限由低到高
+var roleRank = map[string]int{
+	models.RoleViewer: 1,
+	models.RoleEditor: 2,
+	models.RoleOwner:  3,
+}
+
+// LedgerRequired 根据 X-Ledger-ID 请求头确定当前账本（未指定时使用用户最早加入的账本）并校验成员身份。
+// 只读成员只能发起 GET 请求。需在 AuthRequired 之后使用
+func LedgerRequired(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := db.Where("user_id = ?", CurrentUserID(c))
+		if ledgerID := c.GetHeader("X-Ledger-ID"); ledgerID != "" {
+			query = query.Where("ledger_id = ?", ledgerID)
+		}
+
+		var member models.LedgerMember
+		if err := query.Order("ledger_id").First(&member).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{Error: "Ledger not found or access denied"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead && !HasRole(member.Role, models.RoleEditor) {
+			c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{Error: "Viewers cannot modify this ledger"})
+			return
+		}
+
+		c.Set(ledgerIDKey, member.LedgerID)
+		c.Set(ledgerRoleKey, member.Role)
+		c.Next()
+	}
+}
+
+// HasRole 判断角色是否具有 min 及以上的权限
+func HasRole(role, min string) bool {
+	return roleRank[role] >= roleRank[min]
+}
+
+// ValidRole 判断是否为合法的成员角色
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// CurrentLedgerID 返回当前请求所操作的账本 ID
+func CurrentLedgerID(c *gin.Context) uint {
+	return c.GetUint(ledgerIDKey)
+}
+
+// CurrentLedgerRole 返回当前用户在该账本中的角色
+func CurrentLedgerRole(c *gin.Context) string {
+	return c.GetString(ledgerRoleKey)
+}
@@ -4,23 +4,90 @@ import (
 	"time"
 )
 
+// 账户类型，信用卡和贷款为负债账户
+const (
+	AccountCash       = "cash"
+	AccountDebit      = "debit"
+	AccountCreditCard = "credit_card"
+	AccountLoan       = "loan"
+	AccountInvestment = "investment"
+)
+
+// Account 账户。余额按资产方向记录：支出减少余额，负债账户欠款时余额为负数
 type Account struct {
+	ID              uint      `json:"id" gorm:"primary_key"`
+	LedgerID        uint      `json:"-" gorm:"index"` // 所属账本
+	Name            string    `json:"name" gorm:"not null"`
+	Type            string    `json:"type" gorm:"type:varchar(20);default:'debit'"` // cash、debit、credit_card、loan 或 investment
+	Balance         Money     `json:"balance" gorm:"not null"`
+	OpeningBalance  Money     `json:"opening_balance"`                     // 期初余额，余额应始终等于期初余额加上所有交易的影响
+	Currency        string    `json:"currency" gorm:"type:varchar(3)"`     // ISO 4217 币种代码
+	CreditLimit     Money     `json:"credit_limit,omitempty"`              // 信用卡额度
+	StatementDay    int       `json:"statement_day,omitempty"`             // 信用卡账单日（1~31，当月没有该日时取月末）
+	PaymentDueDay   int       `json:"payment_due_day,omitempty"`           // 信用卡或贷款的还款日（1~31）
+	AvailableCredit *Money    `json:"available_credit,omitempty" gorm:"-"` // 信用卡剩余额度，仅在账户列表中填充
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// IsLiability 判断账户是否为负债账户
+func (a Account) IsLiability() bool {
+	return a.Type == AccountCreditCard || a.Type == AccountLoan
+}
+
+// BalanceAudit 账户余额修复记录：余额与按期初余额和交易重算的结果不一致时，记录修复前后的余额
+type BalanceAudit struct {
+	ID         uint      `json:"id" gorm:"primary_key"`
+	LedgerID   uint      `json:"-" gorm:"index"` // 所属账本
+	AccountID  uint      `json:"account_id" gorm:"index"`
+	OldBalance Money     `json:"old_balance"`
+	NewBalance Money     `json:"new_balance"`
+	Source     string    `json:"source" gorm:"type:varchar(20)"` // api 或 startup
+	CreatedBy  uint      `json:"created_by"`                     // 启动检查时为 0
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BalanceSnapshot 账户在某日结束时按交易记录计算的余额，用于加速余额走势查询。
+// 该日及之前的交易或期初余额变化时快照即被删除
+type BalanceSnapshot struct {
 	ID        uint      `json:"id" gorm:"primary_key"`
-	Name      string    `json:"name" gorm:"not null"`
-	Balance   float64   `json:"balance" gorm:"not null"`
+	LedgerID  uint      `json:"-" gorm:"index"` // 所属账本
+	AccountID uint      `json:"account_id" gorm:"unique_index:idx_balance_snapshot"`
+	Date      string    `json:"date" gorm:"type:varchar(10);unique_index:idx_balance_snapshot"`
+	Balance   Money     `json:"balance"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type Transaction struct {
-	ID          uint      `json:"id" gorm:"primary_key"`
-	AccountID   uint      `json:"account_id" gorm:"not null"`
-	Amount      float64   `json:"amount" gorm:"not null"`
-	Type        string    `json:"type" gorm:"not null"` // "income" or "expense"
-	CategoryID  uint      `json:"category_id" gorm:"not null"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Account     Account   `json:"account" gorm:"foreignkey:AccountID"`
-	Category    Category  `json:"category" gorm:"foreignkey:CategoryID"`
+	ID          uint               `json:"id" gorm:"primary_key"`
+	LedgerID    uint               `json:"-" gorm:"index"`          // 所属账本
+	CreatedBy   uint               `json:"created_by" gorm:"index"` // 记账的成员
+	AccountID   uint               `json:"account_id" gorm:"not null"`
+	ToAccountID uint               `json:"to_account_id,omitempty" gorm:"index"` // 转账的转入账户
+	Amount      Money              `json:"amount" gorm:"not null"`
+	Currency    string             `json:"currency" gorm:"type:varchar(3)"` // 与账户币种一致
+	ToAmount    Money              `json:"to_amount,omitempty"`             // 转账记入转入账户的金额（按转入账户币种）
+	Type        string             `json:"type" gorm:"not null"`            // "income", "expense" or "transfer"
+	CategoryID  uint               `json:"category_id" gorm:"not null"`     // 转账为 0；拆分交易为第一条拆分行的分类
+	Description string             `json:"description"`
+	Date        string             `json:"date" gorm:"type:varchar(10);index"` // 业务发生日期，默认为创建当天
+	ExternalID  string             `json:"external_id,omitempty" gorm:"index"` // 导入来源的唯一编号，如 OFX 的 FITID
+	RecurringID uint               `json:"recurring_id,omitempty"`             // 由周期交易生成时对应的模板
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Account     Account            `json:"account" gorm:"foreignkey:AccountID"`
+	Category    Category           `json:"category" gorm:"foreignkey:CategoryID"`
+	Tags        []Tag              `json:"tags,omitempty" gorm:"many2many:transaction_tags;save_associations:false"`
+	TagIDs      []uint             `json:"tag_ids,omitempty" gorm:"-"` // 仅用于创建和更新时指定标签
+	Splits      []TransactionSplit `json:"splits,omitempty" gorm:"foreignkey:TransactionID;save_associations:false"`
+}
+
+// TransactionSplit 拆分交易的一行，各行金额之和等于交易金额，币种与交易一致
+type TransactionSplit struct {
+	ID            uint      `json:"id" gorm:"primary_key"`
+	TransactionID uint      `json:"transaction_id" gorm:"index;not null"`
+	CategoryID    uint      `json:"category_id" gorm:"index;not null"`
+	Amount        Money     `json:"amount" gorm:"not null"`
+	Memo          string    `json:"memo"`
+	Category      *Category `json:"category,omitempty" gorm:"foreignkey:CategoryID;save_associations:false"`
 }
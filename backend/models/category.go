@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -8,55 +11,152 @@ import (
 
 // Category 交易分类模型
 type Category struct {
-	ID        uint      `json:"id" gorm:"primary_key"`
-	Name      string    `json:"name" gorm:"not null"`
-	Type      string    `json:"type" gorm:"not null"` // expense 或 income
-	Icon      string    `json:"icon"`                 // 分类图标
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint       `json:"id" gorm:"primary_key"`
+	LedgerID  uint       `json:"-" gorm:"index"` // 所属账本
+	Name      string     `json:"name" gorm:"not null"`
+	Type      string     `json:"type" gorm:"not null"`             // expense 或 income
+	Icon      string     `json:"icon"`                             // 分类图标
+	ParentID  uint       `json:"parent_id,omitempty" gorm:"index"` // 上级分类，0 表示顶级分类
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Children  []Category `json:"children,omitempty" gorm:"-"` // 仅在按树形返回时填充
 }
 
 // Budget 预算模型
 // BudgetInput 用于创建预算的输入结构
 type BudgetInput struct {
-	CategoryID uint    `json:"category_id" binding:"required"`
-	Amount     float64 `json:"amount" binding:"required"`
-	StartDate  string  `json:"start_date" binding:"required"`
-	EndDate    string  `json:"end_date" binding:"required"`
+	CategoryID uint   `json:"category_id"` // 分类和标签至少指定一个
+	TagID      uint   `json:"tag_id"`
+	Amount     Money  `json:"amount" binding:"required"`
+	Period     string `json:"period"`   // 为空表示一次性预算
+	Rollover   string `json:"rollover"` // 仅周期预算可用
+	StartDate  string `json:"start_date" binding:"required"`
+	EndDate    string `json:"end_date"`   // 一次性预算必填，周期预算为空表示一直延续
+	Thresholds []int  `json:"thresholds"` // 提醒阈值（百分比），如 [50, 80, 100]
 }
 
 // Budget 预算模型
 type Budget struct {
 	gorm.Model
-	CategoryID uint     `json:"category_id" gorm:"not null"`
-	Amount     float64  `json:"amount" gorm:"not null"`
-	StartDate  string   `json:"start_date" gorm:"type:date;not null"`
-	EndDate    string   `json:"end_date" gorm:"type:date;not null"`
+	LedgerID   uint     `json:"-" gorm:"index"`                                    // 所属账本
+	Kind       string   `json:"kind,omitempty" gorm:"type:varchar(10);default:''"` // "envelope" 表示零基预算的信封，Amount 为累计分配的金额
+	CategoryID uint     `json:"category_id" gorm:"not null"`                       // 0 表示不限分类
+	TagID      uint     `json:"tag_id,omitempty" gorm:"index"`                     // 只统计带该标签的支出，0 表示不限标签
+	Amount     Money    `json:"amount" gorm:"not null"`                            // 周期预算为每个周期的金额
+	Period     string   `json:"period,omitempty" gorm:"type:varchar(10)"`          // "weekly"、"monthly"、"quarterly" 或 "yearly"，为空表示一次性预算
+	Rollover   string   `json:"rollover,omitempty" gorm:"type:varchar(10)"`        // 结转方式："none"、"surplus"（结余）、"deficit"（超支）或 "both"
+	StartDate  string   `json:"start_date" gorm:"type:date;not null"`              // 周期预算的第一个周期从这一天开始
+	EndDate    string   `json:"end_date" gorm:"type:date;not null"`                // 周期预算为空表示一直延续
+	Thresholds IntList  `json:"thresholds,omitempty" gorm:"type:text"`             // 提醒阈值（百分比），每期每个阈值只提醒一次
 	Category   Category `json:"category" gorm:"foreignkey:CategoryID"`
+	Tag        *Tag     `json:"tag,omitempty" gorm:"foreignkey:TagID;save_associations:false"`
+}
+
+// BudgetAlert 预算阈值提醒记录，(budget_id, period_start, threshold) 唯一，保证每期每个阈值只提醒一次
+type BudgetAlert struct {
+	ID            uint       `json:"id" gorm:"primary_key"`
+	LedgerID      uint       `json:"-" gorm:"index"`
+	BudgetID      uint       `json:"budget_id" gorm:"unique_index:idx_budget_alert"`
+	PeriodStart   string     `json:"period_start" gorm:"type:varchar(10);unique_index:idx_budget_alert"`
+	PeriodEnd     string     `json:"period_end" gorm:"type:varchar(10)"`
+	Threshold     int        `json:"threshold" gorm:"unique_index:idx_budget_alert"`
+	Available     Money      `json:"available"`
+	ActualExpense Money      `json:"actual_expense"`
+	Percentage    float64    `json:"percentage"`
+	SentAt        *time.Time `json:"sent_at"`         // 发送成功的时间，未配置通知渠道或发送失败时为空
+	Error         string     `json:"error,omitempty"` // 发送失败的原因
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// EnvelopeTransfer 零基预算的资金划拨记录。FromBudgetID 为 0 表示从待分配资金分配到信封，
+// ToBudgetID 为 0 表示退回待分配资金，两者都非 0 表示在信封之间移动
+type EnvelopeTransfer struct {
+	ID           uint      `json:"id" gorm:"primary_key"`
+	LedgerID     uint      `json:"-" gorm:"index"`
+	FromBudgetID uint      `json:"from_budget_id" gorm:"index"`
+	ToBudgetID   uint      `json:"to_budget_id" gorm:"index"`
+	Amount       Money     `json:"amount" gorm:"not null"`
+	Note         string    `json:"note"`
+	CreatedBy    uint      `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// IntList 整数列表，以 JSON 数组存储在单个文本列中
+type IntList []int
+
+// Scan 实现 sql.Scanner
+func (l *IntList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return l.scanString(string(v))
+	case string:
+		return l.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into IntList", value)
+	}
+}
+
+func (l *IntList) scanString(s string) error {
+	if s == "" {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal([]byte(s), l)
+}
+
+// Value 实现 driver.Valuer
+func (l IntList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal([]int(l))
+	return string(data), err
 }
 
 // Statistics 统计数据结构
 type Statistics struct {
-	TotalIncome  float64                  `json:"total_income"`
-	TotalExpense float64                  `json:"total_expense"`
-	NetAmount    float64                  `json:"net_amount"`
+	TotalIncome  Money                    `json:"total_income"`
+	TotalExpense Money                    `json:"total_expense"`
+	NetAmount    Money                    `json:"net_amount"`
 	ByCategory   []CategoryStatistics     `json:"by_category"`
 	ByMonth      []MonthlyStatistics     `json:"by_month"`
+	ByMember     []MemberStatistics      `json:"by_member"`
+	ByTag        []TagStatistics         `json:"by_tag"`
 }
 
 // CategoryStatistics 分类统计
 type CategoryStatistics struct {
 	CategoryID   uint    `json:"category_id"`
 	CategoryName string  `json:"category_name"`
-	Amount      float64 `json:"amount"`
+	ParentID     uint    `json:"parent_id,omitempty"`
+	Amount      Money   `json:"amount"`
 	Percentage  float64 `json:"percentage"`
 }
 
+// MemberStatistics 账本成员统计，按记账的成员汇总
+type MemberStatistics struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Income   Money  `json:"income"`
+	Expense  Money  `json:"expense"`
+}
+
+// TagStatistics 标签统计，带多个标签的交易计入每个标签
+type TagStatistics struct {
+	TagID   uint   `json:"tag_id"`
+	TagName string `json:"tag_name"`
+	Income  Money  `json:"income"`
+	Expense Money  `json:"expense"`
+}
+
 // MonthlyStatistics 月度统计
 type MonthlyStatistics struct {
 	Year        int     `json:"year"`
 	Month       int     `json:"month"`
-	Income      float64 `json:"income"`
-	Expense     float64 `json:"expense"`
-	NetAmount   float64 `json:"net_amount"`
+	Income      Money   `json:"income"`
+	Expense     Money   `json:"expense"`
+	NetAmount   Money   `json:"net_amount"`
 }
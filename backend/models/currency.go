@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// ExchangeRate 汇率：自 Date 起，1 单位 Currency 可兑换 Rate 单位 QuoteCurrency
+type ExchangeRate struct {
+	ID            uint      `json:"id" gorm:"primary_key"`
+	LedgerID      uint      `json:"-" gorm:"index"` // 所属账本
+	Currency      string    `json:"currency" gorm:"type:varchar(3);not null;index"`
+	QuoteCurrency string    `json:"quote_currency" gorm:"type:varchar(3);not null"`
+	Rate          float64   `json:"rate" gorm:"not null"`
+	Date          string    `json:"date" gorm:"type:varchar(10);not null"` // 生效日期 YYYY-MM-DD
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Setting 账本设置键值对
+type Setting struct {
+	LedgerID uint   `json:"-" gorm:"primary_key;auto_increment:false"` // 所属账本
+	Name     string `json:"name" gorm:"primary_key"`
+	Value    string `json:"value"`
+}
+
+// SettingBaseCurrency 本位币设置项，统计和汇总金额均换算为该币种
+const SettingBaseCurrency = "base_currency"
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// ImportProfile CSV 导入的列映射配置，可按银行保存复用
+type ImportProfile struct {
+	ID        uint   `json:"id" gorm:"primary_key"`
+	LedgerID  uint   `json:"-" gorm:"index"` // 所属账本
+	Name      string `json:"name" gorm:"not null"`
+	Encoding  string `json:"encoding"`   // utf-8（默认）、gbk 或 gb18030
+	Delimiter string `json:"delimiter"`  // 默认逗号
+	SkipRows  int    `json:"skip_rows"`  // 表头之前需要跳过的行数，如银行导出的说明行
+	HasHeader bool   `json:"has_header"` // 为 true 时列可按表头名称引用
+	// 列引用：表头名称，或从 1 开始的列序号
+	DateColumn        string `json:"date_column"`
+	AmountColumn      string `json:"amount_column"`
+	DebitColumn       string `json:"debit_column"`  // 仅 debit_credit 约定：支出列
+	CreditColumn      string `json:"credit_column"` // 仅 debit_credit 约定：收入列
+	DescriptionColumn string `json:"description_column"`
+	DateFormat        string `json:"date_format"` // Go 时间格式，留空时自动识别常见格式
+	// SignConvention 金额符号约定：
+	//   signed       正数为收入、负数为支出（默认）
+	//   inverted     正数为支出、负数为收入（常见于信用卡账单）
+	//   debit_credit 支出和收入分别在两列
+	SignConvention string `json:"sign_convention"`
+	// 未被规则归类时使用的默认分类
+	IncomeCategoryID  uint      `json:"income_category_id"`
+	ExpenseCategoryID uint      `json:"expense_category_id"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
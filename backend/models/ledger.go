@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// 账本成员角色
+const (
+	RoleOwner  = "owner"  // 可管理账本和成员
+	RoleEditor = "editor" // 可读写账本数据
+	RoleViewer = "viewer" // 只读
+)
+
+// Ledger 账本，账户、分类、预算和交易都属于某个账本。
+// 每个用户注册时获得一个个人账本，也可以创建共享账本并邀请其他用户
+type Ledger struct {
+	ID                uint      `json:"id" gorm:"primary_key"`
+	Name              string    `json:"name" gorm:"not null" binding:"required"`
+	EnvelopeStartDate string    `json:"envelope_start_date,omitempty" gorm:"type:varchar(10)"` // 零基预算的开始日期，为空表示未启用
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	Role              string    `json:"role,omitempty" gorm:"-"` // 当前用户在该账本中的角色，仅在接口返回时填充
+}
+
+// LedgerMember 账本成员
+type LedgerMember struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	LedgerID  uint      `json:"ledger_id" gorm:"not null;unique_index:idx_ledger_member"`
+	UserID    uint      `json:"user_id" gorm:"not null;unique_index:idx_ledger_member"`
+	Role      string    `json:"role" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	User      User      `json:"user" gorm:"foreignkey:UserID"`
+}
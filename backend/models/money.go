@@ -0,0 +1,134 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money 金额，以分为单位的整数存储，避免浮点数累加产生误差。
+// JSON 中序列化为精确的十进制字符串（如 "12.34"），反序列化时同时接受字符串和数字。
+type Money int64
+
+var errInvalidMoney = errors.New("invalid amount")
+
+// ParseMoney 解析最多两位小数的十进制金额字符串
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(s, "-") {
+		negative = true
+		s = s[1:]
+	} else if strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return 0, errInvalidMoney
+	}
+	if len(fracPart) > 2 {
+		return 0, fmt.Errorf("invalid amount %q: at most 2 decimal places", s)
+	}
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	for len(fracPart) < 2 {
+		fracPart += "0"
+	}
+
+	units, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || units > (math.MaxInt64-99)/100 {
+		return 0, fmt.Errorf("invalid amount %q: out of range", s)
+	}
+	cents, _ := strconv.ParseInt(fracPart, 10, 64)
+
+	value := units*100 + cents
+	if negative {
+		value = -value
+	}
+	return Money(value), nil
+}
+
+// String 返回两位小数的十进制表示
+func (m Money) String() string {
+	sign := ""
+	value := int64(m)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/100, value%100)
+}
+
+// MarshalJSON 将金额序列化为十进制字符串
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
+
+// UnmarshalJSON 接受 "12.34" 或 12.34 两种形式，按原始文本精确解析
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	text := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		unquoted, err := strconv.Unquote(text)
+		if err != nil {
+			return err
+		}
+		text = unquoted
+	}
+	value, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = value
+	return nil
+}
+
+// Scan 实现 sql.Scanner。兼容迁移前以 REAL 存储的列和 SUM 等聚合结果。
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case float64:
+		*m = Money(math.Round(v))
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*m = Money(math.Round(f))
+	return nil
+}
+
+// Value 实现 driver.Valuer，以分为单位存储
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Reconciliation 账户对账。进行中时（FinishedAt 为空）可勾选已清算的交易，完成后其中的交易被锁定。
+// 每个账户同时只能有一个进行中的对账
+type Reconciliation struct {
+	ID               uint       `json:"id" gorm:"primary_key"`
+	LedgerID         uint       `json:"-" gorm:"index"` // 所属账本
+	AccountID        uint       `json:"account_id" gorm:"index;not null"`
+	StatementDate    string     `json:"statement_date" gorm:"type:varchar(10)"` // 对账单日期
+	StatementBalance Money      `json:"statement_balance"`                      // 对账单期末余额，按账户余额方向记录
+	AdjustmentID     uint       `json:"adjustment_id,omitempty"`                // 完成时补记的余额调整交易
+	CreatedBy        uint       `json:"created_by"`
+	FinishedAt       *time.Time `json:"finished_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// ClearedTransaction 在对账中勾选为已清算的交易。转账涉及两个账户，在各自账户的对账中分别清算
+type ClearedTransaction struct {
+	ID               uint `json:"id" gorm:"primary_key"`
+	ReconciliationID uint `json:"reconciliation_id" gorm:"index;not null"`
+	AccountID        uint `json:"account_id" gorm:"unique_index:idx_cleared_transaction"`
+	TransactionID    uint `json:"transaction_id" gorm:"unique_index:idx_cleared_transaction"`
+}
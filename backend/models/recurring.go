@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// RecurringTransaction 周期交易模板：从 StartDate 起每隔 Interval 个 Frequency 生成一笔交易，
+// 直到 EndDate（为空表示不结束）
+type RecurringTransaction struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	LedgerID    uint      `json:"-" gorm:"index"` // 所属账本
+	CreatedBy   uint      `json:"created_by"`     // 创建模板的成员，生成的交易记在其名下
+	AccountID   uint      `json:"account_id" binding:"required"`
+	ToAccountID uint      `json:"to_account_id,omitempty"` // 仅转账使用
+	Amount      Money     `json:"amount" binding:"required"`
+	Type        string    `json:"type" binding:"required"` // "income", "expense" 或 "transfer"
+	CategoryID  uint      `json:"category_id"`
+	Description string    `json:"description"`
+	Frequency   string    `json:"frequency" binding:"required"` // "daily", "weekly", "monthly" 或 "yearly"
+	Interval    int       `json:"interval"`
+	StartDate   string    `json:"start_date" gorm:"type:varchar(10)"`
+	EndDate     string    `json:"end_date,omitempty" gorm:"type:varchar(10)"`
+	NextDate    string    `json:"next_date" gorm:"type:varchar(10);index"`     // 下一次待生成的日期，为空表示已结束
+	LastDate    string    `json:"last_date,omitempty" gorm:"type:varchar(10)"` // 最近一次已生成的日期
+	LastError   string    `json:"last_error,omitempty"`                        // 最近一次生成失败的原因
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
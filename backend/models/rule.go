@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Rule 自动归类规则。条件均为可选，未设置的条件视为满足，所有已设置的条件都满足时执行动作。
+// 规则在创建交易、导入和生成周期交易时按 Priority 从小到大执行：分类和描述取第一条设置了它们的匹配规则，
+// 标签则累加所有匹配规则的标签
+type Rule struct {
+	ID       uint   `json:"id" gorm:"primary_key"`
+	LedgerID uint   `json:"-" gorm:"index"` // 所属账本
+	Name     string `json:"name" gorm:"not null" binding:"required"`
+	Priority int    `json:"priority"` // 数值小的先执行
+	Disabled bool   `json:"disabled"`
+	// 条件
+	DescriptionContains string `json:"description_contains"` // 描述包含该文本，不区分大小写
+	DescriptionRegex    string `json:"description_regex"`    // 描述匹配该正则表达式
+	MinAmount           Money  `json:"min_amount"`           // 金额下限（含），0 表示不限
+	MaxAmount           Money  `json:"max_amount"`           // 金额上限（含），0 表示不限
+	AccountID           uint   `json:"account_id"`
+	Type                string `json:"type"` // "income", "expense" or "transfer"
+	// 动作
+	CategoryID     uint       `json:"category_id"`               // 设置分类，须与交易类型一致
+	AddTags        StringList `json:"add_tags" gorm:"type:text"` // 追加的标签名称
+	SetDescription string     `json:"set_description"`           // 改写描述，可用 $1 等引用正则分组
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// StringList 字符串列表，以 JSON 数组存储在单个文本列中
+type StringList []string
+
+// Scan 实现 sql.Scanner
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return l.scanString(string(v))
+	case string:
+		return l.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+}
+
+func (l *StringList) scanString(s string) error {
+	if s == "" {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal([]byte(s), l)
+}
+
+// Value 实现 driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal([]string(l))
+	return string(data), err
+}
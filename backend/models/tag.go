@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// Tag 交易标签，表达分类之外的维度，如某次旅行或可报销的支出。标签名在账本内唯一
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	LedgerID  uint      `json:"-" gorm:"unique_index:idx_ledger_tag"` // 所属账本
+	Name      string    `json:"name" gorm:"not null;unique_index:idx_ledger_tag"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// User 用户，账户、分类、交易等数据均归属于某个用户
+type User struct {
+	ID           uint      `json:"id" gorm:"primary_key"`
+	Username     string    `json:"username" gorm:"type:varchar(64);unique_index;not null"`
+	PasswordHash string    `json:"-" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Session 登录会话，只保存令牌的哈希值
+type Session struct {
+	TokenHash string    `gorm:"primary_key;type:varchar(64)"`
+	UserID    uint      `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// Credentials 注册和登录请求
+type Credentials struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
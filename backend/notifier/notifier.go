@@ -0,0 +1,51 @@
+// Package notifier 发送预算提醒等通知，内置 SMTP 邮件和通用 Webhook 两种实现
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"personal-finance/models"
+)
+
+// Alert 一次预算阈值提醒
+type Alert struct {
+	BudgetID      uint         `json:"budget_id"`
+	LedgerID      uint         `json:"ledger_id"`
+	Subject       string       `json:"subject"` // 预算对象，如分类或标签名称
+	Threshold     int          `json:"threshold"`
+	PeriodStart   string       `json:"period_start"`
+	PeriodEnd     string       `json:"period_end"`
+	Available     models.Money `json:"available"`
+	ActualExpense models.Money `json:"actual_expense"`
+	Percentage    float64      `json:"percentage"`
+}
+
+// Title 返回提醒的标题，如“预算提醒：餐饮已使用 85%”
+func (a Alert) Title() string {
+	return fmt.Sprintf("预算提醒：%s已使用 %.0f%%", a.Subject, a.Percentage)
+}
+
+// Text 返回提醒的正文
+func (a Alert) Text() string {
+	return fmt.Sprintf("%s 的预算在 %s 至 %s 期间已达到 %d%% 的提醒阈值。\r\n可用金额：%s\r\n实际支出：%s\r\n使用比例：%.1f%%\r\n",
+		a.Subject, a.PeriodStart, a.PeriodEnd, a.Threshold, a.Available, a.ActualExpense, a.Percentage)
+}
+
+// Notifier 通知渠道
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+// Multi 依次发送到多个渠道，某个渠道失败不影响其他渠道，返回所有失败原因
+type Multi []Notifier
+
+// Notify 实现 Notifier
+func (m Multi) Notify(alert Alert) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier 通过 SMTP 发送提醒邮件。服务器支持 STARTTLS 时自动启用；
+// 未配置用户名时不进行认证
+type SMTPNotifier struct {
+	Addr     string // host:port
+	From     string
+	To       []string
+	Username string
+	Password string
+}
+
+// Notify 实现 Notifier
+func (n *SMTPNotifier) Notify(alert Alert) error {
+	var auth smtp.Auth
+	if n.Username != "" {
+		host, _, err := net.SplitHostPort(n.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+	if err := smtp.SendMail(n.Addr, auth, n.From, n.To, n.message(alert)); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
+
+// message 生成 UTF-8 编码的邮件，标题按 RFC 2047 编码，正文使用 base64
+func (n *SMTPNotifier) message(alert Alert) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", n.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", alert.Title()))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	body := base64.StdEncoding.EncodeToString([]byte(alert.Text()))
+	for len(body) > 76 {
+		buf.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	buf.WriteString(body + "\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,46 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier 以 JSON 格式将提醒 POST 到指定地址，响应非 2xx 时视为失败
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client // 为空时使用 10 秒超时的默认客户端
+}
+
+var defaultWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// webhookPayload 在提醒字段之外附带标题和正文，方便直接转发到聊天工具
+type webhookPayload struct {
+	Alert
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// Notify 实现 Notifier
+func (n *WebhookNotifier) Notify(alert Alert) error {
+	body, err := json.Marshal(webhookPayload{Alert: alert, Title: alert.Title(), Text: alert.Text()})
+	if err != nil {
+		return err
+	}
+
+	client := n.Client
+	if client == nil {
+		client = defaultWebhookClient
+	}
+	resp, err := client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"log"
+	"personal-finance/handlers"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Start 在后台启动调度：立即补生成停机期间错过的周期交易，之后每隔 interval 检查一次。
+// 生成的支出会检查预算提醒（alerts 可为空）。返回的函数用于停止调度。
+func Start(db *gorm.DB, alerts *handlers.BudgetAlerter, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			run(db, alerts)
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func run(db *gorm.DB, alerts *handlers.BudgetAlerter) {
+	created, err := handlers.MaterializeRecurring(db, alerts, time.Now().Format("2006-01-02"))
+	if err != nil {
+		log.Printf("周期交易调度失败: %v", err)
+		return
+	}
+	if created > 0 {
+		log.Printf("已生成 %d 笔周期交易", created)
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountTypes(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.AccountHandler{DB: db}
+	r.POST("/accounts", h.CreateAccount)
+	r.GET("/accounts", h.GetAccounts)
+	r.PUT("/accounts/:id", h.UpdateAccount)
+
+	create := func(payload gin.H) models.Account {
+		w := doJSON(r, "POST", "/accounts", payload)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var account models.Account
+		json.Unmarshal(w.Body.Bytes(), &account)
+		return account
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		w := doJSON(r, "POST", "/accounts", gin.H{"name": "存折", "balance": "0", "type": "bank"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "POST", "/accounts", gin.H{"name": "钱包", "balance": "0", "type": "cash", "credit_limit": "100"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "POST", "/accounts", gin.H{"name": "信用卡", "balance": "0", "type": "credit_card", "statement_day": 32})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	debit := create(gin.H{"name": "工资卡", "balance": "8000"})
+	assert.Equal(t, models.AccountDebit, debit.Type)
+	create(gin.H{"name": "基金", "balance": "2000", "type": "investment"})
+	card := create(gin.H{
+		"name": "信用卡", "balance": "-1500", "type": "credit_card",
+		"credit_limit": "10000", "statement_day": 5, "payment_due_day": 25,
+	})
+	create(gin.H{"name": "房贷", "balance": "-3000", "type": "loan", "payment_due_day": 15})
+
+	t.Run("Net Worth", func(t *testing.T) {
+		w := doJSON(r, "GET", "/accounts", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Accounts         []models.Account `json:"accounts"`
+			TotalAssets      models.Money     `json:"total_assets"`
+			TotalLiabilities models.Money     `json:"total_liabilities"`
+			NetWorth         models.Money     `json:"net_worth"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.Equal(t, "10000.00", resp.TotalAssets.String())
+		assert.Equal(t, "4500.00", resp.TotalLiabilities.String())
+		assert.Equal(t, "5500.00", resp.NetWorth.String())
+		for _, account := range resp.Accounts {
+			if account.ID == card.ID && assert.NotNil(t, account.AvailableCredit) {
+				assert.Equal(t, "8500.00", account.AvailableCredit.String())
+			}
+		}
+	})
+
+	t.Run("Update Keeps Type", func(t *testing.T) {
+		w := doJSON(r, "PUT", fmt.Sprintf("/accounts/%d", card.ID), gin.H{
+			"name": "信用卡", "balance": "-1500", "credit_limit": "20000", "statement_day": 10, "payment_due_day": 28,
+		})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var updated models.Account
+		json.Unmarshal(w.Body.Bytes(), &updated)
+		assert.Equal(t, models.AccountCreditCard, updated.Type)
+		assert.Equal(t, 10, updated.StatementDay)
+
+		// 改为借记账户时必须清除信用卡设置
+		w = doJSON(r, "PUT", fmt.Sprintf("/accounts/%d", card.ID), gin.H{
+			"name": "信用卡", "balance": "-1500", "type": "debit", "credit_limit": "20000",
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Partial Update", func(t *testing.T) {
+		// 未给出的字段保持不变
+		w := doJSON(r, "PUT", fmt.Sprintf("/accounts/%d", card.ID), gin.H{"name": "招行信用卡"})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var updated models.Account
+		json.Unmarshal(w.Body.Bytes(), &updated)
+		assert.Equal(t, "招行信用卡", updated.Name)
+		assert.Equal(t, "-1500.00", updated.Balance.String())
+		assert.Equal(t, "20000.00", updated.CreditLimit.String())
+		assert.Equal(t, 10, updated.StatementDay)
+		assert.Equal(t, 28, updated.PaymentDueDay)
+
+		w = doJSON(r, "PUT", fmt.Sprintf("/accounts/%d", card.ID), gin.H{"statement_day": 5})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		json.Unmarshal(w.Body.Bytes(), &updated)
+		assert.Equal(t, "招行信用卡", updated.Name)
+		assert.Equal(t, 5, updated.StatementDay)
+		assert.Equal(t, "20000.00", updated.CreditLimit.String())
+
+		w = doJSON(r, "PUT", fmt.Sprintf("/accounts/%d", card.ID), gin.H{"name": " "})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
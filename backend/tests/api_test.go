@@ -13,20 +13,21 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 )
 
-func setupTestRouter() (*gin.Engine, *handlers.CategoryHandler, *handlers.StatisticsHandler) {
-	// 设置测试模式
-	gin.SetMode(gin.TestMode)
-	r := gin.Default()
-
+// setupTestDB 创建并迁移一个内存测试数据库
+func setupTestDB() *gorm.DB {
 	// 配置测试数据库
 	cfg := &config.Config{
-		DBPath:  ":memory:", // 使用内存数据库进行测试
-		GinMode: "test",
+		DBPath:       ":memory:", // 使用内存数据库进行测试
+		GinMode:      "test",
+		BaseCurrency: "CNY",
 	}
 	db := database.InitDB(cfg)
+	// 内存数据库按连接隔离，测试中只保留一个连接
+	db.DB().SetMaxOpenConns(1)
 
 	// 自动迁移数据库结构
 	db.AutoMigrate(
@@ -34,7 +35,35 @@ func setupTestRouter() (*gin.Engine, *handlers.CategoryHandler, *handlers.Statis
 		&models.Budget{},
 		&models.Transaction{},
 		&models.Account{},
+		&models.ExchangeRate{},
+		&models.Setting{},
+		&models.ImportProfile{},
+		&models.RecurringTransaction{},
+		&models.User{},
+		&models.Session{},
+		&models.Ledger{},
+		&models.LedgerMember{},
+		&models.Rule{},
+		&models.Tag{},
+		&models.TransactionSplit{},
+		&models.BudgetAlert{},
+		&models.EnvelopeTransfer{},
+		&models.Reconciliation{},
+		&models.ClearedTransaction{},
+		&models.BalanceAudit{},
+		&models.BalanceSnapshot{},
 	)
+	database.RunMigrations(db, cfg)
+
+	return db
+}
+
+func setupTestRouter() (*gin.Engine, *handlers.CategoryHandler, *handlers.StatisticsHandler) {
+	// 设置测试模式
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	db := setupTestDB()
 
 	categoryHandler := &handlers.CategoryHandler{DB: db}
 	statisticsHandler := &handlers.StatisticsHandler{DB: db}
@@ -182,14 +211,15 @@ func TestCategoryOperations(t *testing.T) {
 }
 
 func TestBudgetOperations(t *testing.T) {
-	r, categoryHandler, statisticsHandler := setupTestRouter()
+	r, categoryHandler, _ := setupTestRouter()
+	budgetHandler := &handlers.BudgetHandler{DB: categoryHandler.DB}
 
 	// 设置路由
 	r.POST("/categories", categoryHandler.CreateCategory)
-	r.POST("/budgets", statisticsHandler.CreateBudget)
-	r.GET("/budgets", statisticsHandler.GetBudgets)
-	r.PUT("/budgets/:id", statisticsHandler.UpdateBudget)
-	r.DELETE("/budgets/:id", statisticsHandler.DeleteBudget)
+	r.POST("/budgets", budgetHandler.CreateBudget)
+	r.GET("/budgets", budgetHandler.GetBudgets)
+	r.PUT("/budgets/:id", budgetHandler.UpdateBudget)
+	r.DELETE("/budgets/:id", budgetHandler.DeleteBudget)
 
 	// 首先创建一个分类用于测试
 	category := models.Category{
@@ -209,7 +239,7 @@ func TestBudgetOperations(t *testing.T) {
 	t.Run("Create Budget", func(t *testing.T) {
 		budget := models.BudgetInput{
 			CategoryID: createdCategory.ID,
-			Amount:     1000.0,
+			Amount:     models.Money(100000),
 			StartDate:  "2025-02-01",
 			EndDate:    "2025-02-28",
 		}
@@ -266,8 +296,8 @@ func TestStatistics(t *testing.T) {
 		err := json.Unmarshal(w.Body.Bytes(), &stats)
 		assert.Nil(t, err)
 		// 验证统计数据的结构
-		assert.GreaterOrEqual(t, stats.TotalExpense, 0.0)
-		assert.GreaterOrEqual(t, stats.TotalIncome, 0.0)
+		assert.GreaterOrEqual(t, stats.TotalExpense, models.Money(0))
+		assert.GreaterOrEqual(t, stats.TotalIncome, models.Money(0))
 		assert.Equal(t, stats.NetAmount, stats.TotalIncome-stats.TotalExpense)
 	})
 
@@ -286,9 +316,9 @@ func TestStatistics(t *testing.T) {
 			} `json:"current_month"`
 			Budgets []struct {
 				models.Budget
-				ActualExpense  float64 `json:"actual_expense"`
-				Remaining      float64 `json:"remaining"`
-				PercentageUsed float64 `json:"percentage_used"`
+				ActualExpense  models.Money `json:"actual_expense"`
+				Remaining      models.Money `json:"remaining"`
+				PercentageUsed float64      `json:"percentage_used"`
 			} `json:"budgets"`
 		}
 		err := json.Unmarshal(w.Body.Bytes(), &response)
@@ -299,7 +329,7 @@ func TestStatistics(t *testing.T) {
 		for _, budget := range response.Budgets {
 			assert.Equal(t, budget.Remaining, budget.Amount-budget.ActualExpense)
 			if budget.Amount > 0 {
-				assert.Equal(t, budget.PercentageUsed, (budget.ActualExpense/budget.Amount)*100)
+				assert.Equal(t, budget.PercentageUsed, float64(budget.ActualExpense)/float64(budget.Amount)*100)
 			}
 		}
 	})
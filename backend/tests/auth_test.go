@@ -0,0 +1,309 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"personal-finance/handlers"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAuthRouter() (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	db := setupTestDB()
+
+	authHandler := &handlers.AuthHandler{DB: db, SessionTTL: time.Hour, BaseCurrency: "EUR"}
+	r.POST("/auth/register", authHandler.Register)
+	r.POST("/auth/login", authHandler.Login)
+
+	api := r.Group("", middleware.AuthRequired(db))
+	api.POST("/auth/logout", authHandler.Logout)
+	api.GET("/auth/me", authHandler.GetCurrentUser)
+
+	ledgerHandler := &handlers.LedgerHandler{DB: db, BaseCurrency: "EUR"}
+	api.POST("/ledgers", ledgerHandler.CreateLedger)
+	api.GET("/ledgers", ledgerHandler.GetLedgers)
+	api.DELETE("/ledgers/:id", ledgerHandler.DeleteLedger)
+	api.GET("/ledgers/:id/members", ledgerHandler.GetLedgerMembers)
+	api.POST("/ledgers/:id/members", ledgerHandler.AddLedgerMember)
+	api.PUT("/ledgers/:id/members/:user_id", ledgerHandler.UpdateLedgerMember)
+	api.DELETE("/ledgers/:id/members/:user_id", ledgerHandler.RemoveLedgerMember)
+
+	api.Use(middleware.LedgerRequired(db))
+
+	accountHandler := &handlers.AccountHandler{DB: db}
+	api.POST("/accounts", accountHandler.CreateAccount)
+	api.GET("/accounts", accountHandler.GetAccounts)
+	api.PUT("/accounts/:id", accountHandler.UpdateAccount)
+	categoryHandler := &handlers.CategoryHandler{DB: db}
+	api.GET("/categories", categoryHandler.GetCategories)
+	transactionHandler := &handlers.TransactionHandler{DB: db}
+	api.POST("/transactions", transactionHandler.CreateTransaction)
+	api.GET("/transactions", transactionHandler.GetTransactions)
+	api.DELETE("/transactions/:id", transactionHandler.DeleteTransaction)
+	ruleHandler := &handlers.RuleHandler{DB: db}
+	api.POST("/rules", ruleHandler.CreateRule)
+	api.DELETE("/rules/:id", ruleHandler.DeleteRule)
+	statisticsHandler := &handlers.StatisticsHandler{DB: db}
+	api.GET("/statistics", statisticsHandler.GetStatistics)
+	budgetHandler := &handlers.BudgetHandler{DB: db}
+	api.POST("/budgets", budgetHandler.CreateBudget)
+	api.PUT("/budgets/:id", budgetHandler.UpdateBudget)
+	settingHandler := &handlers.SettingHandler{DB: db}
+	api.GET("/settings", settingHandler.GetSettings)
+	api.PUT("/settings", settingHandler.UpdateSettings)
+	rateHandler := &handlers.ExchangeRateHandler{DB: db}
+	api.POST("/exchange-rates", rateHandler.CreateExchangeRate)
+	api.GET("/exchange-rates", rateHandler.GetExchangeRates)
+	api.PUT("/exchange-rates/:id", rateHandler.UpdateExchangeRate)
+	api.DELETE("/exchange-rates/:id", rateHandler.DeleteExchangeRate)
+
+	return r, db
+}
+
+func doAuthJSON(r *gin.Engine, method, path, token string, payload interface{}) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+	req := httptest.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthentication(t *testing.T) {
+	r, db := setupAuthRouter()
+
+	// 启用登录前已有的数据
+	legacy := models.Account{Name: "旧账户", Balance: 10000}
+	db.Create(&legacy)
+	db.Exec("UPDATE settings SET value = 'JPY' WHERE ledger_id = 0 AND name = ?", models.SettingBaseCurrency)
+
+	register := func(username string) string {
+		w := doJSON(r, "POST", "/auth/register", gin.H{"username": username, "password": "s3cret-pass"})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var result struct {
+			Token string `json:"token"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result.Token
+	}
+	alice := register("alice")
+	bob := register("bob")
+
+	t.Run("Register Validation", func(t *testing.T) {
+		w := doJSON(r, "POST", "/auth/register", gin.H{"username": "alice", "password": "another-pass"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w = doJSON(r, "POST", "/auth/register", gin.H{"username": "carol", "password": "short"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var user models.User
+		db.Where("username = ?", "alice").First(&user)
+		assert.NotContains(t, user.PasswordHash, "s3cret-pass")
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		w := doJSON(r, "POST", "/auth/login", gin.H{"username": "alice", "password": "wrong-pass"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = doJSON(r, "POST", "/auth/login", gin.H{"username": "nobody", "password": "s3cret-pass"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = doJSON(r, "POST", "/auth/login", gin.H{"username": "alice", "password": "s3cret-pass"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "token")
+		assert.NotContains(t, w.Body.String(), "password")
+	})
+
+	t.Run("Requires Token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, doAuthJSON(r, "GET", "/accounts", "", nil).Code)
+		assert.Equal(t, http.StatusUnauthorized, doAuthJSON(r, "GET", "/accounts", "invalid", nil).Code)
+
+		w := doAuthJSON(r, "GET", "/auth/me", alice, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "alice")
+	})
+
+	t.Run("Data Isolation", func(t *testing.T) {
+		// 第一个用户接管已有数据，后注册的用户获得默认分类
+		accounts := func(token string) []models.Account {
+			w := doAuthJSON(r, "GET", "/accounts", token, nil)
+			var result struct {
+				Accounts []models.Account `json:"accounts"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &result)
+			return result.Accounts
+		}
+		assert.Len(t, accounts(alice), 1)
+		assert.Len(t, accounts(bob), 0)
+
+		w := doAuthJSON(r, "GET", "/categories?type=expense", bob, nil)
+		var categories []models.Category
+		json.Unmarshal(w.Body.Bytes(), &categories)
+		assert.Len(t, categories, 6)
+
+		w = doAuthJSON(r, "POST", "/accounts", bob, gin.H{"name": "Bob 的钱包", "balance": "50"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var wallet models.Account
+		json.Unmarshal(w.Body.Bytes(), &wallet)
+
+		// 不能访问或引用其他账本的账户
+		w = doAuthJSON(r, "PUT", fmt.Sprintf("/accounts/%d", legacy.ID), bob, gin.H{"name": "stolen"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		// 路径中的编号不能拼接进 SQL 条件
+		for _, id := range []string{"0)%20OR%20(1=1", "abc"} {
+			w = doAuthJSON(r, "PUT", "/accounts/"+id, bob, gin.H{"name": "stolen"})
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		}
+		assert.Len(t, accounts(alice), 1)
+		assert.Equal(t, "旧账户", accounts(alice)[0].Name)
+		w = doAuthJSON(r, "POST", "/transactions", bob, gin.H{
+			"account_id": legacy.ID, "amount": "1", "type": "expense", "category_id": categories[0].ID,
+		})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		// 不能使用其他账本的分类
+		var bobCategory models.Category
+		db.First(&bobCategory, categories[0].ID)
+		var aliceCategory models.Category
+		db.Where("ledger_id <> ? AND type = ?", bobCategory.LedgerID, "expense").First(&aliceCategory)
+		w = doAuthJSON(r, "POST", "/transactions", bob, gin.H{
+			"account_id": wallet.ID, "amount": "1", "type": "expense", "category_id": aliceCategory.ID,
+		})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = doAuthJSON(r, "POST", "/transactions", bob, gin.H{
+			"account_id": wallet.ID, "amount": "20", "type": "expense", "category_id": categories[0].ID,
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var created struct {
+			Transaction models.Transaction `json:"transaction"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &created)
+
+		w = doAuthJSON(r, "GET", "/transactions", alice, nil)
+		var page transactionPage
+		json.Unmarshal(w.Body.Bytes(), &page)
+		assert.Equal(t, int64(0), page.Total)
+
+		w = doAuthJSON(r, "DELETE", fmt.Sprintf("/transactions/%d", created.Transaction.ID), alice, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = doAuthJSON(r, "DELETE", "/transactions/0)%20OR%20(1=1", alice, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doAuthJSON(r, "POST", "/rules", alice, gin.H{
+			"name": "咖啡", "description_contains": "coffee", "category_id": aliceCategory.ID,
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		for _, id := range []string{"0)%20OR%20(1=1", "abc"} {
+			w = doAuthJSON(r, "DELETE", "/rules/"+id, bob, nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		}
+		var rules int
+		db.Model(&models.Rule{}).Count(&rules)
+		assert.Equal(t, 1, rules)
+		var remaining int
+		db.Model(&models.Transaction{}).Count(&remaining)
+		assert.Equal(t, 1, remaining)
+
+		w = doAuthJSON(r, "GET", "/statistics?start_date=2000-01-01&end_date=2100-01-01", alice, nil)
+		var stats models.Statistics
+		json.Unmarshal(w.Body.Bytes(), &stats)
+		assert.Equal(t, "0.00", stats.TotalExpense.String())
+	})
+
+	t.Run("Settings Isolation", func(t *testing.T) {
+		settings := func(token string) string {
+			var result struct {
+				BaseCurrency string `json:"base_currency"`
+			}
+			json.Unmarshal(doAuthJSON(r, "GET", "/settings", token, nil).Body.Bytes(), &result)
+			return result.BaseCurrency
+		}
+		// 首个用户接管旧的本位币，之后的用户和新账本使用配置的默认本位币
+		assert.Equal(t, "JPY", settings(alice))
+		assert.Equal(t, "EUR", settings(bob))
+		w := doAuthJSON(r, "POST", "/ledgers", bob, gin.H{"name": "旅行"})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var ledger models.Ledger
+		json.Unmarshal(w.Body.Bytes(), &ledger)
+		var seeded models.Setting
+		assert.NoError(t, db.Where("ledger_id = ? AND name = ?", ledger.ID, models.SettingBaseCurrency).First(&seeded).Error)
+		assert.Equal(t, "EUR", seeded.Value)
+
+		// 本位币和汇率按账本设置，修改不影响其他账本
+		w = doAuthJSON(r, "PUT", "/settings", bob, gin.H{"base_currency": "USD"})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "USD", settings(bob))
+		assert.Equal(t, "JPY", settings(alice))
+
+		w = doAuthJSON(r, "POST", "/exchange-rates", alice, gin.H{"currency": "USD", "quote_currency": "CNY", "rate": 7.0, "date": "2025-01-01"})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var rate models.ExchangeRate
+		json.Unmarshal(w.Body.Bytes(), &rate)
+
+		var rates []models.ExchangeRate
+		json.Unmarshal(doAuthJSON(r, "GET", "/exchange-rates", bob, nil).Body.Bytes(), &rates)
+		assert.Len(t, rates, 0)
+		path := fmt.Sprintf("/exchange-rates/%d", rate.ID)
+		w = doAuthJSON(r, "PUT", path, bob, gin.H{"currency": "USD", "quote_currency": "CNY", "rate": 1.0, "date": "2025-01-01"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		doAuthJSON(r, "DELETE", path, bob, nil)
+		json.Unmarshal(doAuthJSON(r, "GET", "/exchange-rates", alice, nil).Body.Bytes(), &rates)
+		if assert.Len(t, rates, 1) {
+			assert.Equal(t, 7.0, rates[0].Rate)
+		}
+	})
+
+	t.Run("Budget Isolation", func(t *testing.T) {
+		createBudget := func(token string) models.Budget {
+			var categories []models.Category
+			json.Unmarshal(doAuthJSON(r, "GET", "/categories?type=expense", token, nil).Body.Bytes(), &categories)
+			w := doAuthJSON(r, "POST", "/budgets", token, gin.H{
+				"category_id": categories[0].ID, "amount": "500", "start_date": "2025-01-01", "end_date": "2025-01-31",
+			})
+			assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			var budget models.Budget
+			json.Unmarshal(w.Body.Bytes(), &budget)
+			return budget
+		}
+		victim := createBudget(alice)
+		own := createBudget(bob)
+		var before models.Budget
+		db.First(&before, victim.ID)
+
+		// 请求体中的编号不能指向其他账本的预算
+		w := doAuthJSON(r, "PUT", fmt.Sprintf("/budgets/%d", own.ID), bob, gin.H{
+			"ID": victim.ID, "id": victim.ID, "category_id": own.CategoryID, "amount": "1",
+			"start_date": "2025-01-01", "end_date": "2025-01-31",
+		})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var after models.Budget
+		db.First(&after, victim.ID)
+		assert.Equal(t, before.LedgerID, after.LedgerID)
+		assert.Equal(t, "500.00", after.Amount.String())
+		assert.Equal(t, before.CategoryID, after.CategoryID)
+		var updated models.Budget
+		db.First(&updated, own.ID)
+		assert.Equal(t, "1.00", updated.Amount.String())
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, doAuthJSON(r, "POST", "/auth/logout", bob, nil).Code)
+		assert.Equal(t, http.StatusUnauthorized, doAuthJSON(r, "GET", "/accounts", bob, nil).Code)
+	})
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalanceIntegrity(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.AccountHandler{DB: db}
+	r.POST("/accounts", h.CreateAccount)
+	r.PUT("/accounts/:id", h.UpdateAccount)
+	r.PUT("/accounts/:id/opening-balance", h.SetOpeningBalance)
+	r.GET("/accounts/integrity", h.CheckBalances)
+	r.POST("/accounts/integrity/repair", h.RepairBalances)
+	r.GET("/accounts/integrity/audits", h.GetBalanceAudits)
+
+	create := func(name, balance string) models.Account {
+		w := doJSON(r, "POST", "/accounts", gin.H{"name": name, "balance": balance})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var account models.Account
+		json.Unmarshal(w.Body.Bytes(), &account)
+		return account
+	}
+	bank := create("银行卡", "1000")
+	cash := create("现金", "0")
+	assert.Equal(t, "1000.00", bank.OpeningBalance.String())
+
+	dining := models.Category{Name: "餐饮", Type: "expense"}
+	db.Create(&dining)
+	w := doJSON(r, "POST", "/transactions", gin.H{
+		"account_id": bank.ID, "amount": "100", "type": "expense", "category_id": dining.ID, "date": "2025-01-05",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = doJSON(r, "POST", "/transfers", gin.H{
+		"from_account_id": bank.ID, "to_account_id": cash.ID, "amount": "200", "date": "2025-01-06",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	type check struct {
+		Consistent    bool                          `json:"consistent"`
+		Repaired      int                           `json:"repaired"`
+		Discrepancies []handlers.BalanceDiscrepancy `json:"discrepancies"`
+	}
+	run := func(method, path string) check {
+		w := doJSON(r, method, path, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result check
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+
+	t.Run("Consistent", func(t *testing.T) {
+		assert.True(t, run("GET", "/accounts/integrity").Consistent)
+
+		// 不能直接修改余额，修改期初余额时余额随之变动
+		w := doJSON(r, "PUT", fmt.Sprintf("/accounts/%d", bank.ID), gin.H{"name": "银行卡", "balance": "750"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "PUT", fmt.Sprintf("/accounts/%d", bank.ID), gin.H{"name": "工资卡", "balance": "700"})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = doJSON(r, "PUT", fmt.Sprintf("/accounts/%d/opening-balance", bank.ID), gin.H{"opening_balance": "1050"})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var updated models.Account
+		json.Unmarshal(w.Body.Bytes(), &updated)
+		assert.Equal(t, "1050.00", updated.OpeningBalance.String())
+		assert.Equal(t, "750.00", updated.Balance.String())
+		assert.Equal(t, "工资卡", updated.Name)
+		assert.True(t, run("GET", "/accounts/integrity").Consistent)
+	})
+
+	t.Run("Detect And Repair", func(t *testing.T) {
+		db.Model(&models.Account{}).Where("id = ?", cash.ID).UpdateColumn("balance", 15000)
+
+		result := run("GET", "/accounts/integrity")
+		assert.False(t, result.Consistent)
+		if assert.Len(t, result.Discrepancies, 1) {
+			assert.Equal(t, cash.ID, result.Discrepancies[0].AccountID)
+			assert.Equal(t, "200.00", result.Discrepancies[0].ComputedBalance.String())
+			assert.Equal(t, "-50.00", result.Discrepancies[0].Difference.String())
+		}
+		startup, err := handlers.VerifyBalances(db, false)
+		assert.NoError(t, err)
+		assert.Len(t, startup, 1)
+
+		assert.Equal(t, 1, run("POST", "/accounts/integrity/repair").Repaired)
+		assert.Equal(t, "200.00", balanceOf(db, cash.ID))
+		assert.True(t, run("GET", "/accounts/integrity").Consistent)
+
+		var audits []models.BalanceAudit
+		json.Unmarshal(doJSON(r, "GET", "/accounts/integrity/audits", nil).Body.Bytes(), &audits)
+		if assert.Len(t, audits, 1) {
+			assert.Equal(t, "150.00", audits[0].OldBalance.String())
+			assert.Equal(t, "200.00", audits[0].NewBalance.String())
+			assert.Equal(t, "api", audits[0].Source)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalanceHistory(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.AccountHandler{DB: db}
+	r.POST("/accounts", h.CreateAccount)
+	r.GET("/accounts/balance-history", h.GetBalanceHistory)
+
+	create := func(payload gin.H) models.Account {
+		w := doJSON(r, "POST", "/accounts", payload)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var account models.Account
+		json.Unmarshal(w.Body.Bytes(), &account)
+		return account
+	}
+	bank := create(gin.H{"name": "工资卡", "balance": "1000"})
+	card := create(gin.H{"name": "信用卡", "balance": "0", "type": "credit_card"})
+	create(gin.H{"name": "美元账户", "balance": "100", "currency": "USD"})
+	db.Create(&models.ExchangeRate{Currency: "USD", QuoteCurrency: "CNY", Rate: 7, Date: "2020-01-01"})
+
+	salary := models.Category{Name: "工资", Type: "income"}
+	db.Create(&salary)
+	shopping := models.Category{Name: "购物", Type: "expense"}
+	db.Create(&shopping)
+
+	// 较早的大量小额支出，查询时会为其写入月末快照
+	for i := 0; i < 120; i++ {
+		db.Create(&models.Transaction{
+			AccountID: bank.ID, Amount: 100, Type: "expense", CategoryID: shopping.ID,
+			Date: fmt.Sprintf("2023-%02d-%02d", i%12+1, i%28+1),
+		})
+	}
+	db.Model(&models.Account{}).Where("id = ?", bank.ID).UpdateColumn("balance", 88000)
+
+	record := func(payload gin.H) {
+		path := "/transactions"
+		if payload["from_account_id"] != nil {
+			path = "/transfers"
+		}
+		w := doJSON(r, "POST", path, payload)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	record(gin.H{"account_id": bank.ID, "amount": "500", "type": "income", "category_id": salary.ID, "date": "2024-01-15"})
+	record(gin.H{"account_id": card.ID, "amount": "200", "type": "expense", "category_id": shopping.ID, "date": "2024-02-10"})
+	record(gin.H{"from_account_id": bank.ID, "to_account_id": card.ID, "amount": "200", "date": "2024-03-05"})
+
+	type history struct {
+		Dates    []string `json:"dates"`
+		Accounts []struct {
+			AccountID uint           `json:"account_id"`
+			Balances  []models.Money `json:"balances"`
+		} `json:"accounts"`
+		Total []models.Money `json:"total"`
+	}
+	get := func(query string) history {
+		w := doJSON(r, "GET", "/accounts/balance-history?"+query, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result history
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+	texts := func(values []models.Money) []string {
+		var result []string
+		for _, value := range values {
+			result = append(result, value.String())
+		}
+		return result
+	}
+
+	t.Run("Monthly", func(t *testing.T) {
+		result := get("interval=monthly&start_date=2024-01-01&end_date=2024-03-20")
+		assert.Equal(t, []string{"2024-01-31", "2024-02-29", "2024-03-20"}, result.Dates)
+		if assert.Len(t, result.Accounts, 3) {
+			assert.Equal(t, []string{"1380.00", "1380.00", "1180.00"}, texts(result.Accounts[0].Balances))
+			assert.Equal(t, []string{"0.00", "-200.00", "0.00"}, texts(result.Accounts[1].Balances))
+		}
+		assert.Equal(t, []string{"2080.00", "1880.00", "1880.00"}, texts(result.Total))
+
+		var snapshots int
+		db.Model(&models.BalanceSnapshot{}).Where("account_id = ?", bank.ID).Count(&snapshots)
+		assert.Equal(t, 14, snapshots)
+	})
+
+	t.Run("Weekly And Daily", func(t *testing.T) {
+		result := get("interval=weekly&start_date=2024-03-01&end_date=2024-03-12")
+		assert.Equal(t, []string{"2024-03-03", "2024-03-10", "2024-03-12"}, result.Dates)
+		assert.Equal(t, []string{"1380.00", "1180.00", "1180.00"}, texts(result.Accounts[0].Balances))
+
+		result = get(fmt.Sprintf("interval=daily&start_date=2024-03-04&end_date=2024-03-06&account_id=%d", card.ID))
+		assert.Len(t, result.Dates, 3)
+		if assert.Len(t, result.Accounts, 1) {
+			assert.Equal(t, []string{"-200.00", "0.00", "0.00"}, texts(result.Accounts[0].Balances))
+		}
+	})
+
+	t.Run("Snapshots Invalidated", func(t *testing.T) {
+		record(gin.H{"account_id": bank.ID, "amount": "10", "type": "expense", "category_id": shopping.ID, "date": "2023-12-15"})
+		var snapshots int
+		db.Model(&models.BalanceSnapshot{}).Where("account_id = ?", bank.ID).Count(&snapshots)
+		assert.Equal(t, 11, snapshots)
+
+		result := get("interval=monthly&start_date=2024-01-01&end_date=2024-03-20")
+		assert.Equal(t, []string{"1370.00", "1370.00", "1170.00"}, texts(result.Accounts[0].Balances))
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		w := doJSON(r, "GET", "/accounts/balance-history?interval=yearly", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "GET", "/accounts/balance-history?interval=daily&start_date=2000-01-01&end_date=2024-01-01", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
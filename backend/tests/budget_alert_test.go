@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"personal-finance/notifier"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// startFakeSMTP 启动一个只接收邮件的本地 SMTP 服务器，收到的邮件原文写入返回的通道
+func startFakeSMTP(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, messages)
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func serveSMTP(conn net.Conn, messages chan<- string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch command := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(command, "DATA"):
+			fmt.Fprint(conn, "354 End data with <CR><LF>.<CR><LF>\r\n")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			messages <- data.String()
+			fmt.Fprint(conn, "250 OK\r\n")
+		case strings.HasPrefix(command, "QUIT"):
+			fmt.Fprint(conn, "221 Bye\r\n")
+			return
+		default:
+			fmt.Fprint(conn, "250 OK\r\n")
+		}
+	}
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case value := <-ch:
+		return value
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for notification")
+		var zero T
+		return zero
+	}
+}
+
+func TestBudgetAlerts(t *testing.T) {
+	smtpAddr, emails := startFakeSMTP(t)
+	webhooks := make(chan notifier.Alert, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert notifier.Alert
+		json.NewDecoder(r.Body).Decode(&alert)
+		webhooks <- alert
+	}))
+	defer server.Close()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	db := setupTestDB()
+	alerts := &handlers.BudgetAlerter{DB: db, Notifier: notifier.Multi{
+		&notifier.SMTPNotifier{Addr: smtpAddr, From: "finance@example.com", To: []string{"me@example.com"}},
+		&notifier.WebhookNotifier{URL: server.URL},
+	}}
+	transactionHandler := &handlers.TransactionHandler{DB: db, Alerts: alerts}
+	r.POST("/transactions", transactionHandler.CreateTransaction)
+	r.PUT("/transactions/:id", transactionHandler.UpdateTransaction)
+	budgetHandler := &handlers.BudgetHandler{DB: db}
+	r.POST("/budgets", budgetHandler.CreateBudget)
+	r.GET("/budgets/:id/alerts", budgetHandler.GetBudgetAlerts)
+
+	account := models.Account{Name: "银行卡", Balance: 100000}
+	db.Create(&account)
+	dining := models.Category{Name: "餐饮", Type: "expense"}
+	db.Create(&dining)
+
+	t.Run("Validation", func(t *testing.T) {
+		w := doJSON(r, "POST", "/budgets", gin.H{
+			"category_id": dining.ID, "amount": "100", "period": "monthly", "start_date": "2024-01-01",
+			"thresholds": []int{80, 0},
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	w := doJSON(r, "POST", "/budgets", gin.H{
+		"category_id": dining.ID, "amount": "100", "period": "monthly", "start_date": "2024-01-01",
+		"thresholds": []int{100, 50, 80, 50},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var budget models.Budget
+	json.Unmarshal(w.Body.Bytes(), &budget)
+	assert.Equal(t, models.IntList{50, 80, 100}, budget.Thresholds)
+
+	expense := func(amount, date string) models.Transaction {
+		w := doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": account.ID, "amount": amount, "type": "expense", "category_id": dining.ID, "date": date,
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created struct {
+			Transaction models.Transaction `json:"transaction"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &created)
+		return created.Transaction
+	}
+
+	t.Run("Fires Once Per Threshold", func(t *testing.T) {
+		expense("40", "2024-01-05")
+		lunch := expense("20", "2024-01-10")
+
+		email, err := mail.ReadMessage(strings.NewReader(receive(t, emails)))
+		if assert.NoError(t, err) {
+			subject, _ := new(mime.WordDecoder).DecodeHeader(email.Header.Get("Subject"))
+			assert.Equal(t, "预算提醒：餐饮已使用 60%", subject)
+			body, _ := io.ReadAll(email.Body)
+			assert.NotEmpty(t, body)
+		}
+		alert := receive(t, webhooks)
+		assert.Equal(t, 50, alert.Threshold)
+		assert.Equal(t, "2024-01-31", alert.PeriodEnd)
+
+		// 仍在 50% 和 80% 之间，不再提醒；修改交易越过 80% 后只提醒 80%
+		expense("5", "2024-01-12")
+		w := doJSON(r, "PUT", fmt.Sprintf("/transactions/%d", lunch.ID), gin.H{
+			"account_id": account.ID, "amount": "50", "type": "expense", "category_id": dining.ID, "date": "2024-01-10",
+		})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		receive(t, emails)
+		alert = receive(t, webhooks)
+		assert.Equal(t, 80, alert.Threshold)
+		assert.Equal(t, "95.00", alert.ActualExpense.String())
+	})
+
+	t.Run("Each Period Starts Over", func(t *testing.T) {
+		expense("120", "2024-02-03")
+		var thresholds []int
+		for i := 0; i < 3; i++ {
+			receive(t, emails)
+			thresholds = append(thresholds, receive(t, webhooks).Threshold)
+		}
+		assert.Equal(t, []int{50, 80, 100}, thresholds)
+		assert.Empty(t, webhooks)
+
+		var fired []models.BudgetAlert
+		w := doJSON(r, "GET", fmt.Sprintf("/budgets/%d/alerts", budget.ID), nil)
+		json.Unmarshal(w.Body.Bytes(), &fired)
+		if assert.Len(t, fired, 5) {
+			assert.Equal(t, "2024-02-01", fired[0].PeriodStart)
+			assert.Equal(t, 100, fired[0].Threshold)
+		}
+		assert.Eventually(t, func() bool {
+			var unsent int
+			db.Model(&models.BudgetAlert{}).Where("sent_at IS NULL").Count(&unsent)
+			return unsent == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecurringBudgets(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.BudgetHandler{DB: db}
+	r.POST("/budgets", h.CreateBudget)
+	r.GET("/budgets/:id/status", h.GetBudgetStatus)
+	r.GET("/budgets/:id/history", h.GetBudgetHistory)
+	statisticsHandler := &handlers.StatisticsHandler{DB: db}
+	r.GET("/statistics/budget-overview", statisticsHandler.GetBudgetOverview)
+
+	account := models.Account{Name: "银行卡", Balance: 1000000}
+	db.Create(&account)
+	dining := models.Category{Name: "餐饮", Type: "expense"}
+	db.Create(&dining)
+
+	// 从三个月前的 1 日开始的月度预算，每月各记一笔支出
+	now := time.Now()
+	first := time.Date(now.Year(), now.Month()-3, 1, 0, 0, 0, 0, time.UTC)
+	for i, amount := range []string{"100", "250", "80", "40"} {
+		w := doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": account.ID, "amount": amount, "type": "expense", "category_id": dining.ID,
+			"date": first.AddDate(0, i, 0).Format("2006-01-02"),
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		w := doJSON(r, "POST", "/budgets", gin.H{
+			"category_id": dining.ID, "amount": "200", "period": "daily", "start_date": "2024-01-01",
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "POST", "/budgets", gin.H{
+			"category_id": dining.ID, "amount": "200", "start_date": "2024-01-01",
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	w := doJSON(r, "POST", "/budgets", gin.H{
+		"category_id": dining.ID, "amount": "200", "period": "monthly", "start_date": first.Format("2006-01-02"),
+	})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var budget models.Budget
+	json.Unmarshal(w.Body.Bytes(), &budget)
+
+	t.Run("Status Of Period", func(t *testing.T) {
+		var result struct {
+			Period struct {
+				StartDate string `json:"start_date"`
+				EndDate   string `json:"end_date"`
+			} `json:"period"`
+			Status struct {
+				ActualExpense models.Money `json:"actual_expense"`
+			} `json:"status"`
+		}
+		second := first.AddDate(0, 1, 0)
+		w := doJSON(r, "GET", fmt.Sprintf("/budgets/%d/status?date=%s", budget.ID, second.AddDate(0, 0, 9).Format("2006-01-02")), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &result)
+		assert.Equal(t, second.Format("2006-01-02"), result.Period.StartDate)
+		assert.Equal(t, second.AddDate(0, 1, -1).Format("2006-01-02"), result.Period.EndDate)
+		assert.Equal(t, "250.00", result.Status.ActualExpense.String())
+
+		// 默认统计当前周期
+		w = doJSON(r, "GET", fmt.Sprintf("/budgets/%d/status", budget.ID), nil)
+		json.Unmarshal(w.Body.Bytes(), &result)
+		assert.Equal(t, "40.00", result.Status.ActualExpense.String())
+	})
+
+	t.Run("History", func(t *testing.T) {
+		var result struct {
+			Periods []struct {
+				StartDate     string       `json:"start_date"`
+				Planned       models.Money `json:"planned"`
+				ActualExpense models.Money `json:"actual_expense"`
+				Remaining     models.Money `json:"remaining"`
+			} `json:"periods"`
+		}
+		w := doJSON(r, "GET", fmt.Sprintf("/budgets/%d/history", budget.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &result)
+		if assert.Len(t, result.Periods, 4) {
+			assert.Equal(t, first.AddDate(0, 3, 0).Format("2006-01-02"), result.Periods[0].StartDate)
+			assert.Equal(t, "40.00", result.Periods[0].ActualExpense.String())
+			assert.Equal(t, "200.00", result.Periods[2].Planned.String())
+			assert.Equal(t, "-50.00", result.Periods[2].Remaining.String())
+			assert.Equal(t, first.Format("2006-01-02"), result.Periods[3].StartDate)
+		}
+
+		w = doJSON(r, "GET", fmt.Sprintf("/budgets/%d/history?periods=2", budget.ID), nil)
+		json.Unmarshal(w.Body.Bytes(), &result)
+		assert.Len(t, result.Periods, 2)
+
+		w = doJSON(r, "GET", fmt.Sprintf("/budgets/%d/history?periods=0", budget.ID), nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Overview Uses Current Period", func(t *testing.T) {
+		w := doJSON(r, "GET", "/statistics/budget-overview", nil)
+		var result struct {
+			Budgets []struct {
+				ID            uint         `json:"ID"`
+				PeriodStart   string       `json:"period_start"`
+				ActualExpense models.Money `json:"actual_expense"`
+			} `json:"budgets"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &result)
+		if assert.Len(t, result.Budgets, 1) {
+			assert.Equal(t, first.AddDate(0, 3, 0).Format("2006-01-02"), result.Budgets[0].PeriodStart)
+			assert.Equal(t, "40.00", result.Budgets[0].ActualExpense.String())
+		}
+	})
+
+	t.Run("Weekly Periods End With Budget", func(t *testing.T) {
+		w := doJSON(r, "POST", "/budgets", gin.H{
+			"category_id": dining.ID, "amount": "50", "period": "weekly",
+			"start_date": "2024-03-04", "end_date": "2024-03-20",
+		})
+		var weekly models.Budget
+		json.Unmarshal(w.Body.Bytes(), &weekly)
+
+		var result struct {
+			Periods []struct {
+				StartDate string `json:"start_date"`
+				EndDate   string `json:"end_date"`
+			} `json:"periods"`
+		}
+		w = doJSON(r, "GET", fmt.Sprintf("/budgets/%d/history", weekly.ID), nil)
+		json.Unmarshal(w.Body.Bytes(), &result)
+		if assert.Len(t, result.Periods, 3) {
+			assert.Equal(t, "2024-03-18", result.Periods[0].StartDate)
+			assert.Equal(t, "2024-03-20", result.Periods[0].EndDate)
+			assert.Equal(t, "2024-03-11", result.Periods[1].StartDate)
+			assert.Equal(t, "2024-03-17", result.Periods[1].EndDate)
+		}
+	})
+}
+
+func TestBudgetRollover(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.BudgetHandler{DB: db}
+	r.POST("/budgets", h.CreateBudget)
+	r.GET("/budgets/:id/status", h.GetBudgetStatus)
+	r.GET("/budgets/:id/history", h.GetBudgetHistory)
+	statisticsHandler := &handlers.StatisticsHandler{DB: db}
+	r.GET("/statistics/budget-overview", statisticsHandler.GetBudgetOverview)
+
+	account := models.Account{Name: "银行卡", Balance: 1000000}
+	db.Create(&account)
+	dining := models.Category{Name: "餐饮", Type: "expense"}
+	db.Create(&dining)
+
+	now := time.Now()
+	first := time.Date(now.Year(), now.Month()-3, 1, 0, 0, 0, 0, time.UTC)
+	for i, amount := range []string{"100", "250", "80", "40"} {
+		doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": account.ID, "amount": amount, "type": "expense", "category_id": dining.ID,
+			"date": first.AddDate(0, i, 0).Format("2006-01-02"),
+		})
+	}
+
+	createBudget := func(rollover string) models.Budget {
+		w := doJSON(r, "POST", "/budgets", gin.H{
+			"category_id": dining.ID, "amount": "200", "period": "monthly", "rollover": rollover,
+			"start_date": first.Format("2006-01-02"),
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var budget models.Budget
+		json.Unmarshal(w.Body.Bytes(), &budget)
+		return budget
+	}
+	type periodStatus struct {
+		CarriedOver   models.Money `json:"carried_over"`
+		Available     models.Money `json:"available"`
+		ActualExpense models.Money `json:"actual_expense"`
+		Remaining     models.Money `json:"remaining"`
+	}
+	history := func(budget models.Budget) []periodStatus {
+		w := doJSON(r, "GET", fmt.Sprintf("/budgets/%d/history", budget.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result struct {
+			Periods []periodStatus `json:"periods"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result.Periods
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		w := doJSON(r, "POST", "/budgets", gin.H{
+			"category_id": dining.ID, "amount": "200", "rollover": "surplus",
+			"start_date": "2024-01-01", "end_date": "2024-01-31",
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "POST", "/budgets", gin.H{
+			"category_id": dining.ID, "amount": "200", "period": "monthly", "rollover": "all",
+			"start_date": "2024-01-01",
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Carry Surplus", func(t *testing.T) {
+		periods := history(createBudget("surplus"))
+		if assert.Len(t, periods, 4) {
+			// 最近的周期在前：100 → 50 → 170 依次结转
+			assert.Equal(t, "170.00", periods[0].CarriedOver.String())
+			assert.Equal(t, "370.00", periods[0].Available.String())
+			assert.Equal(t, "330.00", periods[0].Remaining.String())
+			assert.Equal(t, "300.00", periods[2].Available.String())
+			assert.Equal(t, "0.00", periods[3].CarriedOver.String())
+		}
+	})
+
+	t.Run("Carry Deficit", func(t *testing.T) {
+		budget := createBudget("deficit")
+		periods := history(budget)
+		if assert.Len(t, periods, 4) {
+			assert.Equal(t, "0.00", periods[0].CarriedOver.String())
+			assert.Equal(t, "-50.00", periods[1].CarriedOver.String())
+			assert.Equal(t, "150.00", periods[1].Available.String())
+			assert.Equal(t, "0.00", periods[2].CarriedOver.String())
+		}
+
+		var result struct {
+			Status periodStatus `json:"status"`
+		}
+		w := doJSON(r, "GET", fmt.Sprintf("/budgets/%d/status?date=%s", budget.ID, first.AddDate(0, 2, 3).Format("2006-01-02")), nil)
+		json.Unmarshal(w.Body.Bytes(), &result)
+		assert.Equal(t, "150.00", result.Status.Available.String())
+		assert.Equal(t, "70.00", result.Status.Remaining.String())
+	})
+
+	t.Run("Overview Includes Rollover", func(t *testing.T) {
+		w := doJSON(r, "GET", "/statistics/budget-overview", nil)
+		var result struct {
+			Budgets []struct {
+				Rollover string `json:"rollover"`
+				periodStatus
+			} `json:"budgets"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &result)
+		available := make(map[string]string)
+		for _, budget := range result.Budgets {
+			available[budget.Rollover] = budget.Available.String()
+		}
+		assert.Equal(t, map[string]string{"surplus": "370.00", "deficit": "200.00"}, available)
+	})
+}
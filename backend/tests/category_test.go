@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryHierarchy(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.CategoryHandler{DB: db}
+	r.POST("/categories", h.CreateCategory)
+	r.GET("/categories", h.GetCategories)
+	r.PUT("/categories/:id", h.UpdateCategory)
+	r.DELETE("/categories/:id", h.DeleteCategory)
+	budgetHandler := &handlers.BudgetHandler{DB: db}
+	r.GET("/budgets/:id/status", budgetHandler.GetBudgetStatus)
+
+	create := func(payload gin.H) models.Category {
+		w := doJSON(r, "POST", "/categories", payload)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var category models.Category
+		json.Unmarshal(w.Body.Bytes(), &category)
+		return category
+	}
+
+	food := create(gin.H{"name": "餐饮", "type": "expense"})
+	lunch := create(gin.H{"name": "午餐", "type": "expense", "parent_id": food.ID})
+	takeout := create(gin.H{"name": "外卖", "type": "expense", "parent_id": lunch.ID})
+	salary := create(gin.H{"name": "工资", "type": "income"})
+
+	t.Run("Validation", func(t *testing.T) {
+		w := doJSON(r, "POST", "/categories", gin.H{"name": "奖金", "type": "income", "parent_id": food.ID})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doJSON(r, "POST", "/categories", gin.H{"name": "早餐", "type": "expense", "parent_id": 999})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		// 把上级挂到自己的下级下会形成循环
+		w = doJSON(r, "PUT", fmt.Sprintf("/categories/%d", food.ID), gin.H{"name": "餐饮", "type": "expense", "parent_id": takeout.ID})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "PUT", fmt.Sprintf("/categories/%d", food.ID), gin.H{"name": "餐饮", "type": "expense", "parent_id": food.ID})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// 有下级时不能修改类型或删除
+		w = doJSON(r, "PUT", fmt.Sprintf("/categories/%d", food.ID), gin.H{"name": "餐饮", "type": "income"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "DELETE", fmt.Sprintf("/categories/%d", lunch.ID), nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Tree", func(t *testing.T) {
+		w := doJSON(r, "GET", "/categories?tree=true", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var tree []models.Category
+		json.Unmarshal(w.Body.Bytes(), &tree)
+		assert.Len(t, tree, 2)
+		assert.Equal(t, "餐饮", tree[0].Name)
+		assert.Equal(t, "午餐", tree[0].Children[0].Name)
+		assert.Equal(t, "外卖", tree[0].Children[0].Children[0].Name)
+
+		w = doJSON(r, "GET", "/categories", nil)
+		var flat []models.Category
+		json.Unmarshal(w.Body.Bytes(), &flat)
+		assert.Len(t, flat, 4)
+	})
+
+	t.Run("Rollup", func(t *testing.T) {
+		bank := models.Account{Name: "银行卡", Balance: 100000}
+		db.Create(&bank)
+		for _, tx := range []gin.H{
+			{"account_id": bank.ID, "amount": "10", "type": "expense", "category_id": food.ID, "date": "2025-03-01"},
+			{"account_id": bank.ID, "amount": "20", "type": "expense", "category_id": lunch.ID, "date": "2025-03-02"},
+			{"account_id": bank.ID, "amount": "30", "type": "expense", "category_id": takeout.ID, "date": "2025-03-03"},
+			{"account_id": bank.ID, "amount": "100", "type": "income", "category_id": salary.ID, "date": "2025-03-04"},
+		} {
+			assert.Equal(t, http.StatusCreated, doJSON(r, "POST", "/transactions", tx).Code)
+		}
+
+		amounts := func(query string) map[uint]string {
+			w := doJSON(r, "GET", "/statistics?start_date=2025-03-01&end_date=2025-03-31"+query, nil)
+			var stats models.Statistics
+			json.Unmarshal(w.Body.Bytes(), &stats)
+			result := make(map[uint]string)
+			for _, stat := range stats.ByCategory {
+				result[stat.CategoryID] = stat.Amount.String()
+			}
+			return result
+		}
+		assert.Equal(t, map[uint]string{food.ID: "10.00", lunch.ID: "20.00", takeout.ID: "30.00", salary.ID: "100.00"}, amounts(""))
+		assert.Equal(t, map[uint]string{food.ID: "60.00", lunch.ID: "50.00", takeout.ID: "30.00", salary.ID: "100.00"}, amounts("&rollup=true"))
+
+		budget := models.Budget{CategoryID: food.ID, Amount: 10000, StartDate: "2025-03-01", EndDate: "2025-03-31"}
+		db.Create(&budget)
+		status := func(query string) string {
+			w := doJSON(r, "GET", fmt.Sprintf("/budgets/%d/status%s", budget.ID, query), nil)
+			var result struct {
+				Status struct {
+					ActualExpense models.Money `json:"actual_expense"`
+				} `json:"status"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &result)
+			return result.Status.ActualExpense.String()
+		}
+		assert.Equal(t, "10.00", status(""))
+		assert.Equal(t, "60.00", status("?rollup=true"))
+	})
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiCurrency(t *testing.T) {
+	r, db := setupTransactionRouter()
+
+	accountHandler := &handlers.AccountHandler{DB: db}
+	rateHandler := &handlers.ExchangeRateHandler{DB: db}
+	r.POST("/accounts", accountHandler.CreateAccount)
+	r.GET("/accounts", accountHandler.GetAccounts)
+	r.POST("/exchange-rates", rateHandler.CreateExchangeRate)
+
+	var cny, usd models.Account
+	w := doJSON(r, "POST", "/accounts", gin.H{"name": "招行", "balance": "1000"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &cny)
+	assert.Equal(t, "CNY", cny.Currency)
+
+	w = doJSON(r, "POST", "/accounts", gin.H{"name": "美元户", "balance": "100", "currency": "usd"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &usd)
+	assert.Equal(t, "USD", usd.Currency)
+
+	food := models.Category{Name: "餐饮", Type: "expense"}
+	db.Create(&food)
+
+	t.Run("Missing Rate", func(t *testing.T) {
+		w := doJSON(r, "GET", "/accounts", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	doJSON(r, "POST", "/exchange-rates", gin.H{"currency": "USD", "quote_currency": "CNY", "rate": 7.0, "date": "2025-01-01"})
+	doJSON(r, "POST", "/exchange-rates", gin.H{"currency": "USD", "quote_currency": "CNY", "rate": 7.2, "date": "2025-03-01"})
+
+	t.Run("Net Worth In Base Currency", func(t *testing.T) {
+		w := doJSON(r, "GET", "/accounts", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			NetWorth     models.Money `json:"net_worth"`
+			BaseCurrency string       `json:"base_currency"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.Equal(t, "CNY", resp.BaseCurrency)
+		assert.Equal(t, "1720.00", resp.NetWorth.String())
+	})
+
+	t.Run("Statistics Use Rate On Transaction Date", func(t *testing.T) {
+		doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": usd.ID, "amount": "10", "type": "expense", "category_id": food.ID, "date": "2025-02-10",
+		})
+		doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": usd.ID, "amount": "10", "type": "expense", "category_id": food.ID, "date": "2025-03-10",
+		})
+		doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": cny.ID, "amount": "5.5", "type": "expense", "category_id": food.ID, "date": "2025-03-10",
+		})
+
+		w := doJSON(r, "GET", "/statistics?start_date=2025-01-01&end_date=2025-12-31", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var stats models.Statistics
+		json.Unmarshal(w.Body.Bytes(), &stats)
+		assert.Equal(t, "147.50", stats.TotalExpense.String())
+		assert.Len(t, stats.ByMonth, 2)
+		assert.Equal(t, 3, stats.ByMonth[0].Month)
+	})
+
+	t.Run("Cross Currency Transfer", func(t *testing.T) {
+		w := doJSON(r, "POST", "/transfers", gin.H{
+			"from_account_id": cny.ID, "to_account_id": usd.ID, "amount": "72", "date": "2025-03-02",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		// 100 - 10 - 10 + 72/7.2
+		assert.Equal(t, "90.00", balanceOf(db, usd.ID))
+		assert.Equal(t, "922.50", balanceOf(db, cny.ID))
+	})
+}
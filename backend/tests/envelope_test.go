@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"personal-finance/handlers"
+	"personal-finance/middleware"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelopeBudgeting(t *testing.T) {
+	r, db := setupAuthRouter()
+	api := r.Group("", middleware.AuthRequired(db), middleware.LedgerRequired(db))
+	h := &handlers.EnvelopeHandler{DB: db}
+	api.POST("/envelopes/enable", h.EnableEnvelopes)
+	api.GET("/envelopes", h.GetEnvelopes)
+	api.POST("/envelopes/assign", h.AssignEnvelope)
+	api.POST("/envelopes/move", h.MoveEnvelope)
+	api.GET("/envelopes/check", h.CheckEnvelopes)
+	budgetHandler := &handlers.BudgetHandler{DB: db}
+	api.GET("/budgets", budgetHandler.GetBudgets)
+
+	w := doJSON(r, "POST", "/auth/register", gin.H{"username": "alice", "password": "s3cret-pass"})
+	var registered struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &registered)
+	token := registered.Token
+
+	w = doAuthJSON(r, "POST", "/accounts", token, gin.H{"name": "银行卡", "balance": "0"})
+	var account models.Account
+	json.Unmarshal(w.Body.Bytes(), &account)
+	var categories []models.Category
+	json.Unmarshal(doAuthJSON(r, "GET", "/categories", token, nil).Body.Bytes(), &categories)
+	var income models.Category
+	var expenses []models.Category
+	for _, category := range categories {
+		if category.Type == "income" && income.ID == 0 {
+			income = category
+		} else if category.Type == "expense" {
+			expenses = append(expenses, category)
+		}
+	}
+	groceries, rent := expenses[0], expenses[1]
+
+	record := func(amount, kind string, categoryID uint, date string) {
+		w := doAuthJSON(r, "POST", "/transactions", token, gin.H{
+			"account_id": account.ID, "amount": amount, "type": kind, "category_id": categoryID, "date": date,
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	type summary struct {
+		TotalIncome       models.Money `json:"total_income"`
+		Assigned          models.Money `json:"assigned"`
+		ToBeAssigned      models.Money `json:"to_be_assigned"`
+		UnbudgetedExpense models.Money `json:"unbudgeted_expense"`
+		Envelopes         []struct {
+			CategoryID uint         `json:"category_id"`
+			Assigned   models.Money `json:"assigned"`
+			Spent      models.Money `json:"spent"`
+			Balance    models.Money `json:"balance"`
+		} `json:"envelopes"`
+	}
+	decode := func(body []byte) summary {
+		var result summary
+		json.Unmarshal(body, &result)
+		return result
+	}
+
+	t.Run("Requires Enabling", func(t *testing.T) {
+		w := doAuthJSON(r, "GET", "/envelopes", token, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	// 开始日期之前的收入不进入待分配资金
+	record("500", "income", income.ID, "2024-12-20")
+	record("3000", "income", income.ID, "2025-01-01")
+	w = doAuthJSON(r, "POST", "/envelopes/enable", token, gin.H{"start_date": "2025-01-01"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "3000.00", decode(w.Body.Bytes()).ToBeAssigned.String())
+
+	t.Run("Assign", func(t *testing.T) {
+		w := doAuthJSON(r, "POST", "/envelopes/assign", token, gin.H{"category_id": groceries.ID, "amount": "800"})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = doAuthJSON(r, "POST", "/envelopes/assign", token, gin.H{"category_id": rent.ID, "amount": "2000"})
+		result := decode(w.Body.Bytes())
+		assert.Equal(t, "200.00", result.ToBeAssigned.String())
+		assert.Equal(t, "2800.00", result.Assigned.String())
+		assert.Len(t, result.Envelopes, 2)
+
+		// 不能超出待分配资金，也不能用收入分类
+		w = doAuthJSON(r, "POST", "/envelopes/assign", token, gin.H{"category_id": groceries.ID, "amount": "300"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doAuthJSON(r, "POST", "/envelopes/assign", token, gin.H{"category_id": income.ID, "amount": "100"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// 信封不出现在普通预算列表中
+		var budgets []models.Budget
+		json.Unmarshal(doAuthJSON(r, "GET", "/budgets", token, nil).Body.Bytes(), &budgets)
+		assert.Empty(t, budgets)
+	})
+
+	t.Run("Spend And Move", func(t *testing.T) {
+		record("650", "expense", groceries.ID, "2025-01-10")
+		record("40", "expense", expenses[2].ID, "2025-01-11")
+
+		w := doAuthJSON(r, "POST", "/envelopes/move", token, gin.H{"from_category_id": groceries.ID, "to_category_id": rent.ID, "amount": "200"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doAuthJSON(r, "POST", "/envelopes/move", token, gin.H{"from_category_id": rent.ID, "to_category_id": groceries.ID, "amount": "100"})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		result := decode(w.Body.Bytes())
+		balances := make(map[uint]string)
+		for _, envelope := range result.Envelopes {
+			balances[envelope.CategoryID] = envelope.Balance.String()
+		}
+		assert.Equal(t, map[uint]string{groceries.ID: "250.00", rent.ID: "1900.00"}, balances)
+		assert.Equal(t, "40.00", result.UnbudgetedExpense.String())
+		assert.Equal(t, "200.00", result.ToBeAssigned.String())
+
+		// 退回待分配资金
+		w = doAuthJSON(r, "POST", "/envelopes/assign", token, gin.H{"category_id": groceries.ID, "amount": "-50"})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "250.00", decode(w.Body.Bytes()).ToBeAssigned.String())
+	})
+
+	t.Run("Child Categories", func(t *testing.T) {
+		// 子分类的支出计入上级分类的信封
+		var parent models.Category
+		db.First(&parent, groceries.ID)
+		fruit := models.Category{LedgerID: parent.LedgerID, Name: "水果", Type: "expense", ParentID: parent.ID}
+		db.Create(&fruit)
+		record("30", "expense", fruit.ID, "2025-01-12")
+
+		result := decode(doAuthJSON(r, "GET", "/envelopes", token, nil).Body.Bytes())
+		for _, envelope := range result.Envelopes {
+			if envelope.CategoryID == groceries.ID {
+				assert.Equal(t, "680.00", envelope.Spent.String())
+				assert.Equal(t, "170.00", envelope.Balance.String())
+			}
+		}
+		assert.Equal(t, "40.00", result.UnbudgetedExpense.String())
+	})
+
+	t.Run("Plain Budget Update", func(t *testing.T) {
+		w := doAuthJSON(r, "POST", "/budgets", token, gin.H{
+			"category_id": rent.ID, "amount": "1000", "start_date": "2025-01-01", "period": "monthly",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var budget models.Budget
+		json.Unmarshal(w.Body.Bytes(), &budget)
+		path := fmt.Sprintf("/budgets/%d", budget.ID)
+
+		// 修改普通预算不能把它变成信封，也不能写入无效的日期或金额
+		w = doAuthJSON(r, "PUT", path, token, gin.H{
+			"kind": "envelope", "category_id": rent.ID, "amount": "1200", "start_date": "2025-01-01", "period": "monthly",
+		})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		db.First(&budget, budget.ID)
+		assert.Equal(t, "", budget.Kind)
+		assert.Equal(t, "1200.00", budget.Amount.String())
+
+		for _, invalid := range []gin.H{
+			{"category_id": rent.ID, "amount": "1200", "start_date": "garbage", "period": "monthly"},
+			{"category_id": rent.ID, "amount": "1200", "start_date": "2025-01-01", "end_date": "2024-12-31"},
+			{"category_id": rent.ID, "amount": "-5", "start_date": "2025-01-01", "period": "monthly"},
+		} {
+			w = doAuthJSON(r, "PUT", path, token, invalid)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		}
+		db.First(&budget, budget.ID)
+		assert.Equal(t, "2025-01-01", budget.StartDate)
+
+		w = doAuthJSON(r, "GET", "/envelopes", token, nil)
+		assert.Len(t, decode(w.Body.Bytes()).Envelopes, 2)
+	})
+
+	t.Run("Consistency Check", func(t *testing.T) {
+		var check struct {
+			Consistent    bool              `json:"consistent"`
+			Discrepancies []json.RawMessage `json:"discrepancies"`
+		}
+		w := doAuthJSON(r, "GET", "/envelopes/check", token, nil)
+		json.Unmarshal(w.Body.Bytes(), &check)
+		assert.True(t, check.Consistent, w.Body.String())
+
+		// 直接改写信封金额后校验失败
+		db.Model(&models.Budget{}).Where("kind = ? AND category_id = ?", "envelope", rent.ID).UpdateColumn("amount", 1)
+		w = doAuthJSON(r, "GET", "/envelopes/check", token, nil)
+		json.Unmarshal(w.Body.Bytes(), &check)
+		assert.False(t, check.Consistent)
+		assert.Len(t, check.Discrepancies, 1)
+
+		w = doAuthJSON(r, "POST", "/envelopes/enable", token, gin.H{"start_date": "2024-12-01"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
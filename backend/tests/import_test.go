@@ -0,0 +1,286 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"personal-finance/handlers"
+	"personal-finance/importer"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func doUpload(r *gin.Engine, path string, fields map[string]string, file []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		w.WriteField(k, v)
+	}
+	part, _ := w.CreateFormFile("file", "statement")
+	part.Write(file)
+	w.Close()
+
+	req := httptest.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCSVImport(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.ImportHandler{DB: db}
+	r.POST("/import-profiles", h.CreateImportProfile)
+	r.POST("/imports/csv/preview", h.PreviewCSVImport)
+	r.POST("/imports/csv", h.ConfirmCSVImport)
+
+	account := models.Account{Name: "工行", Balance: 100000}
+	db.Create(&account)
+	food := models.Category{Name: "餐饮", Type: "expense"}
+	salary := models.Category{Name: "工资", Type: "income"}
+	db.Create(&food)
+	db.Create(&salary)
+
+	w := doJSON(r, "POST", "/import-profiles", gin.H{
+		"name": "工商银行", "encoding": "gbk", "skip_rows": 1, "has_header": true,
+		"date_column": "交易日期", "amount_column": "金额", "description_column": "摘要",
+		"date_format": "2006/01/02", "sign_convention": "signed",
+		"income_category_id": salary.ID, "expense_category_id": food.ID,
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var profile models.ImportProfile
+	json.Unmarshal(w.Body.Bytes(), &profile)
+
+	encode := func(text string) []byte {
+		data, _ := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(text))
+		return data
+	}
+	fields := map[string]string{
+		"profile_id": fmt.Sprint(profile.ID),
+		"account_id": fmt.Sprint(account.ID),
+	}
+
+	t.Run("Invalid Profile", func(t *testing.T) {
+		w := doJSON(r, "POST", "/import-profiles", gin.H{"name": "x", "date_column": "1", "encoding": "big5"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "POST", "/import-profiles", gin.H{"name": "x", "date_column": "1", "amount_column": "2", "skip_rows": -1})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// 已保存的配置同样在解析前校验，不会因负数的跳过行数越界
+		_, err := importer.ParseCSV(bytes.NewReader([]byte("2025-03-01,-10\n")), models.ImportProfile{
+			DateColumn: "1", AmountColumn: "2", SkipRows: -1,
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing Or Invalid IDs", func(t *testing.T) {
+		csv := encode("工商银行账户明细\n交易日期,摘要,金额\n2025/03/01,午餐,-10\n")
+		for _, invalid := range []map[string]string{
+			{"profile_id": fmt.Sprint(profile.ID)},
+			{"profile_id": fmt.Sprint(profile.ID), "account_id": "0) OR (1=1"},
+			{"account_id": fmt.Sprint(account.ID)},
+			{"profile_id": "abc", "account_id": fmt.Sprint(account.ID)},
+		} {
+			w := doUpload(r, "/imports/csv", invalid, csv)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		}
+		assert.Equal(t, "1000.00", balanceOf(db, account.ID))
+	})
+
+	t.Run("Preview Reports Row Errors", func(t *testing.T) {
+		csv := encode("工商银行账户明细\n交易日期,摘要,金额\n2025/03/01,午餐,\"-1,234.50\"\n2025/13/01,错误日期,-5\n2025/03/05,工资,8000\n")
+		w := doUpload(r, "/imports/csv/preview", fields, csv)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var preview struct {
+			Rows []struct {
+				Line        int          `json:"line"`
+				Date        string       `json:"date"`
+				Amount      models.Money `json:"amount"`
+				Type        string       `json:"type"`
+				Description string       `json:"description"`
+				CategoryID  uint         `json:"category_id"`
+				Errors      []string     `json:"errors"`
+			} `json:"rows"`
+			Valid   int `json:"valid"`
+			Invalid int `json:"invalid"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &preview)
+		assert.Equal(t, 2, preview.Valid)
+		assert.Equal(t, 1, preview.Invalid)
+		assert.Equal(t, "午餐", preview.Rows[0].Description)
+		assert.Equal(t, "1234.50", preview.Rows[0].Amount.String())
+		assert.Equal(t, "expense", preview.Rows[0].Type)
+		assert.Equal(t, food.ID, preview.Rows[0].CategoryID)
+		assert.Equal(t, 4, preview.Rows[1].Line)
+		assert.NotEmpty(t, preview.Rows[1].Errors)
+		assert.Equal(t, salary.ID, preview.Rows[2].CategoryID)
+
+		// 预览不写入数据
+		assert.Equal(t, "1000.00", balanceOf(db, account.ID))
+
+		// 含错误行时拒绝导入
+		w = doUpload(r, "/imports/csv", fields, csv)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "1000.00", balanceOf(db, account.ID))
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		csv := encode("工商银行账户明细\n交易日期,摘要,金额\n2025/03/01,午餐,\"-1,234.50\"\n2025/03/05,工资,8000\n")
+		w := doUpload(r, "/imports/csv", fields, csv)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "7765.50", balanceOf(db, account.ID))
+
+		var count int
+		db.Model(&models.Transaction{}).Where("account_id = ?", account.ID).Count(&count)
+		assert.Equal(t, 2, count)
+	})
+}
+
+func TestOFXImport(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.ImportHandler{DB: db}
+	r.POST("/imports/ofx/preview", h.PreviewOFXImport)
+	r.POST("/imports/ofx", h.ConfirmOFXImport)
+
+	account := models.Account{Name: "Checking", Balance: 100000, Currency: "USD"}
+	db.Create(&account)
+	groceries := models.Category{Name: "日用", Type: "expense"}
+	salary := models.Category{Name: "工资", Type: "income"}
+	db.Create(&groceries)
+	db.Create(&salary)
+
+	fields := map[string]string{
+		"account_id":          fmt.Sprint(account.ID),
+		"income_category_id":  fmt.Sprint(salary.ID),
+		"expense_category_id": fmt.Sprint(groceries.ID),
+	}
+	sgml, _ := os.ReadFile("testdata/statement.ofx")
+	xml, _ := os.ReadFile("testdata/statement.qfx")
+
+	t.Run("Parse SGML", func(t *testing.T) {
+		f, _ := os.Open("testdata/statement.ofx")
+		defer f.Close()
+		rows, err := importer.ParseOFX(f)
+		assert.NoError(t, err)
+		assert.Len(t, rows, 3)
+		assert.Equal(t, "2025-03-02", rows[0].Date)
+		assert.Equal(t, "42.15", rows[0].Amount.String())
+		assert.Equal(t, "expense", rows[0].Type)
+		assert.Equal(t, "20250302001", rows[0].ExternalID)
+		assert.Equal(t, "GROCERY MART POS PURCHASE", rows[0].Description)
+		assert.Equal(t, "income", rows[1].Type)
+	})
+
+	t.Run("Reject Non OFX", func(t *testing.T) {
+		w := doUpload(r, "/imports/ofx/preview", fields, []byte("date,amount\n"))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		w := doUpload(r, "/imports/ofx", fields, sgml)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "3447.86", balanceOf(db, account.ID))
+	})
+
+	t.Run("Skip Imported FITID", func(t *testing.T) {
+		w := doUpload(r, "/imports/ofx/preview", fields, xml)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var preview struct {
+			Rows []struct {
+				ExternalID string `json:"external_id"`
+				Duplicate  bool   `json:"duplicate"`
+			} `json:"rows"`
+			Valid      int `json:"valid"`
+			Duplicates int `json:"duplicates"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &preview)
+		assert.Equal(t, 1, preview.Valid)
+		assert.Equal(t, 1, preview.Duplicates)
+		assert.True(t, preview.Rows[0].Duplicate)
+		assert.False(t, preview.Rows[1].Duplicate)
+
+		w = doUpload(r, "/imports/ofx", fields, xml)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var result struct {
+			Imported int `json:"imported"`
+			Skipped  int `json:"skipped"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &result)
+		assert.Equal(t, 1, result.Imported)
+		assert.Equal(t, 1, result.Skipped)
+		assert.Equal(t, "3327.86", balanceOf(db, account.ID))
+
+		// 再次导入同一文件不产生新交易
+		w = doUpload(r, "/imports/ofx", fields, sgml)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "3327.86", balanceOf(db, account.ID))
+
+		var count int
+		db.Model(&models.Transaction{}).Where("account_id = ?", account.ID).Count(&count)
+		assert.Equal(t, 4, count)
+	})
+}
+
+func TestQIFImport(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.ImportHandler{DB: db}
+	r.POST("/imports/qif/preview", h.PreviewQIFImport)
+	r.POST("/imports/qif", h.ConfirmQIFImport)
+
+	account := models.Account{Name: "Savings", Balance: 500000}
+	db.Create(&account)
+	rent := models.Category{Name: "住房", Type: "expense"}
+	refund := models.Category{Name: "退款", Type: "income"}
+	db.Create(&rent)
+	db.Create(&refund)
+
+	fields := map[string]string{
+		"account_id":          fmt.Sprint(account.ID),
+		"income_category_id":  fmt.Sprint(refund.ID),
+		"expense_category_id": fmt.Sprint(rent.ID),
+	}
+	qif, _ := os.ReadFile("testdata/statement.qif")
+
+	t.Run("Parse", func(t *testing.T) {
+		f, _ := os.Open("testdata/statement.qif")
+		defer f.Close()
+		rows, err := importer.ParseQIF(f, "")
+		assert.NoError(t, err)
+		assert.Len(t, rows, 4)
+		assert.Equal(t, "2025-03-01", rows[0].Date)
+		assert.Equal(t, "1250.00", rows[0].Amount.String())
+		assert.Equal(t, "Landlord March rent", rows[0].Description)
+		assert.Equal(t, "2025-03-04", rows[1].Date)
+		assert.Equal(t, "income", rows[1].Type)
+		assert.True(t, rows[2].Valid())
+		assert.False(t, rows[3].Valid())
+	})
+
+	t.Run("Day First Date Format", func(t *testing.T) {
+		rows, err := importer.ParseQIF(bytes.NewBufferString("!Type:CCard\nD05/03/2025\nT-10\n^\n"), "02/01/2006")
+		assert.NoError(t, err)
+		assert.Equal(t, "2025-03-05", rows[0].Date)
+	})
+
+	t.Run("Rejects Invalid Rows", func(t *testing.T) {
+		w := doUpload(r, "/imports/qif", fields, qif)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "5000.00", balanceOf(db, account.ID))
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		valid := bytes.Split(qif, []byte("D13/45/2025"))[0]
+		w := doUpload(r, "/imports/qif", fields, valid)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "3817.65", balanceOf(db, account.ID))
+	})
+}
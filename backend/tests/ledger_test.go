@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func doLedgerJSON(r *gin.Engine, method, path, token string, ledgerID uint, payload interface{}) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+	req := httptest.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Ledger-ID", fmt.Sprint(ledgerID))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSharedLedgers(t *testing.T) {
+	r, _ := setupAuthRouter()
+
+	register := func(username string) string {
+		w := doJSON(r, "POST", "/auth/register", gin.H{"username": username, "password": "s3cret-pass"})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var result struct {
+			Token string `json:"token"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result.Token
+	}
+	alice := register("alice")
+	bob := register("bob")
+	carol := register("carol")
+	dave := register("dave")
+
+	// alice 创建家庭账本，bob 可记账，carol 只读
+	w := doAuthJSON(r, "POST", "/ledgers", alice, gin.H{"name": "家庭账本"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var home models.Ledger
+	json.Unmarshal(w.Body.Bytes(), &home)
+	assert.Equal(t, models.RoleOwner, home.Role)
+
+	membersPath := fmt.Sprintf("/ledgers/%d/members", home.ID)
+	assert.Equal(t, http.StatusCreated, doAuthJSON(r, "POST", membersPath, alice, gin.H{"username": "bob", "role": "editor"}).Code)
+	assert.Equal(t, http.StatusCreated, doAuthJSON(r, "POST", membersPath, alice, gin.H{"username": "carol", "role": "viewer"}).Code)
+	assert.Equal(t, http.StatusConflict, doAuthJSON(r, "POST", membersPath, alice, gin.H{"username": "bob", "role": "viewer"}).Code)
+	assert.Equal(t, http.StatusBadRequest, doAuthJSON(r, "POST", membersPath, alice, gin.H{"username": "dave", "role": "admin"}).Code)
+	assert.Equal(t, http.StatusForbidden, doAuthJSON(r, "POST", membersPath, bob, gin.H{"username": "dave", "role": "viewer"}).Code)
+
+	w = doAuthJSON(r, "GET", "/ledgers", bob, nil)
+	var ledgers []models.Ledger
+	json.Unmarshal(w.Body.Bytes(), &ledgers)
+	assert.Len(t, ledgers, 2)
+
+	w = doLedgerJSON(r, "POST", "/accounts", alice, home.ID, gin.H{"name": "共同账户", "balance": "1000"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var account models.Account
+	json.Unmarshal(w.Body.Bytes(), &account)
+
+	w = doLedgerJSON(r, "GET", "/categories", bob, home.ID, nil)
+	var categories []models.Category
+	json.Unmarshal(w.Body.Bytes(), &categories)
+	var expense models.Category
+	for _, category := range categories {
+		if category.Type == "expense" {
+			expense = category
+			break
+		}
+	}
+
+	t.Run("Member Roles", func(t *testing.T) {
+		for _, c := range []struct {
+			token  string
+			amount string
+		}{{alice, "100"}, {bob, "30"}, {bob, "20"}} {
+			w := doLedgerJSON(r, "POST", "/transactions", c.token, home.ID, gin.H{
+				"account_id": account.ID, "amount": c.amount, "type": "expense", "category_id": expense.ID, "date": "2024-03-01",
+			})
+			assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		}
+
+		// 只读成员可以查看，不能记账
+		w := doLedgerJSON(r, "GET", "/transactions", carol, home.ID, nil)
+		var page transactionPage
+		json.Unmarshal(w.Body.Bytes(), &page)
+		assert.Equal(t, int64(3), page.Total)
+		w = doLedgerJSON(r, "POST", "/transactions", carol, home.ID, gin.H{
+			"account_id": account.ID, "amount": "1", "type": "expense", "category_id": expense.ID,
+		})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// 非成员无法访问
+		assert.Equal(t, http.StatusForbidden, doLedgerJSON(r, "GET", "/accounts", dave, home.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, doAuthJSON(r, "GET", membersPath, dave, nil).Code)
+
+		// 未指定账本时使用个人账本
+		w = doAuthJSON(r, "GET", "/accounts", bob, nil)
+		var accounts []models.Account
+		json.Unmarshal(w.Body.Bytes(), &accounts)
+		assert.Len(t, accounts, 0)
+	})
+
+	t.Run("Statistics By Member", func(t *testing.T) {
+		w := doLedgerJSON(r, "GET", "/statistics?start_date=2024-01-01&end_date=2024-12-31", carol, home.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var stats models.Statistics
+		json.Unmarshal(w.Body.Bytes(), &stats)
+		assert.Equal(t, "150.00", stats.TotalExpense.String())
+		if assert.Len(t, stats.ByMember, 2) {
+			assert.Equal(t, "alice", stats.ByMember[0].Username)
+			assert.Equal(t, "100.00", stats.ByMember[0].Expense.String())
+			assert.Equal(t, "bob", stats.ByMember[1].Username)
+			assert.Equal(t, "50.00", stats.ByMember[1].Expense.String())
+		}
+	})
+
+	t.Run("Membership Changes", func(t *testing.T) {
+		var aliceUser models.User
+		w := doAuthJSON(r, "GET", membersPath, carol, nil)
+		var members []models.LedgerMember
+		json.Unmarshal(w.Body.Bytes(), &members)
+		assert.Len(t, members, 3)
+		for _, member := range members {
+			if member.User.Username == "alice" {
+				aliceUser = member.User
+			}
+		}
+
+		// 账本至少保留一个所有者
+		ownerPath := fmt.Sprintf("%s/%d", membersPath, aliceUser.ID)
+		assert.Equal(t, http.StatusBadRequest, doAuthJSON(r, "PUT", ownerPath, alice, gin.H{"role": "editor"}).Code)
+		assert.Equal(t, http.StatusBadRequest, doAuthJSON(r, "DELETE", ownerPath, alice, nil).Code)
+		assert.Equal(t, http.StatusForbidden, doAuthJSON(r, "DELETE", ownerPath, bob, nil).Code)
+
+		// 成员可以自行退出，退出后无法访问
+		var carolUser models.User
+		for _, member := range members {
+			if member.User.Username == "carol" {
+				carolUser = member.User
+			}
+		}
+		assert.Equal(t, http.StatusOK, doAuthJSON(r, "DELETE", fmt.Sprintf("%s/%d", membersPath, carolUser.ID), carol, nil).Code)
+		assert.Equal(t, http.StatusForbidden, doLedgerJSON(r, "GET", "/transactions", carol, home.ID, nil).Code)
+
+		// 仍有账户的账本不能删除
+		assert.Equal(t, http.StatusBadRequest, doAuthJSON(r, "DELETE", fmt.Sprintf("/ledgers/%d", home.ID), alice, nil).Code)
+	})
+}
@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconciliation(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.ReconciliationHandler{DB: db}
+	r.POST("/accounts/:id/reconciliations", h.StartReconciliation)
+	r.GET("/accounts/:id/reconciliations", h.GetReconciliations)
+	r.GET("/reconciliations/:id", h.GetReconciliation)
+	r.PUT("/reconciliations/:id/cleared", h.SetCleared)
+	r.POST("/reconciliations/:id/finish", h.FinishReconciliation)
+	r.DELETE("/reconciliations/:id", h.CancelReconciliation)
+	accountHandler := &handlers.AccountHandler{DB: db}
+	r.PUT("/accounts/:id", accountHandler.UpdateAccount)
+	r.PUT("/accounts/:id/opening-balance", accountHandler.SetOpeningBalance)
+
+	bank := models.Account{Name: "银行卡", Balance: 100000}
+	db.Create(&bank)
+	dining := models.Category{Name: "餐饮", Type: "expense"}
+	db.Create(&dining)
+	salary := models.Category{Name: "工资", Type: "income"}
+	db.Create(&salary)
+
+	record := func(amount, kind string, categoryID uint, date string) models.Transaction {
+		w := doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": bank.ID, "amount": amount, "type": kind, "category_id": categoryID, "date": date,
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created struct {
+			Transaction models.Transaction `json:"transaction"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &created)
+		return created.Transaction
+	}
+	lunch := record("100", "expense", dining.ID, "2025-01-05")
+	dinner := record("50", "expense", dining.ID, "2025-01-10")
+	pay := record("500", "income", salary.ID, "2025-01-20")
+	later := record("30", "expense", dining.ID, "2025-02-02")
+
+	type summary struct {
+		ID             uint         `json:"id"`
+		AdjustmentID   uint         `json:"adjustment_id"`
+		ClearedBalance models.Money `json:"cleared_balance"`
+		Difference     models.Money `json:"difference"`
+		Transactions   []struct {
+			ID      uint         `json:"id"`
+			Delta   models.Money `json:"delta"`
+			Cleared bool         `json:"cleared"`
+		} `json:"transactions"`
+	}
+	decode := func(body []byte) summary {
+		var result summary
+		json.Unmarshal(body, &result)
+		return result
+	}
+
+	// 对账单包含午餐、工资和一笔未记账的 10 元手续费，晚餐尚未入账
+	w := doJSON(r, "POST", fmt.Sprintf("/accounts/%d/reconciliations", bank.ID), gin.H{
+		"statement_date": "2025-01-31", "statement_balance": "1390",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	reconciliation := decode(w.Body.Bytes())
+	assert.Equal(t, "1000.00", reconciliation.ClearedBalance.String())
+	assert.Equal(t, "390.00", reconciliation.Difference.String())
+	assert.Len(t, reconciliation.Transactions, 3)
+	path := fmt.Sprintf("/reconciliations/%d", reconciliation.ID)
+
+	t.Run("Clear Transactions", func(t *testing.T) {
+		w := doJSON(r, "PUT", path+"/cleared", gin.H{"transaction_ids": []uint{later.ID}, "cleared": true})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "PUT", "/reconciliations/0)%20OR%20(1=1/cleared", gin.H{"transaction_ids": []uint{lunch.ID}, "cleared": true})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "DELETE", "/reconciliations/abc", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doJSON(r, "PUT", path+"/cleared", gin.H{"transaction_ids": []uint{lunch.ID, pay.ID, dinner.ID}, "cleared": true})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = doJSON(r, "PUT", path+"/cleared", gin.H{"transaction_ids": []uint{dinner.ID}, "cleared": false})
+		result := decode(w.Body.Bytes())
+		assert.Equal(t, "1400.00", result.ClearedBalance.String())
+		assert.Equal(t, "-10.00", result.Difference.String())
+
+		w = doJSON(r, "POST", fmt.Sprintf("/accounts/%d/reconciliations", bank.ID), gin.H{
+			"statement_date": "2025-02-28", "statement_balance": "0",
+		})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Finish With Adjustment", func(t *testing.T) {
+		w := doJSON(r, "POST", path+"/finish", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		// 旧数据中顶级分类的 parent_id 可能为 NULL，调整交易应沿用已有的分类
+		db.Exec("INSERT INTO categories (ledger_id, name, type, parent_id) VALUES (0, '余额调整', 'expense', NULL)")
+		var legacy models.Category
+		db.Where("name = ?", "余额调整").First(&legacy)
+
+		w = doJSON(r, "POST", path+"/finish", gin.H{"adjust": true})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		result := decode(w.Body.Bytes())
+		assert.Equal(t, "0.00", result.Difference.String())
+		assert.Len(t, result.Transactions, 3)
+		assert.Equal(t, "1310.00", balanceOf(db, bank.ID))
+
+		var adjustment models.Transaction
+		db.Preload("Category").First(&adjustment, result.AdjustmentID)
+		assert.Equal(t, "expense", adjustment.Type)
+		assert.Equal(t, "10.00", adjustment.Amount.String())
+		assert.Equal(t, "余额调整", adjustment.Category.Name)
+		assert.Equal(t, legacy.ID, adjustment.CategoryID)
+		var count int
+		db.Model(&models.Category{}).Where("name = ?", "余额调整").Count(&count)
+		assert.Equal(t, 1, count)
+
+		w = doJSON(r, "PUT", path+"/cleared", gin.H{"transaction_ids": []uint{dinner.ID}, "cleared": true})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Locked", func(t *testing.T) {
+		w := doJSON(r, "PUT", fmt.Sprintf("/transactions/%d", lunch.ID), gin.H{
+			"account_id": bank.ID, "amount": "120", "type": "expense", "category_id": dining.ID, "date": "2025-01-05",
+		})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w = doJSON(r, "DELETE", fmt.Sprintf("/transactions/%d", pay.ID), nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		// 不影响余额的修改仍然允许
+		w = doJSON(r, "PUT", fmt.Sprintf("/transactions/%d", lunch.ID), gin.H{
+			"account_id": bank.ID, "amount": "100", "type": "expense", "category_id": dining.ID, "date": "2025-01-05",
+			"description": "工作餐",
+		})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = doJSON(r, "DELETE", fmt.Sprintf("/transactions/%d", dinner.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = doJSON(r, "PUT", fmt.Sprintf("/accounts/%d/opening-balance", bank.ID), gin.H{"opening_balance": "2000"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Next Reconciliation", func(t *testing.T) {
+		w := doJSON(r, "POST", fmt.Sprintf("/accounts/%d/reconciliations", bank.ID), gin.H{
+			"statement_date": "2025-01-31", "statement_balance": "1360",
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doJSON(r, "POST", fmt.Sprintf("/accounts/%d/reconciliations", bank.ID), gin.H{
+			"statement_date": "2025-02-28", "statement_balance": "1360",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		next := decode(w.Body.Bytes())
+		assert.Equal(t, "1390.00", next.ClearedBalance.String())
+		if assert.Len(t, next.Transactions, 1) {
+			assert.Equal(t, later.ID, next.Transactions[0].ID)
+			assert.Equal(t, "-30.00", next.Transactions[0].Delta.String())
+		}
+
+		w = doJSON(r, "DELETE", fmt.Sprintf("/reconciliations/%d", next.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var history []models.Reconciliation
+		json.Unmarshal(doJSON(r, "GET", fmt.Sprintf("/accounts/%d/reconciliations", bank.ID), nil).Body.Bytes(), &history)
+		assert.Len(t, history, 1)
+	})
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecurringTransactions(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.RecurringHandler{DB: db}
+	r.POST("/recurring-transactions", h.CreateRecurringTransaction)
+	r.GET("/recurring-transactions", h.GetRecurringTransactions)
+	r.PUT("/recurring-transactions/:id", h.UpdateRecurringTransaction)
+	r.DELETE("/recurring-transactions/:id", h.DeleteRecurringTransaction)
+	r.POST("/recurring-transactions/run", h.RunRecurringTransactions)
+
+	bank := models.Account{Name: "银行卡", Balance: 1000000}
+	db.Create(&bank)
+	housing := models.Category{Name: "住房", Type: "expense"}
+	salary := models.Category{Name: "工资", Type: "income"}
+	db.Create(&housing)
+	db.Create(&salary)
+
+	create := func(payload gin.H) models.RecurringTransaction {
+		w := doJSON(r, "POST", "/recurring-transactions", payload)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var recurring models.RecurringTransaction
+		json.Unmarshal(w.Body.Bytes(), &recurring)
+		return recurring
+	}
+	dates := func(recurringID uint) []string {
+		var result []string
+		db.Model(&models.Transaction{}).Where("recurring_id = ?", recurringID).Order("date").Pluck("date", &result)
+		return result
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		w := doJSON(r, "POST", "/recurring-transactions", gin.H{
+			"account_id": bank.ID, "amount": "10", "type": "expense", "category_id": housing.ID,
+			"frequency": "hourly", "start_date": "2025-01-01",
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doJSON(r, "POST", "/recurring-transactions", gin.H{
+			"account_id": bank.ID, "amount": "10", "type": "expense", "category_id": salary.ID,
+			"frequency": "monthly", "start_date": "2025-01-01",
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doJSON(r, "POST", "/recurring-transactions", gin.H{
+			"account_id": bank.ID, "amount": "10", "type": "expense", "category_id": housing.ID,
+			"frequency": "monthly", "start_date": "2025-03-01", "end_date": "2025-02-01",
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Month End Catch Up", func(t *testing.T) {
+		rent := create(gin.H{
+			"account_id": bank.ID, "amount": "3000", "type": "expense", "category_id": housing.ID,
+			"description": "房租", "frequency": "monthly", "start_date": "2025-01-31", "end_date": "2025-06-30",
+		})
+		assert.Equal(t, 1, rent.Interval)
+		assert.Equal(t, "2025-01-31", rent.NextDate)
+
+		// 停机到 4 月中旬后补生成
+		created, err := handlers.MaterializeRecurring(db, nil, "2025-04-15")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, created)
+		assert.Equal(t, []string{"2025-01-31", "2025-02-28", "2025-03-31"}, dates(rent.ID))
+		assert.Equal(t, "1000.00", balanceOf(db, bank.ID))
+
+		// 重复运行不会重复生成
+		created, _ = handlers.MaterializeRecurring(db, nil, "2025-04-15")
+		assert.Equal(t, 0, created)
+
+		// 超过结束日期后停止
+		created, _ = handlers.MaterializeRecurring(db, nil, "2025-12-31")
+		assert.Equal(t, 3, created)
+		assert.Equal(t, []string{"2025-01-31", "2025-02-28", "2025-03-31", "2025-04-30", "2025-05-31", "2025-06-30"}, dates(rent.ID))
+		db.First(&rent, rent.ID)
+		assert.Equal(t, "", rent.NextDate)
+		assert.Equal(t, "2025-06-30", rent.LastDate)
+		assert.Equal(t, "-8000.00", balanceOf(db, bank.ID))
+	})
+
+	t.Run("Update Keeps Generated Occurrences", func(t *testing.T) {
+		pay := create(gin.H{
+			"account_id": bank.ID, "amount": "500", "type": "income", "category_id": salary.ID,
+			"frequency": "weekly", "interval": 2, "start_date": "2025-01-06",
+		})
+		handlers.MaterializeRecurring(db, nil, "2025-02-01")
+		assert.Equal(t, []string{"2025-01-06", "2025-01-20"}, dates(pay.ID))
+
+		// 改为每周，只影响最后一次生成之后的日期
+		w := doJSON(r, "PUT", fmt.Sprintf("/recurring-transactions/%d", pay.ID), gin.H{
+			"account_id": bank.ID, "amount": "500", "type": "income", "category_id": salary.ID,
+			"frequency": "weekly", "start_date": "2025-01-06",
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var updated models.RecurringTransaction
+		json.Unmarshal(w.Body.Bytes(), &updated)
+		assert.Equal(t, "2025-01-27", updated.NextDate)
+
+		handlers.MaterializeRecurring(db, nil, "2025-02-03")
+		assert.Equal(t, []string{"2025-01-06", "2025-01-20", "2025-01-27", "2025-02-03"}, dates(pay.ID))
+	})
+
+	t.Run("Failure Is Recorded And Retried", func(t *testing.T) {
+		cash := models.Account{Name: "现金"}
+		db.Create(&cash)
+		daily := create(gin.H{
+			"account_id": cash.ID, "amount": "1", "type": "expense", "category_id": housing.ID,
+			"frequency": "daily", "start_date": "2025-03-01",
+		})
+		db.Delete(&cash)
+
+		_, err := handlers.MaterializeRecurring(db, nil, "2025-03-03")
+		assert.NoError(t, err)
+		assert.Empty(t, dates(daily.ID))
+		db.First(&daily, daily.ID)
+		assert.Equal(t, "2025-03-01", daily.NextDate)
+		assert.NotEmpty(t, daily.LastError)
+	})
+
+	t.Run("Rules Applied", func(t *testing.T) {
+		// 生成的交易与手动记账一样应用归类规则，模板的分类优先
+		property := models.Category{Name: "物业", Type: "expense"}
+		db.Create(&property)
+		db.Create(&models.Rule{
+			Name: "物业费", DescriptionContains: "物业", CategoryID: property.ID,
+			SetDescription: "小区物业费", AddTags: models.StringList{"住房"},
+		})
+		fee := create(gin.H{
+			"account_id": bank.ID, "amount": "200", "type": "expense", "category_id": housing.ID,
+			"description": "物业 5 月", "frequency": "monthly", "start_date": "2025-05-01", "end_date": "2025-05-01",
+		})
+		handlers.MaterializeRecurring(db, nil, "2025-05-02")
+
+		var transaction models.Transaction
+		if assert.NoError(t, db.Preload("Tags").Where("recurring_id = ?", fee.ID).First(&transaction).Error) {
+			assert.Equal(t, "小区物业费", transaction.Description)
+			assert.Equal(t, housing.ID, transaction.CategoryID)
+			if assert.Len(t, transaction.Tags, 1) {
+				assert.Equal(t, "住房", transaction.Tags[0].Name)
+			}
+		}
+	})
+
+	t.Run("Run Only Current Ledger", func(t *testing.T) {
+		today := time.Now().Format("2006-01-02")
+		own := create(gin.H{
+			"account_id": bank.ID, "amount": "5", "type": "expense", "category_id": housing.ID,
+			"frequency": "monthly", "start_date": today,
+		})
+		// 其他账本中已到期的模板只由定时调度生成
+		otherAccount := models.Account{Name: "其他账本", LedgerID: 7, Balance: 10000}
+		db.Create(&otherAccount)
+		otherCategory := models.Category{Name: "其他", Type: "expense", LedgerID: 7}
+		db.Create(&otherCategory)
+		other := models.RecurringTransaction{
+			LedgerID: 7, AccountID: otherAccount.ID, Amount: 100, Type: "expense", CategoryID: otherCategory.ID,
+			Frequency: "monthly", Interval: 1, StartDate: today, NextDate: today,
+		}
+		db.Create(&other)
+
+		w := doJSON(r, "POST", "/recurring-transactions/run", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, []string{today}, dates(own.ID))
+		assert.Empty(t, dates(other.ID))
+		assert.Equal(t, "100.00", balanceOf(db, otherAccount.ID))
+	})
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategorizationRules(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.RuleHandler{DB: db}
+	r.POST("/rules", h.CreateRule)
+	r.GET("/rules", h.GetRules)
+	r.PUT("/rules/:id", h.UpdateRule)
+	r.POST("/rules/apply", h.ApplyRules)
+	importHandler := &handlers.ImportHandler{DB: db}
+	r.POST("/imports/qif/preview", importHandler.PreviewQIFImport)
+	r.POST("/imports/qif", importHandler.ConfirmQIFImport)
+
+	account := models.Account{Name: "银行卡", Balance: 500000}
+	card := models.Account{Name: "信用卡"}
+	db.Create(&account)
+	db.Create(&card)
+	dining := models.Category{Name: "餐饮", Type: "expense"}
+	housing := models.Category{Name: "住房", Type: "expense"}
+	other := models.Category{Name: "其他支出", Type: "expense"}
+	refund := models.Category{Name: "退款", Type: "income"}
+	db.Create(&dining)
+	db.Create(&housing)
+	db.Create(&other)
+	db.Create(&refund)
+
+	createRule := func(payload gin.H) models.Rule {
+		w := doJSON(r, "POST", "/rules", payload)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var rule models.Rule
+		json.Unmarshal(w.Body.Bytes(), &rule)
+		return rule
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		w := doJSON(r, "POST", "/rules", gin.H{"name": "无动作", "description_contains": "x"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "POST", "/rules", gin.H{"name": "坏正则", "description_regex": "(", "category_id": dining.ID})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "POST", "/rules", gin.H{"name": "类型不符", "type": "income", "category_id": dining.ID})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "POST", "/rules", gin.H{"name": "分类不存在", "category_id": 999})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	coffee := createRule(gin.H{
+		"name": "咖啡", "priority": 10, "description_contains": "coffee",
+		"category_id": dining.ID, "add_tags": []string{"咖啡"},
+	})
+	createRule(gin.H{
+		"name": "房租", "priority": 20, "description_regex": `^Landlord (\w+)`, "type": "expense",
+		"min_amount": "1000", "category_id": housing.ID, "set_description": "房租 $1",
+	})
+	createRule(gin.H{
+		"name": "信用卡大额", "priority": 30, "account_id": card.ID, "min_amount": "500",
+		"category_id": other.ID, "add_tags": []string{"大额", "咖啡"},
+	})
+
+	t.Run("Priority Order", func(t *testing.T) {
+		w := doJSON(r, "GET", "/rules", nil)
+		var rules []models.Rule
+		json.Unmarshal(w.Body.Bytes(), &rules)
+		if assert.Len(t, rules, 3) {
+			assert.Equal(t, coffee.ID, rules[0].ID)
+			assert.Equal(t, models.StringList{"咖啡"}, rules[0].AddTags)
+		}
+	})
+
+	t.Run("Apply On Create", func(t *testing.T) {
+		// 未指定分类时由规则补全，标签按规则顺序累加
+		w := doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": card.ID, "amount": "600", "type": "expense", "description": "Coffee beans wholesale",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created struct {
+			Transaction models.Transaction `json:"transaction"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &created)
+		assert.Equal(t, dining.ID, created.Transaction.CategoryID)
+		if assert.Len(t, created.Transaction.Tags, 2) {
+			assert.Equal(t, "咖啡", created.Transaction.Tags[0].Name)
+			assert.Equal(t, "大额", created.Transaction.Tags[1].Name)
+		}
+
+		// 手动选择的分类优先，描述仍按规则改写
+		w = doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": account.ID, "amount": "3000", "type": "expense", "category_id": other.ID,
+			"description": "Landlord April",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &created)
+		assert.Equal(t, other.ID, created.Transaction.CategoryID)
+		assert.Equal(t, "房租 April", created.Transaction.Description)
+
+		// 金额不满足条件时不归类
+		w = doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": account.ID, "amount": "10", "type": "expense", "description": "Landlord fee",
+		})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Apply On Import", func(t *testing.T) {
+		qif, _ := os.ReadFile("testdata/statement.qif")
+		fields := map[string]string{
+			"account_id":          fmt.Sprint(account.ID),
+			"income_category_id":  fmt.Sprint(refund.ID),
+			"expense_category_id": fmt.Sprint(other.ID),
+		}
+		w := doUpload(r, "/imports/qif/preview", fields, qif)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var preview struct {
+			Rows []struct {
+				Description string   `json:"description"`
+				CategoryID  uint     `json:"category_id"`
+				Tags        []string `json:"tags"`
+			} `json:"rows"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &preview)
+		if assert.Len(t, preview.Rows, 4) {
+			assert.Equal(t, housing.ID, preview.Rows[0].CategoryID)
+			assert.Equal(t, "房租 March", preview.Rows[0].Description)
+			assert.Equal(t, refund.ID, preview.Rows[1].CategoryID)
+			assert.Equal(t, dining.ID, preview.Rows[2].CategoryID)
+			assert.Equal(t, []string{"咖啡"}, preview.Rows[2].Tags)
+		}
+	})
+
+	t.Run("Reapply Dry Run", func(t *testing.T) {
+		legacy := models.Transaction{
+			AccountID: account.ID, Amount: 1500, Type: "expense", CategoryID: other.ID,
+			Description: "coffee with team", Date: "2024-01-05",
+		}
+		db.Create(&legacy)
+
+		w := doJSON(r, "POST", "/rules/apply?dry_run=true&start_date=2024-01-01&end_date=2024-12-31", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result struct {
+			DryRun  bool `json:"dry_run"`
+			Checked int  `json:"checked"`
+			Changes []struct {
+				TransactionID uint     `json:"transaction_id"`
+				OldCategoryID uint     `json:"old_category_id"`
+				NewCategoryID uint     `json:"new_category_id"`
+				AddedTags     []string `json:"added_tags"`
+			} `json:"changes"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &result)
+		assert.True(t, result.DryRun)
+		assert.Equal(t, 1, result.Checked)
+		if assert.Len(t, result.Changes, 1) {
+			assert.Equal(t, legacy.ID, result.Changes[0].TransactionID)
+			assert.Equal(t, other.ID, result.Changes[0].OldCategoryID)
+			assert.Equal(t, dining.ID, result.Changes[0].NewCategoryID)
+			assert.Equal(t, []string{"咖啡"}, result.Changes[0].AddedTags)
+		}
+
+		var unchanged models.Transaction
+		db.First(&unchanged, legacy.ID)
+		assert.Equal(t, other.ID, unchanged.CategoryID)
+
+		// 正式应用后再次试运行没有修改
+		w = doJSON(r, "POST", "/rules/apply?start_date=2024-01-01&end_date=2024-12-31", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var updated models.Transaction
+		db.Preload("Tags").First(&updated, legacy.ID)
+		assert.Equal(t, dining.ID, updated.CategoryID)
+		assert.Len(t, updated.Tags, 1)
+
+		w = doJSON(r, "POST", "/rules/apply?dry_run=true&start_date=2024-01-01&end_date=2024-12-31", nil)
+		json.Unmarshal(w.Body.Bytes(), &result)
+		assert.Len(t, result.Changes, 0)
+	})
+}
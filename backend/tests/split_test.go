@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitTransactions(t *testing.T) {
+	r, db := setupTransactionRouter()
+	budgetHandler := &handlers.BudgetHandler{DB: db}
+	r.POST("/budgets", budgetHandler.CreateBudget)
+	r.GET("/budgets/:id/status", budgetHandler.GetBudgetStatus)
+
+	account := models.Account{Name: "银行卡", Balance: 100000}
+	db.Create(&account)
+	groceries := models.Category{Name: "食品", Type: "expense"}
+	household := models.Category{Name: "日用品", Type: "expense"}
+	gifts := models.Category{Name: "礼物", Type: "expense"}
+	salary := models.Category{Name: "工资", Type: "income"}
+	db.Create(&groceries)
+	db.Create(&household)
+	db.Create(&gifts)
+	db.Create(&salary)
+
+	receipt := func(amount string, splits []gin.H) gin.H {
+		return gin.H{
+			"account_id": account.ID, "amount": amount, "type": "expense",
+			"description": "超市小票", "date": "2024-06-08", "splits": splits,
+		}
+	}
+	lines := []gin.H{
+		{"category_id": groceries.ID, "amount": "120.50", "memo": "蔬菜水果"},
+		{"category_id": household.ID, "amount": "45", "memo": "洗衣液"},
+		{"category_id": gifts.ID, "amount": "80"},
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		w := doJSON(r, "POST", "/transactions", receipt("250", lines))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "POST", "/transactions", receipt("100", []gin.H{
+			{"category_id": groceries.ID, "amount": "60"}, {"category_id": salary.ID, "amount": "40"},
+		}))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "POST", "/transactions", receipt("100", []gin.H{
+			{"category_id": groceries.ID, "amount": "100"}, {"category_id": gifts.ID, "amount": "0"},
+		}))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "1000.00", balanceOf(db, account.ID))
+	})
+
+	w := doJSON(r, "POST", "/transactions", receipt("245.50", lines))
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Transaction models.Transaction `json:"transaction"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	split := created.Transaction
+
+	t.Run("Create", func(t *testing.T) {
+		assert.Equal(t, groceries.ID, split.CategoryID)
+		assert.Len(t, split.Splits, 3)
+		assert.Equal(t, "754.50", balanceOf(db, account.ID))
+
+		w := doJSON(r, "GET", fmt.Sprintf("/transactions?category_id=%d", gifts.ID), nil)
+		var page transactionPage
+		json.Unmarshal(w.Body.Bytes(), &page)
+		if assert.Equal(t, int64(1), page.Total) && assert.Len(t, page.Transactions[0].Splits, 3) {
+			assert.Equal(t, "洗衣液", page.Transactions[0].Splits[1].Memo)
+		}
+	})
+
+	t.Run("Statistics By Split", func(t *testing.T) {
+		w := doJSON(r, "GET", "/statistics?start_date=2024-06-01&end_date=2024-06-30", nil)
+		var stats models.Statistics
+		json.Unmarshal(w.Body.Bytes(), &stats)
+		assert.Equal(t, "245.50", stats.TotalExpense.String())
+		amounts := make(map[uint]string)
+		for _, stat := range stats.ByCategory {
+			amounts[stat.CategoryID] = stat.Amount.String()
+		}
+		assert.Equal(t, map[uint]string{groceries.ID: "120.50", household.ID: "45.00", gifts.ID: "80.00"}, amounts)
+	})
+
+	t.Run("Budget By Split", func(t *testing.T) {
+		w := doJSON(r, "POST", "/budgets", gin.H{
+			"category_id": household.ID, "amount": "100", "start_date": "2024-06-01", "end_date": "2024-06-30",
+		})
+		var budget models.Budget
+		json.Unmarshal(w.Body.Bytes(), &budget)
+
+		w = doJSON(r, "GET", fmt.Sprintf("/budgets/%d/status", budget.ID), nil)
+		var status struct {
+			Status struct {
+				ActualExpense models.Money `json:"actual_expense"`
+			} `json:"status"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &status)
+		assert.Equal(t, "45.00", status.Status.ActualExpense.String())
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		// 不提供拆分行时保留原有拆分，金额须仍与其合计一致
+		payload := receipt("300", nil)
+		delete(payload, "splits")
+		w := doJSON(r, "PUT", fmt.Sprintf("/transactions/%d", split.ID), payload)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doJSON(r, "PUT", fmt.Sprintf("/transactions/%d", split.ID), receipt("300", []gin.H{
+			{"category_id": household.ID, "amount": "100"}, {"category_id": gifts.ID, "amount": "200"},
+		}))
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "700.00", balanceOf(db, account.ID))
+
+		var splits []models.TransactionSplit
+		db.Where("transaction_id = ?", split.ID).Find(&splits)
+		assert.Len(t, splits, 2)
+
+		// 空数组取消拆分，交易恢复为单一分类
+		payload = receipt("300", []gin.H{})
+		payload["category_id"] = gifts.ID
+		w = doJSON(r, "PUT", fmt.Sprintf("/transactions/%d", split.ID), payload)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var count int
+		db.Model(&models.TransactionSplit{}).Where("transaction_id = ?", split.ID).Count(&count)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		w := doJSON(r, "POST", "/transactions", receipt("245.50", lines))
+		json.Unmarshal(w.Body.Bytes(), &created)
+		w = doJSON(r, "DELETE", fmt.Sprintf("/transactions/%d", created.Transaction.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var count int
+		db.Model(&models.TransactionSplit{}).Where("transaction_id = ?", created.Transaction.ID).Count(&count)
+		assert.Equal(t, 0, count)
+	})
+}
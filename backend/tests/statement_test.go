@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreditCardStatements(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.StatementHandler{DB: db}
+	r.GET("/accounts/:id/statements", h.GetStatements)
+	r.GET("/accounts/:id/statements/:closing_date", h.GetStatement)
+
+	bank := models.Account{Name: "工资卡", Balance: 1000000}
+	db.Create(&bank)
+	card := models.Account{Name: "信用卡", Type: models.AccountCreditCard, CreditLimit: 1000000, StatementDay: 5, PaymentDueDay: 25}
+	db.Create(&card)
+	shopping := models.Category{Name: "购物", Type: "expense"}
+	db.Create(&shopping)
+	refund := models.Category{Name: "退款", Type: "income"}
+	db.Create(&refund)
+
+	record := func(amount, kind string, categoryID uint, date string) {
+		w := doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": card.ID, "amount": amount, "type": kind, "category_id": categoryID, "date": date,
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	pay := func(amount, date string) {
+		w := doJSON(r, "POST", "/transfers", gin.H{
+			"from_account_id": bank.ID, "to_account_id": card.ID, "amount": amount, "date": date,
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	record("300", "expense", shopping.ID, "2024-01-10")
+	record("200", "expense", shopping.ID, "2024-01-20")
+	record("50", "income", refund.ID, "2024-02-01")
+	record("1000", "expense", shopping.ID, "2024-02-10")
+	pay("450", "2024-02-20")
+	pay("100", "2024-03-10")
+
+	type statement struct {
+		StartDate           string               `json:"start_date"`
+		ClosingDate         string               `json:"closing_date"`
+		DueDate             string               `json:"due_date"`
+		OpeningBalance      models.Money         `json:"opening_balance"`
+		Charges             models.Money         `json:"charges"`
+		Credits             models.Money         `json:"credits"`
+		Payments            models.Money         `json:"payments"`
+		StatementBalance    models.Money         `json:"statement_balance"`
+		MinimumPayment      models.Money         `json:"minimum_payment"`
+		PaidAmount          models.Money         `json:"paid_amount"`
+		Status              string               `json:"status"`
+		Transactions        []models.Transaction `json:"transactions"`
+		PaymentTransactions []models.Transaction `json:"payment_transactions"`
+	}
+	get := func(closingDate string) statement {
+		w := doJSON(r, "GET", fmt.Sprintf("/accounts/%d/statements/%s", card.ID, closingDate), nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Statement statement `json:"statement"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Statement
+	}
+
+	t.Run("Paid In Full", func(t *testing.T) {
+		s := get("2024-02-05")
+		assert.Equal(t, "2024-01-06", s.StartDate)
+		assert.Equal(t, "2024-02-25", s.DueDate)
+		assert.Equal(t, "500.00", s.Charges.String())
+		assert.Equal(t, "50.00", s.Credits.String())
+		assert.Equal(t, "450.00", s.StatementBalance.String())
+		assert.Equal(t, "45.00", s.MinimumPayment.String())
+		assert.Equal(t, "450.00", s.PaidAmount.String())
+		assert.Equal(t, "paid", s.Status)
+		assert.Len(t, s.Transactions, 3)
+		assert.Len(t, s.PaymentTransactions, 1)
+	})
+
+	t.Run("Minimum Paid", func(t *testing.T) {
+		s := get("2024-03-05")
+		assert.Equal(t, "450.00", s.OpeningBalance.String())
+		assert.Equal(t, "450.00", s.Payments.String())
+		assert.Equal(t, "1000.00", s.StatementBalance.String())
+		assert.Equal(t, "100.00", s.MinimumPayment.String())
+		assert.Equal(t, "minimum_paid", s.Status)
+	})
+
+	t.Run("Overdue", func(t *testing.T) {
+		s := get("2024-04-05")
+		assert.Equal(t, "900.00", s.StatementBalance.String())
+		assert.Equal(t, "overdue", s.Status)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		w := doJSON(r, "GET", fmt.Sprintf("/accounts/%d/statements?count=60", card.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Statements []statement `json:"statements"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if assert.NotEmpty(t, resp.Statements) {
+			assert.Equal(t, "open", resp.Statements[0].Status)
+			assert.Equal(t, "900.00", resp.Statements[0].StatementBalance.String())
+			// 第一笔交易之前的账单不再列出
+			assert.Equal(t, "2024-02-05", resp.Statements[len(resp.Statements)-1].ClosingDate)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		w := doJSON(r, "GET", fmt.Sprintf("/accounts/%d/statements/2024-02-06", card.ID), nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = doJSON(r, "GET", fmt.Sprintf("/accounts/%d/statements", bank.ID), nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = doJSON(r, "GET", "/accounts/0)%20OR%20(1=1/statements", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"personal-finance/classifier"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategorySuggestions(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.SuggestionHandler{DB: db}
+	r.POST("/suggestions/category", h.SuggestCategory)
+	r.GET("/suggestions/metrics", h.GetSuggestionMetrics)
+
+	account := models.Account{Name: "银行卡", Balance: 1000000}
+	db.Create(&account)
+	dining := models.Category{Name: "餐饮", Type: "expense"}
+	transport := models.Category{Name: "交通", Type: "expense"}
+	salary := models.Category{Name: "工资", Type: "income"}
+	db.Create(&dining)
+	db.Create(&transport)
+	db.Create(&salary)
+
+	create := func(description, amount, kind string, categoryID uint) models.Transaction {
+		w := doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": account.ID, "amount": amount, "type": kind,
+			"category_id": categoryID, "description": description,
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created struct {
+			Transaction models.Transaction `json:"transaction"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &created)
+		return created.Transaction
+	}
+
+	type suggestionResult struct {
+		Suggestions []struct {
+			CategoryID   uint    `json:"category_id"`
+			CategoryName string  `json:"category_name"`
+			Confidence   float64 `json:"confidence"`
+		} `json:"suggestions"`
+		TrainedOn int `json:"trained_on"`
+	}
+	suggest := func(payload gin.H) suggestionResult {
+		w := doJSON(r, "POST", "/suggestions/category", payload)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result suggestionResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+
+	t.Run("Tokenize", func(t *testing.T) {
+		assert.Equal(t, []string{"星", "星巴", "巴", "巴克", "克", "coffee"}, classifier.Tokenize("星巴克 Coffee 2024"))
+	})
+
+	t.Run("Empty History", func(t *testing.T) {
+		result := suggest(gin.H{"description": "星巴克"})
+		assert.Empty(t, result.Suggestions)
+		assert.Equal(t, 0, result.TrainedOn)
+	})
+
+	for _, d := range []string{"星巴克咖啡", "麦当劳午餐", "瑞幸咖啡", "肯德基晚餐"} {
+		create(d, "35", "expense", dining.ID)
+	}
+	for _, d := range []string{"滴滴打车", "地铁充值", "出租车"} {
+		create(d, "60", "expense", transport.ID)
+	}
+	create("三月工资", "12000", "income", salary.ID)
+
+	t.Run("Ranked Suggestions", func(t *testing.T) {
+		result := suggest(gin.H{"description": "星巴克拿铁", "amount": "38", "account_id": account.ID})
+		assert.Equal(t, 8, result.TrainedOn)
+		if assert.Len(t, result.Suggestions, 3) {
+			assert.Equal(t, dining.ID, result.Suggestions[0].CategoryID)
+			assert.Equal(t, "餐饮", result.Suggestions[0].CategoryName)
+			assert.Greater(t, result.Suggestions[0].Confidence, result.Suggestions[1].Confidence)
+			sum := 0.0
+			for _, s := range result.Suggestions {
+				sum += s.Confidence
+			}
+			assert.InDelta(t, 1.0, sum, 1e-9)
+		}
+
+		// 指定类型时只推荐同类型的分类
+		result = suggest(gin.H{"description": "打车回家", "type": "expense"})
+		if assert.Len(t, result.Suggestions, 2) {
+			assert.Equal(t, transport.ID, result.Suggestions[0].CategoryID)
+		}
+	})
+
+	t.Run("Incremental Retraining", func(t *testing.T) {
+		// 新交易立即参与训练
+		for i := 0; i < 3; i++ {
+			create("共享单车", "2", "expense", transport.ID)
+		}
+		result := suggest(gin.H{"description": "共享单车月卡", "type": "expense"})
+		assert.Equal(t, 11, result.TrainedOn)
+		assert.Equal(t, transport.ID, result.Suggestions[0].CategoryID)
+
+		// 修改分类和删除交易同样会同步到模型
+		var bikes []models.Transaction
+		db.Where("description = ?", "共享单车").Find(&bikes)
+		for _, bike := range bikes {
+			w := doJSON(r, "PUT", fmt.Sprintf("/transactions/%d", bike.ID), gin.H{
+				"account_id": account.ID, "amount": "2", "type": "expense",
+				"category_id": dining.ID, "description": "共享单车",
+			})
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+		result = suggest(gin.H{"description": "共享单车月卡", "type": "expense"})
+		assert.Equal(t, dining.ID, result.Suggestions[0].CategoryID)
+
+		for _, bike := range bikes {
+			assert.Equal(t, http.StatusOK, doJSON(r, "DELETE", fmt.Sprintf("/transactions/%d", bike.ID), nil).Code)
+		}
+		result = suggest(gin.H{"description": "共享单车月卡", "type": "expense"})
+		assert.Equal(t, 8, result.TrainedOn)
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		w := doJSON(r, "GET", "/suggestions/metrics", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var metrics classifier.Metrics
+		json.Unmarshal(w.Body.Bytes(), &metrics)
+		assert.Equal(t, 8, metrics.Samples)
+		assert.Greater(t, metrics.Accuracy, 0.5)
+		assert.GreaterOrEqual(t, metrics.Top3Accuracy, metrics.Accuracy)
+		assert.Len(t, metrics.ByLabel, 3)
+	})
+}
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionTags(t *testing.T) {
+	r, db := setupTransactionRouter()
+	h := &handlers.TagHandler{DB: db}
+	r.POST("/tags", h.CreateTag)
+	r.GET("/tags", h.GetTags)
+	r.PUT("/tags/:id", h.UpdateTag)
+	r.DELETE("/tags/:id", h.DeleteTag)
+	budgetHandler := &handlers.BudgetHandler{DB: db}
+	r.POST("/budgets", budgetHandler.CreateBudget)
+	r.GET("/budgets/:id/status", budgetHandler.GetBudgetStatus)
+
+	account := models.Account{Name: "银行卡", Balance: 1000000}
+	db.Create(&account)
+	dining := models.Category{Name: "餐饮", Type: "expense"}
+	travel := models.Category{Name: "交通", Type: "expense"}
+	db.Create(&dining)
+	db.Create(&travel)
+
+	createTag := func(name string) models.Tag {
+		w := doJSON(r, "POST", "/tags", gin.H{"name": name})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var tag models.Tag
+		json.Unmarshal(w.Body.Bytes(), &tag)
+		return tag
+	}
+	trip := createTag(" 旅行 ")
+	work := createTag("出差")
+
+	t.Run("Tag CRUD", func(t *testing.T) {
+		assert.Equal(t, "旅行", trip.Name)
+		w := doJSON(r, "POST", "/tags", gin.H{"name": "旅行"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w = doJSON(r, "POST", "/tags", gin.H{"name": "  "})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		temp := createTag("临时")
+		w = doJSON(r, "PUT", fmt.Sprintf("/tags/%d", temp.ID), gin.H{"name": "出差"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w = doJSON(r, "PUT", fmt.Sprintf("/tags/%d", temp.ID), gin.H{"name": "临时2"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = doJSON(r, "DELETE", fmt.Sprintf("/tags/%d", temp.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	create := func(payload gin.H) models.Transaction {
+		w := doJSON(r, "POST", "/transactions", payload)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created struct {
+			Transaction models.Transaction `json:"transaction"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &created)
+		return created.Transaction
+	}
+
+	hotel := create(gin.H{
+		"account_id": account.ID, "amount": "300", "type": "expense", "category_id": dining.ID,
+		"description": "酒店早餐", "date": "2024-05-02", "tag_ids": []uint{trip.ID, work.ID},
+	})
+	create(gin.H{
+		"account_id": account.ID, "amount": "120", "type": "expense", "category_id": travel.ID,
+		"description": "高铁", "date": "2024-05-01", "tag_ids": []uint{trip.ID},
+	})
+	create(gin.H{
+		"account_id": account.ID, "amount": "50", "type": "expense", "category_id": dining.ID,
+		"description": "午餐", "date": "2024-05-03",
+	})
+
+	t.Run("Create With Tags", func(t *testing.T) {
+		assert.Len(t, hotel.Tags, 2)
+
+		// 其他账本或不存在的标签
+		w := doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": account.ID, "amount": "1", "type": "expense", "category_id": dining.ID,
+			"tag_ids": []uint{999},
+		})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Filter By Tag", func(t *testing.T) {
+		w := doJSON(r, "GET", fmt.Sprintf("/transactions?tag_id=%d", trip.ID), nil)
+		var page transactionPage
+		json.Unmarshal(w.Body.Bytes(), &page)
+		assert.Equal(t, int64(2), page.Total)
+
+		w = doJSON(r, "GET", fmt.Sprintf("/transactions?tag_id=%d&category_id=%d", trip.ID, dining.ID), nil)
+		json.Unmarshal(w.Body.Bytes(), &page)
+		assert.Equal(t, int64(1), page.Total)
+
+		w = doJSON(r, "GET", "/transactions?tag_id=x", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Statistics By Tag", func(t *testing.T) {
+		w := doJSON(r, "GET", "/statistics?start_date=2024-05-01&end_date=2024-05-31", nil)
+		var stats models.Statistics
+		json.Unmarshal(w.Body.Bytes(), &stats)
+		if assert.Len(t, stats.ByTag, 2) {
+			assert.Equal(t, trip.ID, stats.ByTag[0].TagID)
+			assert.Equal(t, "420.00", stats.ByTag[0].Expense.String())
+			assert.Equal(t, "300.00", stats.ByTag[1].Expense.String())
+		}
+	})
+
+	t.Run("Tag Budget", func(t *testing.T) {
+		w := doJSON(r, "POST", "/budgets", gin.H{"amount": "1000", "start_date": "2024-05-01", "end_date": "2024-05-31"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doJSON(r, "POST", "/budgets", gin.H{
+			"tag_id": trip.ID, "amount": "1000", "start_date": "2024-05-01", "end_date": "2024-05-31",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var budget models.Budget
+		json.Unmarshal(w.Body.Bytes(), &budget)
+
+		var status struct {
+			Status struct {
+				ActualExpense models.Money `json:"actual_expense"`
+			} `json:"status"`
+		}
+		w = doJSON(r, "GET", fmt.Sprintf("/budgets/%d/status", budget.ID), nil)
+		json.Unmarshal(w.Body.Bytes(), &status)
+		assert.Equal(t, "420.00", status.Status.ActualExpense.String())
+
+		// 标签和分类同时指定时取交集
+		w = doJSON(r, "POST", "/budgets", gin.H{
+			"tag_id": trip.ID, "category_id": dining.ID, "amount": "1000", "start_date": "2024-05-01", "end_date": "2024-05-31",
+		})
+		json.Unmarshal(w.Body.Bytes(), &budget)
+		w = doJSON(r, "GET", fmt.Sprintf("/budgets/%d/status", budget.ID), nil)
+		json.Unmarshal(w.Body.Bytes(), &status)
+		assert.Equal(t, "300.00", status.Status.ActualExpense.String())
+
+		// 仍有预算使用的标签不能删除
+		w = doJSON(r, "DELETE", fmt.Sprintf("/tags/%d", trip.ID), nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Update And Delete Tags", func(t *testing.T) {
+		w := doJSON(r, "PUT", fmt.Sprintf("/transactions/%d", hotel.ID), gin.H{
+			"account_id": account.ID, "amount": "300", "type": "expense", "category_id": dining.ID,
+			"description": "酒店早餐", "date": "2024-05-02", "tag_ids": []uint{work.ID},
+		})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var updated models.Transaction
+		db.Preload("Tags").First(&updated, hotel.ID)
+		if assert.Len(t, updated.Tags, 1) {
+			assert.Equal(t, work.ID, updated.Tags[0].ID)
+		}
+
+		// 未提供 tag_ids 时保留原有标签
+		w = doJSON(r, "PUT", fmt.Sprintf("/transactions/%d", hotel.ID), gin.H{
+			"account_id": account.ID, "amount": "320", "type": "expense", "category_id": dining.ID,
+			"description": "酒店早餐", "date": "2024-05-02",
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		db.Preload("Tags").First(&updated, hotel.ID)
+		assert.Len(t, updated.Tags, 1)
+
+		w = doJSON(r, "DELETE", fmt.Sprintf("/tags/%d", work.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var untagged models.Transaction
+		db.Preload("Tags").First(&untagged, hotel.ID)
+		assert.Len(t, untagged.Tags, 0)
+
+		w = doJSON(r, "GET", "/tags", nil)
+		var tags []struct {
+			Name             string `json:"name"`
+			TransactionCount int    `json:"transaction_count"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &tags)
+		if assert.Len(t, tags, 1) {
+			assert.Equal(t, 1, tags[0].TransactionCount)
+		}
+	})
+}
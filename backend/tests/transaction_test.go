@@ -0,0 +1,311 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"personal-finance/handlers"
+	"personal-finance/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTransactionRouter() (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	db := setupTestDB()
+
+	h := &handlers.TransactionHandler{DB: db}
+	r.POST("/transactions", h.CreateTransaction)
+	r.GET("/transactions", h.GetTransactions)
+	r.PUT("/transactions/:id", h.UpdateTransaction)
+	r.DELETE("/transactions/:id", h.DeleteTransaction)
+	r.POST("/transfers", h.CreateTransfer)
+
+	statisticsHandler := &handlers.StatisticsHandler{DB: db}
+	r.GET("/statistics", statisticsHandler.GetStatistics)
+
+	return r, db
+}
+
+func doJSON(r *gin.Engine, method, path string, payload interface{}) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+	req := httptest.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+type transactionPage struct {
+	Transactions []models.Transaction `json:"transactions"`
+	Total        int64                `json:"total"`
+	Page         int                  `json:"page"`
+	PageSize     int                  `json:"page_size"`
+}
+
+func balanceOf(db *gorm.DB, id uint) string {
+	var account models.Account
+	db.First(&account, id)
+	return account.Balance.String()
+}
+
+func TestTransactionBalanceConsistency(t *testing.T) {
+	r, db := setupTransactionRouter()
+
+	bank := models.Account{Name: "银行卡", Balance: 100000}
+	cash := models.Account{Name: "现金", Balance: 10000}
+	db.Create(&bank)
+	db.Create(&cash)
+	food := models.Category{Name: "餐饮", Type: "expense"}
+	salary := models.Category{Name: "工资", Type: "income"}
+	db.Create(&food)
+	db.Create(&salary)
+
+	w := doJSON(r, "POST", "/transactions", gin.H{
+		"account_id": bank.ID, "amount": 50, "type": "expense", "category_id": food.ID,
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Transaction models.Transaction `json:"transaction"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	path := fmt.Sprintf("/transactions/%d", created.Transaction.ID)
+	assert.Equal(t, "950.00", balanceOf(db, bank.ID))
+
+	t.Run("Default Date", func(t *testing.T) {
+		assert.Equal(t, time.Now().Format("2006-01-02"), created.Transaction.Date)
+	})
+
+	t.Run("Create With Date", func(t *testing.T) {
+		w := doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": cash.ID, "amount": 10, "type": "expense", "category_id": food.ID, "date": "2025-01-15",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Transaction models.Transaction `json:"transaction"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.Equal(t, "2025-01-15", resp.Transaction.Date)
+		doJSON(r, "DELETE", fmt.Sprintf("/transactions/%d", resp.Transaction.ID), nil)
+
+		w = doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": cash.ID, "amount": 10, "type": "expense", "category_id": food.ID, "date": "15/01/2025",
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Update Amount", func(t *testing.T) {
+		w := doJSON(r, "PUT", path, gin.H{
+			"account_id": bank.ID, "amount": 80, "type": "expense", "category_id": food.ID,
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "920.00", balanceOf(db, bank.ID))
+	})
+
+	t.Run("Update Account And Type", func(t *testing.T) {
+		w := doJSON(r, "PUT", path, gin.H{
+			"account_id": cash.ID, "amount": 30, "type": "income", "category_id": salary.ID,
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1000.00", balanceOf(db, bank.ID))
+		assert.Equal(t, "130.00", balanceOf(db, cash.ID))
+	})
+
+	t.Run("Update With Mismatched Category", func(t *testing.T) {
+		w := doJSON(r, "PUT", path, gin.H{
+			"account_id": cash.ID, "amount": 30, "type": "expense", "category_id": salary.ID,
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "130.00", balanceOf(db, cash.ID))
+	})
+
+	t.Run("Update With Missing Account", func(t *testing.T) {
+		w := doJSON(r, "PUT", path, gin.H{
+			"account_id": 999, "amount": 30, "type": "income", "category_id": salary.ID,
+		})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "130.00", balanceOf(db, cash.ID))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		w := doJSON(r, "DELETE", path, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "100.00", balanceOf(db, cash.ID))
+
+		w = doJSON(r, "DELETE", path, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestTransfer(t *testing.T) {
+	r, db := setupTransactionRouter()
+
+	bank := models.Account{Name: "银行卡", Balance: 100000}
+	cash := models.Account{Name: "现金", Balance: 0}
+	db.Create(&bank)
+	db.Create(&cash)
+
+	w := doJSON(r, "POST", "/transfers", gin.H{
+		"from_account_id": bank.ID, "to_account_id": cash.ID, "amount": 200,
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "800.00", balanceOf(db, bank.ID))
+	assert.Equal(t, "200.00", balanceOf(db, cash.ID))
+
+	t.Run("Same Account", func(t *testing.T) {
+		w := doJSON(r, "POST", "/transfers", gin.H{
+			"from_account_id": bank.ID, "to_account_id": bank.ID, "amount": 200,
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Shows In Both Histories", func(t *testing.T) {
+		for _, id := range []uint{bank.ID, cash.ID} {
+			w := doJSON(r, "GET", fmt.Sprintf("/transactions?account_id=%d", id), nil)
+			var page transactionPage
+			json.Unmarshal(w.Body.Bytes(), &page)
+			assert.Len(t, page.Transactions, 1)
+		}
+	})
+
+	t.Run("Excluded From Statistics", func(t *testing.T) {
+		w := doJSON(r, "GET", "/statistics", nil)
+		var stats models.Statistics
+		json.Unmarshal(w.Body.Bytes(), &stats)
+		assert.Equal(t, models.Money(0), stats.TotalIncome)
+		assert.Equal(t, models.Money(0), stats.TotalExpense)
+	})
+
+	t.Run("Delete Reverses Both Accounts", func(t *testing.T) {
+		var transfer models.Transaction
+		db.Where("type = ?", "transfer").First(&transfer)
+		w := doJSON(r, "DELETE", fmt.Sprintf("/transactions/%d", transfer.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1000.00", balanceOf(db, bank.ID))
+		assert.Equal(t, "0.00", balanceOf(db, cash.ID))
+	})
+}
+
+func TestMoney(t *testing.T) {
+	t.Run("Parse", func(t *testing.T) {
+		cases := map[string]models.Money{
+			"12.34": 1234, "12.3": 1230, "12": 1200, "-0.05": -5, ".5": 50, "0.10": 10,
+		}
+		for input, expected := range cases {
+			value, err := models.ParseMoney(input)
+			assert.Nil(t, err, input)
+			assert.Equal(t, expected, value, input)
+		}
+		for _, input := range []string{"", "1.234", "abc", "1e3", "1,000"} {
+			_, err := models.ParseMoney(input)
+			assert.NotNil(t, err, input)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var v struct {
+			A models.Money `json:"a"`
+			B models.Money `json:"b"`
+		}
+		assert.Nil(t, json.Unmarshal([]byte(`{"a":"0.10","b":0.2}`), &v))
+		assert.Equal(t, models.Money(10), v.A)
+		assert.Equal(t, models.Money(20), v.B)
+
+		body, _ := json.Marshal(v)
+		assert.JSONEq(t, `{"a":"0.10","b":"0.20"}`, string(body))
+	})
+
+	t.Run("No Drift", func(t *testing.T) {
+		var total models.Money
+		for i := 0; i < 10000; i++ {
+			total += models.Money(10)
+		}
+		assert.Equal(t, "1000.00", total.String())
+	})
+}
+
+func TestTransactionListing(t *testing.T) {
+	r, db := setupTransactionRouter()
+
+	account := models.Account{Name: "银行卡", Balance: 100000}
+	db.Create(&account)
+	food := models.Category{Name: "餐饮", Type: "expense"}
+	rent := models.Category{Name: "住房", Type: "expense"}
+	salary := models.Category{Name: "工资", Type: "income"}
+	db.Create(&food)
+	db.Create(&rent)
+	db.Create(&salary)
+
+	for i := 1; i <= 25; i++ {
+		category := food
+		description := fmt.Sprintf("午餐 %d", i)
+		if i%5 == 0 {
+			category = rent
+			description = "房租_100%"
+		}
+		doJSON(r, "POST", "/transactions", gin.H{
+			"account_id": account.ID, "amount": fmt.Sprintf("%d.50", i), "type": "expense",
+			"category_id": category.ID, "description": description, "date": fmt.Sprintf("2025-01-%02d", i),
+		})
+	}
+	doJSON(r, "POST", "/transactions", gin.H{
+		"account_id": account.ID, "amount": "5000", "type": "income", "category_id": salary.ID, "date": "2025-01-31",
+	})
+
+	list := func(query string) (int, transactionPage) {
+		w := doJSON(r, "GET", "/transactions?"+query, nil)
+		var page transactionPage
+		json.Unmarshal(w.Body.Bytes(), &page)
+		return w.Code, page
+	}
+
+	t.Run("Pagination", func(t *testing.T) {
+		code, page := list("page=2&page_size=10")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, int64(26), page.Total)
+		assert.Len(t, page.Transactions, 10)
+		assert.Equal(t, "2025-01-16", page.Transactions[0].Date)
+
+		_, page = list("page=3&page_size=10")
+		assert.Len(t, page.Transactions, 6)
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		_, page := list("start_date=2025-01-10&end_date=2025-01-19")
+		assert.Equal(t, int64(10), page.Total)
+
+		_, page = list(fmt.Sprintf("category_id=%d,%d", rent.ID, salary.ID))
+		assert.Equal(t, int64(6), page.Total)
+
+		_, page = list(fmt.Sprintf("category_id=%d&category_id=%d", rent.ID, salary.ID))
+		assert.Equal(t, int64(6), page.Total)
+
+		_, page = list("min_amount=10&max_amount=20.50")
+		assert.Equal(t, int64(11), page.Total)
+
+		_, page = list("description=_100%25")
+		assert.Equal(t, int64(5), page.Total)
+	})
+
+	t.Run("Sort", func(t *testing.T) {
+		_, page := list("sort_by=amount&order=asc&page_size=1")
+		assert.Equal(t, "1.50", page.Transactions[0].Amount.String())
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		for _, query := range []string{"page=0", "page_size=1000", "sort_by=description", "min_amount=abc", "start_date=2025/01/01"} {
+			code, _ := list(query)
+			assert.Equal(t, http.StatusBadRequest, code, query)
+		}
+	})
+}
@@ -1,21 +1,38 @@
 import React from 'react';
-import { BrowserRouter as Router, Routes, Route } from 'react-router-dom';
+import { BrowserRouter as Router, Routes, Route, Navigate } from 'react-router-dom';
 import Layout from './components/Layout';
 import Dashboard from './pages/Dashboard';
 import AccountPage from './pages/AccountPage';
 import TransactionPage from './pages/TransactionPage';
+import LoginPage from './pages/LoginPage';
+import { getToken } from './services/api';
 import './App.css';
 
+// 未登录时跳转到登录页
+const RequireAuth: React.FC<{ children: React.ReactElement }> = ({ children }) => {
+  return getToken() ? children : <Navigate to="/login" replace />;
+};
+
 function App() {
   return (
     <Router>
-      <Layout>
-        <Routes>
-          <Route path="/" element={<Dashboard />} />
-          <Route path="/accounts" element={<AccountPage />} />
-          <Route path="/transactions" element={<TransactionPage />} />
-        </Routes>
-      </Layout>
+      <Routes>
+        <Route path="/login" element={<LoginPage />} />
+        <Route
+          path="*"
+          element={
+            <RequireAuth>
+              <Layout>
+                <Routes>
+                  <Route path="/" element={<Dashboard />} />
+                  <Route path="/accounts" element={<AccountPage />} />
+                  <Route path="/transactions" element={<TransactionPage />} />
+                </Routes>
+              </Layout>
+            </RequireAuth>
+          }
+        />
+      </Routes>
     </Router>
   );
 }
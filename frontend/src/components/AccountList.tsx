@@ -43,7 +43,7 @@ const AccountList: React.FC = () => {
       title: '余额',
       dataIndex: 'balance',
       key: 'balance',
-      render: (balance: number) => `¥${balance.toFixed(2)}`,
+      render: (balance: string) => `¥${balance}`,
     },
     {
       title: '创建时间',
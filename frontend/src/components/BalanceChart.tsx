@@ -0,0 +1,81 @@
+import React from 'react';
+import { Empty } from 'antd';
+
+interface Series {
+  name: string;
+  values: number[];
+  color: string;
+}
+
+interface BalanceChartProps {
+  dates: string[];
+  series: Series[];
+  height?: number;
+}
+
+const WIDTH = 800;
+const PADDING = { top: 16, right: 16, bottom: 28, left: 72 };
+
+// 简单的 SVG 折线图，用于展示余额走势
+const BalanceChart: React.FC<BalanceChartProps> = ({ dates, series, height = 260 }) => {
+  if (dates.length === 0 || series.length === 0) {
+    return <Empty description="暂无数据" />;
+  }
+
+  const values = series.flatMap((s) => s.values);
+  let min = Math.min(0, ...values);
+  let max = Math.max(0, ...values);
+  if (min === max) {
+    max = min + 1;
+  }
+  const plotWidth = WIDTH - PADDING.left - PADDING.right;
+  const plotHeight = height - PADDING.top - PADDING.bottom;
+  const x = (i: number) => PADDING.left + (dates.length === 1 ? plotWidth / 2 : (i / (dates.length - 1)) * plotWidth);
+  const y = (value: number) => PADDING.top + ((max - value) / (max - min)) * plotHeight;
+
+  // 横轴最多标注 6 个日期
+  const step = Math.max(1, Math.ceil(dates.length / 6));
+  const ticks = [min, (min + max) / 2, max];
+
+  return (
+    <div>
+      <svg viewBox={`0 0 ${WIDTH} ${height}`} width="100%" role="img">
+        {ticks.map((tick) => (
+          <g key={tick}>
+            <line x1={PADDING.left} x2={WIDTH - PADDING.right} y1={y(tick)} y2={y(tick)} stroke="#f0f0f0" />
+            <text x={PADDING.left - 8} y={y(tick) + 4} textAnchor="end" fontSize={12} fill="#8c8c8c">
+              {tick.toFixed(0)}
+            </text>
+          </g>
+        ))}
+        {dates.map((date, i) =>
+          i % step === 0 || i === dates.length - 1 ? (
+            <text key={date} x={x(i)} y={height - 8} textAnchor="middle" fontSize={12} fill="#8c8c8c">
+              {date}
+            </text>
+          ) : null
+        )}
+        {series.map((s) => (
+          <polyline
+            key={s.name}
+            fill="none"
+            stroke={s.color}
+            strokeWidth={2}
+            points={s.values.map((value, i) => `${x(i)},${y(value)}`).join(' ')}
+          >
+            <title>{s.name}</title>
+          </polyline>
+        ))}
+      </svg>
+      <div style={{ textAlign: 'center' }}>
+        {series.map((s) => (
+          <span key={s.name} style={{ marginRight: 16, color: s.color }}>
+            ● {s.name}
+          </span>
+        ))}
+      </div>
+    </div>
+  );
+};
+
+export default BalanceChart;
@@ -1,16 +1,48 @@
-import React from 'react';
-import { Layout as AntLayout, Menu } from 'antd';
-import { Link, useLocation } from 'react-router-dom';
+import React, { useEffect, useState } from 'react';
+import { Layout as AntLayout, Menu, Button, Select } from 'antd';
+import { Link, useLocation, useNavigate } from 'react-router-dom';
 import {
   DashboardOutlined,
   WalletOutlined,
   TransactionOutlined,
+  LogoutOutlined,
 } from '@ant-design/icons';
+import { authApi, ledgerApi, getLedgerId, setLedgerId } from '../services/api';
+import { Ledger } from '../types';
 
 const { Header, Content, Sider } = AntLayout;
 
 const Layout: React.FC<{ children: React.ReactNode }> = ({ children }) => {
   const location = useLocation();
+  const navigate = useNavigate();
+  const [ledgers, setLedgers] = useState<Ledger[]>([]);
+  const [ledgerId, setCurrentLedgerId] = useState<number>();
+
+  useEffect(() => {
+    ledgerApi.getAll().then(data => {
+      setLedgers(data);
+      const saved = Number(getLedgerId());
+      const current = data.find(ledger => ledger.id === saved) || data[0];
+      if (current) {
+        setLedgerId(current.id);
+        setCurrentLedgerId(current.id);
+      }
+    });
+  }, []);
+
+  // 切换账本后重新加载页面数据
+  const handleLedgerChange = (id: number) => {
+    setLedgerId(id);
+    window.location.reload();
+  };
+
+  const handleLogout = async () => {
+    try {
+      await authApi.logout();
+    } finally {
+      navigate('/login');
+    }
+  };
 
   const menuItems = [
     {
@@ -36,6 +68,15 @@ const Layout: React.FC<{ children: React.ReactNode }> = ({ children }) => {
         <div style={{ float: 'left', width: 200, height: 31, margin: '16px 24px 16px 0', background: 'rgba(255, 255, 255, 0.2)' }}>
           <h1 style={{ margin: 0, color: '#1890ff', textAlign: 'center' }}>个人记账系统</h1>
         </div>
+        <Select
+          style={{ width: 160, margin: '16px 0' }}
+          value={ledgerId}
+          onChange={handleLedgerChange}
+          options={ledgers.map(ledger => ({ value: ledger.id, label: ledger.name }))}
+        />
+        <Button type="link" icon={<LogoutOutlined />} style={{ float: 'right', margin: 16 }} onClick={handleLogout}>
+          退出登录
+        </Button>
       </Header>
       <AntLayout>
         <Sider width={200} style={{ background: '#fff' }}>
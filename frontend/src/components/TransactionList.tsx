@@ -1,5 +1,6 @@
-import React, { useEffect, useState } from 'react';
+import React, { useCallback, useEffect, useState } from 'react';
 import { Table, Card, Button, Modal, Form, Input, Select, message } from 'antd';
+import type { TablePaginationConfig } from 'antd/es/table';
 import { Transaction, Account, Category } from '../types';
 import { transactionApi, accountApi, categoryApi } from '../services/api';
 
@@ -7,29 +8,38 @@ const { Option } = Select;
 
 const TransactionList: React.FC = () => {
   const [transactions, setTransactions] = useState<Transaction[]>([]);
+  const [total, setTotal] = useState(0);
+  const [page, setPage] = useState(1);
+  const [pageSize, setPageSize] = useState(20);
   const [accounts, setAccounts] = useState<Account[]>([]);
   const [categories, setCategories] = useState<Category[]>([]);
   const [isModalVisible, setIsModalVisible] = useState(false);
   const [form] = Form.useForm();
 
-  const fetchData = async () => {
+  const fetchData = useCallback(async () => {
     try {
       const [transactionsRes, accountsRes, categoriesRes] = await Promise.all([
-        transactionApi.getAll(),
+        transactionApi.list({ page, page_size: pageSize }),
         accountApi.getAll(),
         categoryApi.getAll(),
       ]);
-      setTransactions(Array.isArray(transactionsRes) ? transactionsRes : []);
+      setTransactions(transactionsRes.transactions || []);
+      setTotal(transactionsRes.total);
       setAccounts(Array.isArray(accountsRes) ? accountsRes : []);
       setCategories(Array.isArray(categoriesRes) ? categoriesRes : []);
     } catch (error) {
       message.error('获取数据失败');
     }
-  };
+  }, [page, pageSize]);
 
   useEffect(() => {
     fetchData();
-  }, []);
+  }, [fetchData]);
+
+  const handleTableChange = (pagination: TablePaginationConfig) => {
+    setPage(pagination.current || 1);
+    setPageSize(pagination.pageSize || pageSize);
+  };
 
   const handleCreate = async (values: any) => {
     try {
@@ -62,8 +72,8 @@ const TransactionList: React.FC = () => {
       title: '金额',
       dataIndex: 'amount',
       key: 'amount',
-      render: (amount: number, record: Transaction) => 
-        `${record.type === 'expense' ? '-' : '+'}¥${amount.toFixed(2)}`,
+      render: (amount: string, record: Transaction) => 
+        `${record.type === 'expense' ? '-' : '+'}¥${amount}`,
     },
     {
       title: '描述',
@@ -71,10 +81,9 @@ const TransactionList: React.FC = () => {
       key: 'description',
     },
     {
-      title: '时间',
-      dataIndex: 'created_at',
-      key: 'created_at',
-      render: (date: string) => new Date(date).toLocaleString(),
+      title: '日期',
+      dataIndex: 'date',
+      key: 'date',
     },
   ];
 
@@ -87,6 +96,8 @@ const TransactionList: React.FC = () => {
         dataSource={transactions}
         columns={columns}
         rowKey="id"
+        onChange={handleTableChange}
+        pagination={{ current: page, pageSize, total }}
       />
       
       <Modal
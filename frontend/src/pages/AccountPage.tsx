@@ -1,14 +1,23 @@
 import React, { useEffect, useState } from 'react';
-import { Table, Button, Modal, Form, Input, message, Card, Popconfirm } from 'antd';
+import { Table, Button, Modal, Form, Input, InputNumber, Select, message, Card, Popconfirm } from 'antd';
 import { PlusOutlined, EditOutlined, DeleteOutlined } from '@ant-design/icons';
-import { Account } from '../types';
+import { Account, AccountType } from '../types';
 import { accountApi } from '../services/api';
 
+const accountTypeLabels: Record<AccountType, string> = {
+  cash: '现金',
+  debit: '借记卡',
+  credit_card: '信用卡',
+  loan: '贷款',
+  investment: '投资',
+};
+
 const AccountPage: React.FC = () => {
   const [accounts, setAccounts] = useState<Account[]>([]);
   const [isModalVisible, setIsModalVisible] = useState(false);
   const [editingAccount, setEditingAccount] = useState<Account | null>(null);
   const [form] = Form.useForm();
+  const accountType: AccountType = Form.useWatch('type', form);
 
   const fetchAccounts = async () => {
     try {
@@ -39,23 +48,36 @@ const AccountPage: React.FC = () => {
       const values = await form.validateFields();
       console.log('Form values:', values);
 
-      // 确保余额是数字类型
-      const balance = parseFloat(values.balance);
-      if (isNaN(balance)) {
-        throw new Error('请输入有效的余额');
-      }
-
       if (editingAccount) {
-        const updateResponse = await accountApi.update(editingAccount.id, {
-          ...values,
-          balance: balance
-        });
+        // 余额不能直接修改，期初余额单独提交，余额随之变动
+        const { opening_balance, ...rest } = values;
+        // 未提交的字段保持不变，改为其他类型时需显式清除不适用的信用卡和还款设置
+        if (rest.type !== 'credit_card') {
+          rest.credit_limit = '0';
+          rest.statement_day = 0;
+        }
+        if (rest.type !== 'credit_card' && rest.type !== 'loan') {
+          rest.payment_due_day = 0;
+        }
+        const updateResponse = await accountApi.update(editingAccount.id, rest);
         console.log('Update response:', updateResponse);
+        const openingBalance = String(opening_balance);
+        if (openingBalance !== editingAccount.opening_balance) {
+          if (isNaN(parseFloat(openingBalance))) {
+            throw new Error('请输入有效的期初余额');
+          }
+          await accountApi.setOpeningBalance(editingAccount.id, openingBalance);
+        }
         message.success('账户更新成功');
       } else {
+        // 余额以十进制字符串提交，由后端精确解析
+        const balance = String(values.balance);
+        if (isNaN(parseFloat(balance))) {
+          throw new Error('请输入有效的余额');
+        }
         const createResponse = await accountApi.create({
-          name: values.name,
-          balance: balance
+          ...values,
+          balance: balance,
         });
         console.log('Create response:', createResponse);
         message.success('账户创建成功');
@@ -88,11 +110,23 @@ const AccountPage: React.FC = () => {
       dataIndex: 'name',
       key: 'name',
     },
+    {
+      title: '类型',
+      dataIndex: 'type',
+      key: 'type',
+      render: (type: AccountType) => accountTypeLabels[type] || type,
+    },
     {
       title: '账户余额',
       dataIndex: 'balance',
       key: 'balance',
-      render: (balance: number) => `¥${balance.toFixed(2)}`,
+      render: (balance: string, record: Account) => `${balance} ${record.currency}`,
+    },
+    {
+      title: '剩余额度',
+      dataIndex: 'available_credit',
+      key: 'available_credit',
+      render: (available?: string) => available || '-',
     },
     {
       title: '操作',
@@ -149,7 +183,7 @@ const AccountPage: React.FC = () => {
           setEditingAccount(null);
         }}
       >
-        <Form form={form} layout="vertical" initialValues={{ type: 'bank' }}>
+        <Form form={form} layout="vertical" initialValues={{ type: 'debit' }}>
           <Form.Item
             name="name"
             label="账户名称"
@@ -157,20 +191,24 @@ const AccountPage: React.FC = () => {
           >
             <Input />
           </Form.Item>
+          <Form.Item name="type" label="账户类型" rules={[{ required: true }]}>
+            <Select
+              options={Object.entries(accountTypeLabels).map(([value, label]) => ({ value, label }))}
+            />
+          </Form.Item>
           <Form.Item
-            name="balance"
-            label="账户余额"
+            name={editingAccount ? 'opening_balance' : 'balance'}
+            label={editingAccount ? '期初余额' : '账户余额'}
+            tooltip={editingAccount
+              ? '余额等于期初余额加上所有交易，修改期初余额时余额随之变动；与对账单的差额请通过对账调整'
+              : '信用卡和贷款的欠款请填写负数'}
             rules={[
-              { required: true, message: '请输入账户余额' },
+              { required: true, message: editingAccount ? '请输入期初余额' : '请输入账户余额' },
               {
                 validator: async (_, value) => {
                   if (isNaN(value) || value === '') {
                     throw new Error('请输入有效的数字');
                   }
-                  const numValue = parseFloat(value);
-                  if (numValue < 0) {
-                    throw new Error('余额不能为负数');
-                  }
                 }
               }
             ]}
@@ -182,12 +220,27 @@ const AccountPage: React.FC = () => {
             />
           </Form.Item>
           <Form.Item
-            name="type"
-            label="账户类型"
-            hidden
+            name="currency"
+            label="币种"
+            tooltip="留空则使用本位币"
           >
-            <Input />
+            <Input placeholder="CNY" maxLength={3} />
           </Form.Item>
+          {accountType === 'credit_card' && (
+            <>
+              <Form.Item name="credit_limit" label="信用额度" preserve={false}>
+                <Input type="number" prefix="¥" step="0.01" />
+              </Form.Item>
+              <Form.Item name="statement_day" label="账单日" preserve={false}>
+                <InputNumber min={1} max={31} />
+              </Form.Item>
+            </>
+          )}
+          {(accountType === 'credit_card' || accountType === 'loan') && (
+            <Form.Item name="payment_due_day" label="还款日" preserve={false}>
+              <InputNumber min={1} max={31} />
+            </Form.Item>
+          )}
         </Form>
       </Modal>
     </div>
@@ -1,20 +1,25 @@
 import React, { useEffect, useState } from 'react';
-import { Card, Statistic, Row, Col } from 'antd';
-import { Account, Transaction } from '../types';
-import { accountApi, transactionApi } from '../services/api';
+import { Card, Statistic, Row, Col, Radio } from 'antd';
+import { AccountSummary, BalanceHistory, BalanceInterval, Statistics } from '../types';
+import { accountApi, statisticsApi } from '../services/api';
+import BalanceChart from '../components/BalanceChart';
+
+const SERIES_COLORS = ['#1677ff', '#52c41a', '#fa8c16', '#eb2f96', '#722ed1', '#13c2c2'];
 
 const Dashboard: React.FC = () => {
-  const [accounts, setAccounts] = useState<Account[]>([]);
-  const [transactions, setTransactions] = useState<Transaction[]>([]);
+  const [summary, setSummary] = useState<AccountSummary | null>(null);
+  const [statistics, setStatistics] = useState<Statistics | null>(null);
+  const [historyInterval, setHistoryInterval] = useState<BalanceInterval>('monthly');
+  const [history, setHistory] = useState<BalanceHistory | null>(null);
 
   const fetchData = async () => {
     try {
-      const [accountsRes, transactionsRes] = await Promise.all([
-        accountApi.getAll(),
-        transactionApi.getAll(),
+      const [summaryRes, statisticsRes] = await Promise.all([
+        accountApi.summary(),
+        statisticsApi.get(),
       ]);
-      setAccounts(Array.isArray(accountsRes) ? accountsRes : []);
-      setTransactions(Array.isArray(transactionsRes) ? transactionsRes : []);
+      setSummary(summaryRes);
+      setStatistics(statisticsRes);
     } catch (error) {
       console.error('获取数据失败:', error);
     }
@@ -24,13 +29,32 @@ const Dashboard: React.FC = () => {
     fetchData();
   }, []);
 
-  const totalBalance = accounts.reduce((sum, account) => sum + account.balance, 0);
-  const totalIncome = transactions
-    .filter(t => t.type === 'income')
-    .reduce((sum, t) => sum + t.amount, 0);
-  const totalExpense = transactions
-    .filter(t => t.type === 'expense')
-    .reduce((sum, t) => sum + t.amount, 0);
+  useEffect(() => {
+    accountApi.balanceHistory({ interval: historyInterval })
+      .then(setHistory)
+      .catch((error) => console.error('获取余额走势失败:', error));
+  }, [historyInterval]);
+
+  // 净资产走势之外，同时展示各账户的余额（账户币种）
+  const historySeries = history
+    ? [
+        { name: '净资产', values: history.total.map(Number), color: '#3f8600' },
+        ...history.accounts.map((account, i) => ({
+          name: account.name,
+          values: account.balances.map(Number),
+          color: SERIES_COLORS[i % SERIES_COLORS.length],
+        })),
+      ]
+    : [];
+
+  // 资产、负债和净资产由后端按本位币汇总，信用卡和贷款计入负债
+  const accounts = summary?.accounts || [];
+  const totalAssets = summary?.total_assets || '0.00';
+  const totalLiabilities = summary?.total_liabilities || '0.00';
+  const netWorth = summary?.net_worth || '0.00';
+  // 收支总额由后端统计（默认最近一年）
+  const totalIncome = statistics?.total_income || '0.00';
+  const totalExpense = statistics?.total_expense || '0.00';
 
   return (
     <div>
@@ -39,18 +63,21 @@ const Dashboard: React.FC = () => {
         <Col span={8}>
           <Card>
             <Statistic
-              title="总资产"
-              value={totalBalance}
+              title="净资产"
+              value={netWorth}
               precision={2}
               prefix="¥"
               valueStyle={{ color: '#3f8600' }}
             />
+            <div style={{ marginTop: 8, color: '#8c8c8c' }}>
+              总资产 ¥{totalAssets} · 总负债 ¥{totalLiabilities}
+            </div>
           </Card>
         </Col>
         <Col span={8}>
           <Card>
             <Statistic
-              title="总收入"
+              title="近一年收入"
               value={totalIncome}
               precision={2}
               prefix="¥"
@@ -61,7 +88,7 @@ const Dashboard: React.FC = () => {
         <Col span={8}>
           <Card>
             <Statistic
-              title="总支出"
+              title="近一年支出"
               value={totalExpense}
               precision={2}
               prefix="¥"
@@ -70,6 +97,19 @@ const Dashboard: React.FC = () => {
           </Card>
         </Col>
       </Row>
+      <Card
+        title="余额走势"
+        style={{ marginTop: 24 }}
+        extra={
+          <Radio.Group value={historyInterval} onChange={(e) => setHistoryInterval(e.target.value)} size="small">
+            <Radio.Button value="daily">日</Radio.Button>
+            <Radio.Button value="weekly">周</Radio.Button>
+            <Radio.Button value="monthly">月</Radio.Button>
+          </Radio.Group>
+        }
+      >
+        <BalanceChart dates={history?.dates || []} series={historySeries} />
+      </Card>
       {accounts.length > 0 && (
         <Card title="金融账户" style={{ marginTop: 24 }}>
           <Row gutter={16}>
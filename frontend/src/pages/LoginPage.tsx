@@ -0,0 +1,61 @@
+import React, { useState } from 'react';
+import { Card, Form, Input, Button, Tabs, message } from 'antd';
+import { UserOutlined, LockOutlined } from '@ant-design/icons';
+import { useNavigate } from 'react-router-dom';
+import { authApi } from '../services/api';
+
+const LoginPage: React.FC = () => {
+  const [mode, setMode] = useState<'login' | 'register'>('login');
+  const [loading, setLoading] = useState(false);
+  const navigate = useNavigate();
+
+  const handleSubmit = async (values: { username: string; password: string }) => {
+    setLoading(true);
+    try {
+      if (mode === 'login') {
+        await authApi.login(values.username, values.password);
+      } else {
+        await authApi.register(values.username, values.password);
+      }
+      navigate('/');
+    } catch (error: any) {
+      message.error(error.response?.data?.error || (mode === 'login' ? '登录失败' : '注册失败'));
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div style={{ display: 'flex', justifyContent: 'center', alignItems: 'center', minHeight: '100vh', background: '#f0f2f5' }}>
+      <Card title="个人记账系统" style={{ width: 360 }}>
+        <Tabs
+          activeKey={mode}
+          onChange={(key) => setMode(key as 'login' | 'register')}
+          items={[
+            { key: 'login', label: '登录' },
+            { key: 'register', label: '注册' },
+          ]}
+        />
+        <Form layout="vertical" onFinish={handleSubmit}>
+          <Form.Item
+            name="username"
+            rules={[{ required: true, min: 3, message: '请输入至少 3 个字符的用户名' }]}
+          >
+            <Input prefix={<UserOutlined />} placeholder="用户名" />
+          </Form.Item>
+          <Form.Item
+            name="password"
+            rules={[{ required: true, min: 8, message: '请输入至少 8 位密码' }]}
+          >
+            <Input.Password prefix={<LockOutlined />} placeholder="密码" />
+          </Form.Item>
+          <Button type="primary" htmlType="submit" block loading={loading}>
+            {mode === 'login' ? '登录' : '注册'}
+          </Button>
+        </Form>
+      </Card>
+    </div>
+  );
+};
+
+export default LoginPage;
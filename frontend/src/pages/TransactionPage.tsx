@@ -1,28 +1,32 @@
-import React, { useEffect, useState } from 'react';
-import { Table, Button, Modal, Form, Input, Select, message, Card, DatePicker } from 'antd';
+import React, { useCallback, useEffect, useState } from 'react';
+import { Table, Button, Modal, Form, Input, Select, message, Card, DatePicker, Space } from 'antd';
+import type { TablePaginationConfig } from 'antd/es/table';
+import type { SorterResult } from 'antd/es/table/interface';
 import { PlusOutlined } from '@ant-design/icons';
 import dayjs from 'dayjs';
-import { Transaction, Account, Category } from '../types';
-import { transactionApi, accountApi, categoryApi } from '../services/api';
+import { Transaction, TransactionQuery, Account, Category } from '../types';
+import { transactionApi, transferApi, accountApi, categoryApi } from '../services/api';
 
 const { Option } = Select;
+const { RangePicker } = DatePicker;
 
 const TransactionPage: React.FC = () => {
   const [transactions, setTransactions] = useState<Transaction[]>([]);
+  const [total, setTotal] = useState(0);
+  const [loading, setLoading] = useState(false);
+  const [query, setQuery] = useState<TransactionQuery>({ page: 1, page_size: 10 });
   const [accounts, setAccounts] = useState<Account[]>([]);
   const [categories, setCategories] = useState<Category[]>([]);
   const [isModalVisible, setIsModalVisible] = useState(false);
   const [form] = Form.useForm();
   const transactionType = Form.useWatch('type', form);
 
-  const fetchData = async () => {
+  const fetchLookups = async () => {
     try {
-      const [transactionsRes, accountsRes, categoriesRes] = await Promise.all([
-        transactionApi.getAll(),
+      const [accountsRes, categoriesRes] = await Promise.all([
         accountApi.getAll(),
         categoryApi.getAll(),
       ]);
-      setTransactions(Array.isArray(transactionsRes) ? transactionsRes : []);
       setAccounts(Array.isArray(accountsRes) ? accountsRes : []);
       setCategories(Array.isArray(categoriesRes) ? categoriesRes : []);
     } catch (error) {
@@ -30,17 +34,69 @@ const TransactionPage: React.FC = () => {
     }
   };
 
+  // 按当前筛选、排序和页码从后端分页获取交易
+  const fetchData = useCallback(async () => {
+    setLoading(true);
+    try {
+      const res = await transactionApi.list(query);
+      setTransactions(res.transactions || []);
+      setTotal(res.total);
+    } catch (error) {
+      message.error('获取交易记录失败');
+    } finally {
+      setLoading(false);
+    }
+  }, [query]);
+
   useEffect(() => {
-    fetchData();
+    fetchLookups();
   }, []);
 
+  useEffect(() => {
+    fetchData();
+  }, [fetchData]);
+
+  // 修改筛选条件时回到第一页
+  const updateFilters = (filters: TransactionQuery) => {
+    setQuery(prev => ({ ...prev, ...filters, page: 1 }));
+  };
+
+  const handleTableChange = (
+    pagination: TablePaginationConfig,
+    _filters: unknown,
+    sorter: SorterResult<Transaction> | SorterResult<Transaction>[],
+  ) => {
+    const sort = Array.isArray(sorter) ? sorter[0] : sorter;
+    setQuery(prev => ({
+      ...prev,
+      page: pagination.current || 1,
+      page_size: pagination.pageSize || prev.page_size,
+      sort_by: sort?.order ? (sort.field as TransactionQuery['sort_by']) : undefined,
+      order: sort?.order ? (sort.order === 'ascend' ? 'asc' : 'desc') : undefined,
+    }));
+  };
+
   const handleModalOk = async () => {
     try {
       const values = await form.validateFields();
+      if (values.type === 'transfer') {
+        await transferApi.create({
+          from_account_id: values.account_id,
+          to_account_id: values.to_account_id,
+          amount: String(values.amount),
+          description: values.note || '',
+          date: values.date ? values.date.format('YYYY-MM-DD') : undefined,
+        });
+        message.success('转账记录创建成功');
+        setIsModalVisible(false);
+        form.resetFields();
+        fetchData();
+        return;
+      }
       const transactionData = {
         account_id: values.account_id,
         category_id: values.category_id,
-        amount: parseFloat(values.amount),
+        amount: String(values.amount),
         type: values.type,
         description: values.note || '',
         date: values.date ? values.date.format('YYYY-MM-DD') : undefined,
@@ -60,30 +116,34 @@ const TransactionPage: React.FC = () => {
       title: '日期',
       dataIndex: 'date',
       key: 'date',
+      sorter: true,
       render: (date: string) => dayjs(date).format('YYYY-MM-DD'),
     },
     {
       title: '类型',
       dataIndex: 'type',
       key: 'type',
-      render: (type: string) => (type === 'income' ? '收入' : '支出'),
+      render: (type: Transaction['type']) => ({ income: '收入', expense: '支出', transfer: '转账' }[type]),
     },
     {
       title: '金额',
       dataIndex: 'amount',
       key: 'amount',
-      render: (amount: number, record: Transaction) => {
-        const color = record.type === 'income' ? '#52c41a' : '#f5222d';
-        return <span style={{ color }}>{`¥${amount.toFixed(2)}`}</span>;
+      sorter: true,
+      render: (amount: string, record: Transaction) => {
+        const color = { income: '#52c41a', expense: '#f5222d', transfer: '#1677ff' }[record.type];
+        return <span style={{ color }}>{`${amount} ${record.currency || ''}`}</span>;
       },
     },
     {
       title: '账户',
       dataIndex: 'account_id',
       key: 'account_id',
-      render: (accountId: number) => {
-        const account = accounts.find(a => a.id === accountId);
-        return account?.name || '-';
+      render: (accountId: number, record: Transaction) => {
+        const name = (id?: number) => accounts.find(a => a.id === id)?.name || '-';
+        return record.type === 'transfer'
+          ? `${name(accountId)} → ${name(record.to_account_id)}`
+          : name(accountId);
       },
     },
     {
@@ -112,11 +172,55 @@ const TransactionPage: React.FC = () => {
           </Button>
         }
       >
+        <Space wrap style={{ marginBottom: 16 }}>
+          <RangePicker
+            onChange={(dates) => updateFilters({
+              start_date: dates?.[0]?.format('YYYY-MM-DD'),
+              end_date: dates?.[1]?.format('YYYY-MM-DD'),
+            })}
+          />
+          <Select
+            mode="multiple"
+            allowClear
+            placeholder="分类"
+            style={{ minWidth: 200 }}
+            onChange={(ids: number[]) => updateFilters({ category_id: ids })}
+          >
+            {categories.map(category => (
+              <Option key={category.id} value={category.id}>
+                {category.name}
+              </Option>
+            ))}
+          </Select>
+          <Input
+            placeholder="最小金额"
+            style={{ width: 110 }}
+            onBlur={(e) => updateFilters({ min_amount: e.target.value || undefined })}
+          />
+          <Input
+            placeholder="最大金额"
+            style={{ width: 110 }}
+            onBlur={(e) => updateFilters({ max_amount: e.target.value || undefined })}
+          />
+          <Input.Search
+            allowClear
+            placeholder="搜索备注"
+            onSearch={(value) => updateFilters({ description: value || undefined })}
+          />
+        </Space>
         <Table
           dataSource={transactions}
           columns={columns}
           rowKey="id"
-          pagination={{ pageSize: 10 }}
+          loading={loading}
+          onChange={handleTableChange}
+          pagination={{
+            current: query.page,
+            pageSize: query.page_size,
+            total,
+            showSizeChanger: true,
+            showTotal: (count) => `共 ${count} 条`,
+          }}
         />
       </Card>
 
@@ -146,6 +250,7 @@ const TransactionPage: React.FC = () => {
             <Select>
               <Option value="income">收入</Option>
               <Option value="expense">支出</Option>
+              <Option value="transfer">转账</Option>
             </Select>
           </Form.Item>
           <Form.Item
@@ -168,21 +273,37 @@ const TransactionPage: React.FC = () => {
               ))}
             </Select>
           </Form.Item>
-          <Form.Item
-            name="category_id"
-            label="分类"
-            rules={[{ required: true, message: '请选择分类' }]}
-          >
-            <Select placeholder={transactionType ? '请选择分类' : '请先选择交易类型'}>
-              {categories
-                .filter(c => !transactionType || c.type === transactionType)
-                .map(category => (
-                  <Option key={category.id} value={category.id}>
-                    {category.name}
+          {transactionType === 'transfer' ? (
+            <Form.Item
+              name="to_account_id"
+              label="转入账户"
+              rules={[{ required: true, message: '请选择转入账户' }]}
+            >
+              <Select>
+                {accounts.map(account => (
+                  <Option key={account.id} value={account.id}>
+                    {account.name}
                   </Option>
                 ))}
-            </Select>
-          </Form.Item>
+              </Select>
+            </Form.Item>
+          ) : (
+            <Form.Item
+              name="category_id"
+              label="分类"
+              rules={[{ required: true, message: '请选择分类' }]}
+            >
+              <Select placeholder={transactionType ? '请选择分类' : '请先选择交易类型'}>
+                {categories
+                  .filter(c => !transactionType || c.type === transactionType)
+                  .map(category => (
+                    <Option key={category.id} value={category.id}>
+                      {category.name}
+                    </Option>
+                  ))}
+              </Select>
+            </Form.Item>
+          )}
           <Form.Item
             name="date"
             label="日期"
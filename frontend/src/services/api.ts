@@ -1,5 +1,24 @@
 import axios from 'axios';
-import { Account, Transaction, Category } from '../types';
+import {
+  Account,
+  AccountSummary,
+  BalanceHistory,
+  BalanceInterval,
+  CreditCardStatement,
+  Reconciliation,
+  Transaction,
+  TransactionQuery,
+  TransactionPage,
+  Category,
+  Statistics,
+  TransferInput,
+  User,
+  AuthResponse,
+  Ledger,
+  LedgerMember,
+  LedgerRole,
+  Tag,
+} from '../types';
 
 const api = axios.create({
   baseURL: 'http://localhost:8080/api/v1',
@@ -8,16 +27,96 @@ const api = axios.create({
   },
 });
 
+const TOKEN_KEY = 'token';
+const LEDGER_KEY = 'ledger_id';
+
+export const getToken = () => localStorage.getItem(TOKEN_KEY);
+
+export const getLedgerId = () => localStorage.getItem(LEDGER_KEY);
+
+export const setLedgerId = (id: number) => localStorage.setItem(LEDGER_KEY, String(id));
+
+// 每个请求都带上登录令牌和当前账本
+api.interceptors.request.use(config => {
+  const token = getToken();
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`;
+  }
+  const ledgerId = getLedgerId();
+  if (ledgerId) {
+    config.headers['X-Ledger-ID'] = ledgerId;
+  }
+  return config;
+});
+
+// 令牌失效时回到登录页
+api.interceptors.response.use(
+  res => res,
+  error => {
+    if (error.response?.status === 401 && window.location.pathname !== '/login') {
+      localStorage.removeItem(TOKEN_KEY);
+      localStorage.removeItem(LEDGER_KEY);
+      window.location.href = '/login';
+    }
+    return Promise.reject(error);
+  },
+);
+
+interface AuthAPI {
+  login: (username: string, password: string) => Promise<AuthResponse>;
+  register: (username: string, password: string) => Promise<AuthResponse>;
+  logout: () => Promise<void>;
+  me: () => Promise<User>;
+}
+
+interface LedgerAPI {
+  getAll: () => Promise<Ledger[]>;
+  create: (name: string) => Promise<Ledger>;
+  members: (id: number) => Promise<LedgerMember[]>;
+  addMember: (id: number, username: string, role: LedgerRole) => Promise<LedgerMember>;
+  updateMember: (id: number, userId: number, role: LedgerRole) => Promise<LedgerMember>;
+  removeMember: (id: number, userId: number) => Promise<void>;
+}
+
 interface AccountAPI {
   getAll: () => Promise<Account[]>;
+  summary: () => Promise<AccountSummary>;
+  balanceHistory: (params?: {
+    interval?: BalanceInterval;
+    start_date?: string;
+    end_date?: string;
+    account_id?: number[];
+  }) => Promise<BalanceHistory>;
   create: (data: Partial<Account>) => Promise<Account>;
   update: (id: number, data: Partial<Account>) => Promise<Account>;
+  setOpeningBalance: (id: number, openingBalance: string) => Promise<Account>;
   delete: (id: number) => Promise<void>;
+  statements: (id: number, count?: number) => Promise<CreditCardStatement[]>;
+  statement: (id: number, closingDate: string) => Promise<CreditCardStatement>;
+}
+
+interface ReconciliationAPI {
+  start: (accountId: number, statementDate: string, statementBalance: string) => Promise<Reconciliation>;
+  list: (accountId: number) => Promise<Reconciliation[]>;
+  get: (id: number) => Promise<Reconciliation>;
+  setCleared: (id: number, transactionIds: number[], cleared: boolean) => Promise<Reconciliation>;
+  finish: (id: number, adjust?: boolean) => Promise<Reconciliation>;
+  cancel: (id: number) => Promise<void>;
 }
 
 interface TransactionAPI {
-  getAll: () => Promise<Transaction[]>;
+  list: (query?: TransactionQuery) => Promise<TransactionPage>;
   create: (data: Partial<Transaction>) => Promise<Transaction>;
+  update: (id: number, data: Partial<Transaction>) => Promise<Transaction>;
+  delete: (id: number) => Promise<void>;
+}
+
+interface TransferAPI {
+  create: (data: TransferInput) => Promise<Transaction>;
+}
+
+interface StatisticsAPI {
+  get: (params?: { start_date?: string; end_date?: string }) => Promise<Statistics>;
 }
 
 interface CategoryAPI {
@@ -25,22 +124,98 @@ interface CategoryAPI {
   create: (data: Partial<Category>) => Promise<Category>;
 }
 
+interface TagAPI {
+  getAll: () => Promise<Tag[]>;
+  create: (name: string) => Promise<Tag>;
+  rename: (id: number, name: string) => Promise<Tag>;
+  delete: (id: number) => Promise<void>;
+}
+
 interface APIService {
+  authApi: AuthAPI;
+  ledgerApi: LedgerAPI;
   accountApi: AccountAPI;
+  reconciliationApi: ReconciliationAPI;
   transactionApi: TransactionAPI;
+  transferApi: TransferAPI;
   categoryApi: CategoryAPI;
+  tagApi: TagAPI;
+  statisticsApi: StatisticsAPI;
 }
 
+const saveToken = (data: AuthResponse) => {
+  localStorage.setItem(TOKEN_KEY, data.token);
+  return data;
+};
+
+export const authApi: AuthAPI = {
+  login: (username, password) =>
+    api.post('/auth/login', { username, password }).then(res => saveToken(res.data)),
+  register: (username, password) =>
+    api.post('/auth/register', { username, password }).then(res => saveToken(res.data)),
+  logout: () =>
+    api.post('/auth/logout').finally(() => {
+      localStorage.removeItem(TOKEN_KEY);
+      localStorage.removeItem(LEDGER_KEY);
+    }).then(() => undefined),
+  me: () => api.get('/auth/me').then(res => res.data)
+};
+
+export const ledgerApi: LedgerAPI = {
+  getAll: () => api.get('/ledgers').then(res => res.data),
+  create: (name) => api.post('/ledgers', { name }).then(res => res.data),
+  members: (id) => api.get(`/ledgers/${id}/members`).then(res => res.data),
+  addMember: (id, username, role) =>
+    api.post(`/ledgers/${id}/members`, { username, role }).then(res => res.data),
+  updateMember: (id, userId, role) =>
+    api.put(`/ledgers/${id}/members/${userId}`, { role }).then(res => res.data),
+  removeMember: (id, userId) => api.delete(`/ledgers/${id}/members/${userId}`)
+};
+
 export const accountApi: AccountAPI = {
   getAll: () => api.get('/accounts').then(res => res.data.accounts || res.data),
+  summary: () => api.get('/accounts').then(res => res.data),
+  balanceHistory: (params = {}) =>
+    api.get('/accounts/balance-history', {
+      params: { ...params, account_id: params.account_id?.join(',') },
+    }).then(res => res.data),
   create: (data) => api.post('/accounts', data).then(res => res.data),
   update: (id, data) => api.put(`/accounts/${id}`, data).then(res => res.data),
-  delete: (id) => api.delete(`/accounts/${id}`)
+  setOpeningBalance: (id, openingBalance) =>
+    api.put(`/accounts/${id}/opening-balance`, { opening_balance: openingBalance }).then(res => res.data),
+  delete: (id) => api.delete(`/accounts/${id}`),
+  statements: (id, count) => api.get(`/accounts/${id}/statements`, { params: { count } }).then(res => res.data.statements),
+  statement: (id, closingDate) => api.get(`/accounts/${id}/statements/${closingDate}`).then(res => res.data.statement)
+};
+
+export const reconciliationApi: ReconciliationAPI = {
+  start: (accountId, statementDate, statementBalance) =>
+    api.post(`/accounts/${accountId}/reconciliations`, { statement_date: statementDate, statement_balance: statementBalance })
+      .then(res => res.data),
+  list: (accountId) => api.get(`/accounts/${accountId}/reconciliations`).then(res => res.data),
+  get: (id) => api.get(`/reconciliations/${id}`).then(res => res.data),
+  setCleared: (id, transactionIds, cleared) =>
+    api.put(`/reconciliations/${id}/cleared`, { transaction_ids: transactionIds, cleared }).then(res => res.data),
+  finish: (id, adjust = false) => api.post(`/reconciliations/${id}/finish`, { adjust }).then(res => res.data),
+  cancel: (id) => api.delete(`/reconciliations/${id}`)
 };
 
 export const transactionApi: TransactionAPI = {
-  getAll: () => api.get('/transactions').then(res => res.data),
-  create: (data) => api.post('/transactions', data).then(res => res.data)
+  list: (query = {}) =>
+    api.get('/transactions', {
+      params: {
+        ...query,
+        category_id: query.category_id?.join(',') || undefined,
+        tag_id: query.tag_id?.join(',') || undefined,
+      },
+    }).then(res => res.data),
+  create: (data) => api.post('/transactions', data).then(res => res.data),
+  update: (id, data) => api.put(`/transactions/${id}`, data).then(res => res.data.transaction),
+  delete: (id) => api.delete(`/transactions/${id}`)
+};
+
+export const transferApi: TransferAPI = {
+  create: (data) => api.post('/transfers', data).then(res => res.data.transaction)
 };
 
 export const categoryApi: CategoryAPI = {
@@ -48,10 +223,27 @@ export const categoryApi: CategoryAPI = {
   create: (data) => api.post('/categories', data).then(res => res.data)
 };
 
+export const tagApi: TagAPI = {
+  getAll: () => api.get('/tags').then(res => res.data),
+  create: (name) => api.post('/tags', { name }).then(res => res.data),
+  rename: (id, name) => api.put(`/tags/${id}`, { name }).then(res => res.data),
+  delete: (id) => api.delete(`/tags/${id}`)
+};
+
+export const statisticsApi: StatisticsAPI = {
+  get: (params) => api.get('/statistics', { params }).then(res => res.data)
+};
+
 const apiService: APIService = {
+  authApi,
+  ledgerApi,
   accountApi,
+  reconciliationApi,
   transactionApi,
-  categoryApi
+  transferApi,
+  categoryApi,
+  tagApi,
+  statisticsApi
 };
 
 export default apiService;
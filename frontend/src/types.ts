@@ -1,27 +1,170 @@
+export type AccountType = 'cash' | 'debit' | 'credit_card' | 'loan' | 'investment';
+
 export interface Account {
   id: number;
   name: string;
-  balance: number;
-  type: string;
+  balance: string; // 十进制字符串，如 "12.34"；信用卡和贷款欠款时为负数
+  opening_balance: string; // 期初余额，余额等于期初余额加上所有交易的影响
+  currency: string; // ISO 4217 币种代码，如 "CNY"
+  type: AccountType;
+  credit_limit?: string; // 仅信用卡
+  statement_day?: number; // 仅信用卡
+  payment_due_day?: number; // 信用卡或贷款
+  available_credit?: string; // 信用卡剩余额度，仅在账户列表中返回
   created_at: string;
   updated_at: string;
 }
 
+// 账户列表及按本位币汇总的资产负债
+export interface AccountSummary {
+  accounts: Account[];
+  total_assets: string;
+  total_liabilities: string;
+  net_worth: string;
+  base_currency: string;
+}
+
+export type BalanceInterval = 'daily' | 'weekly' | 'monthly';
+
+// 余额走势，balances 和 total 与 dates 一一对应
+export interface BalanceHistory {
+  interval: BalanceInterval;
+  start_date: string;
+  end_date: string;
+  base_currency: string;
+  dates: string[];
+  accounts: {
+    account_id: number;
+    name: string;
+    type: AccountType;
+    currency: string;
+    balances: string[];
+  }[];
+  total: string[]; // 按本位币换算的净资产
+}
+
+export type StatementStatus = 'open' | 'paid' | 'due' | 'minimum_paid' | 'overdue';
+
+// 信用卡账单，金额为欠款方向
+export interface CreditCardStatement {
+  start_date: string;
+  closing_date: string;
+  due_date: string;
+  opening_balance: string;
+  charges: string;
+  credits: string;
+  payments: string;
+  statement_balance: string;
+  minimum_payment: string;
+  paid_amount: string;
+  status: StatementStatus;
+  transactions?: Transaction[];
+  payment_transactions?: Transaction[];
+}
+
+// 账户对账，余额按账户余额方向记录
+export interface Reconciliation {
+  id: number;
+  account_id: number;
+  statement_date: string;
+  statement_balance: string;
+  adjustment_id?: number; // 完成时补记的调整交易
+  finished_at: string | null;
+  cleared_balance: string;
+  difference: string; // 对账单余额减已清算余额
+  transactions: (Transaction & { delta: string; cleared: boolean })[];
+}
+
 export interface Transaction {
   id: number;
   account_id: number;
+  to_account_id?: number;
   category_id: number;
-  amount: number;
-  type: 'income' | 'expense';
+  amount: string;
+  currency: string;
+  to_amount?: string;
+  type: 'income' | 'expense' | 'transfer';
   description: string;
   date: string;
+  tags?: Tag[];
+  tag_ids?: number[]; // 创建或更新时指定标签，空数组清除标签
+  splits?: TransactionSplit[]; // 拆分行金额之和须等于 amount，更新时空数组取消拆分
   created_at: string;
 }
 
+export interface TransactionSplit {
+  id?: number;
+  category_id: number;
+  amount: string;
+  memo?: string;
+  category?: Category;
+}
+
+export interface Tag {
+  id: number;
+  name: string;
+  transaction_count?: number;
+}
+
+export interface TransactionQuery {
+  page?: number;
+  page_size?: number;
+  account_id?: number;
+  type?: Transaction['type'];
+  start_date?: string;
+  end_date?: string;
+  category_id?: number[];
+  tag_id?: number[];
+  min_amount?: string;
+  max_amount?: string;
+  description?: string;
+  sort_by?: 'date' | 'amount' | 'created_at';
+  order?: 'asc' | 'desc';
+}
+
+export interface TransactionPage {
+  transactions: Transaction[];
+  total: number;
+  page: number;
+  page_size: number;
+}
+
+export interface Statistics {
+  total_income: string;
+  total_expense: string;
+  net_amount: string;
+  by_member?: MemberStatistics[];
+  by_tag?: TagStatistics[];
+}
+
+export interface TagStatistics {
+  tag_id: number;
+  tag_name: string;
+  income: string;
+  expense: string;
+}
+
+export interface MemberStatistics {
+  user_id: number;
+  username: string;
+  income: string;
+  expense: string;
+}
+
+export interface TransferInput {
+  from_account_id: number;
+  to_account_id: number;
+  amount: string;
+  date?: string;
+  description?: string;
+}
+
 export interface Category {
   id: number;
   name: string;
   type: 'income' | 'expense';
+  parent_id?: number;
+  children?: Category[];
 }
 
 export interface ApiResponse<T> {
@@ -29,3 +172,32 @@ export interface ApiResponse<T> {
   message: string;
   data: T;
 }
+
+export interface User {
+  id: number;
+  username: string;
+  created_at: string;
+}
+
+export interface AuthResponse {
+  user: User;
+  token: string;
+  expires_at: string;
+}
+
+export type LedgerRole = 'owner' | 'editor' | 'viewer';
+
+export interface Ledger {
+  id: number;
+  name: string;
+  role?: LedgerRole;
+  created_at: string;
+}
+
+export interface LedgerMember {
+  id: number;
+  ledger_id: number;
+  user_id: number;
+  role: LedgerRole;
+  user: User;
+}
@@ -1,7 +1,7 @@
 export interface Account {
   id: number;
   name: string;
-  balance: number;
+  balance: string;
   created_at: string;
   updated_at: string;
 }
@@ -10,7 +10,7 @@ export interface Transaction {
   id: number;
   account_id: number;
   category_id: number;
-  amount: number;
+  amount: string;
   type: 'income' | 'expense';
   description: string;
   created_at: string;